@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/app"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+const exportUsage = `usage: arevbond export [-o FILE]
+
+Writes all posts, categories and referenced images to a zip archive,
+arevbond-blog-YYYY-MM-DD.zip by default. Use -o - for stdout.`
+
+const importUsage = `usage: arevbond import [-policy skip|overwrite|rename] [-author USERNAME] FILE.zip
+
+Loads an archive made by export. Posts are matched by slug; posts equal
+to the ones in the database are left untouched, so importing the same
+archive twice changes nothing. -policy decides what happens to a post
+whose slug is taken by a different post (skip by default).`
+
+func runExport(ctx context.Context, args []string) error {
+	flags := newFlagSet("export", exportUsage)
+	output := flags.String("o", domain.ArchiveFilename(time.Now()), "output file, - for stdout")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		if *output == "-" {
+			return exportTo(ctx, tools, os.Stdout)
+		}
+
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("can't create archive: %w", err)
+		}
+		defer file.Close()
+
+		if err = exportTo(ctx, tools, file); err != nil {
+			return err
+		}
+
+		if err = file.Close(); err != nil {
+			return fmt.Errorf("can't write archive: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func exportTo(ctx context.Context, tools *app.Tools, out io.Writer) error {
+	report, err := tools.Blog.Export(ctx, out)
+	if err != nil {
+		return fmt.Errorf("can't export blog: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d posts, %d categories, %d images\n",
+		report.Posts, report.Categories, report.Images)
+
+	if len(report.MissingImages) > 0 {
+		fmt.Fprintf(os.Stderr, "missing images: %s\n", strings.Join(report.MissingImages, ", "))
+	}
+
+	return nil
+}
+
+func runImport(ctx context.Context, args []string) error {
+	flags := newFlagSet("import", importUsage)
+	policy := flags.String("policy", string(domain.ConflictSkip), "skip, overwrite or rename")
+	author := flags.String("author", "admin", "username of the author of new posts")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || !domain.ConflictPolicy(*policy).Valid() {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("can't open archive: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("can't open archive: %w", err)
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		authorID, err := findUser(ctx, tools, *author)
+		if err != nil {
+			return err
+		}
+
+		report, err := tools.Blog.Import(ctx, file, info.Size(), domain.ImportOptions{
+			Policy:   domain.ConflictPolicy(*policy),
+			AuthorID: authorID,
+		})
+		if report != nil {
+			printImportReport(report)
+		}
+
+		if err != nil {
+			return fmt.Errorf("can't import archive: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func printImportReport(report *domain.ImportReport) {
+	printList := func(label string, items []string) {
+		if len(items) > 0 {
+			fmt.Printf("%s (%d): %s\n", label, len(items), strings.Join(items, ", "))
+		}
+	}
+
+	printList("created", report.Created)
+	printList("overwritten", report.Updated)
+
+	for from, to := range report.Renamed {
+		fmt.Printf("renamed: %s -> %s\n", from, to)
+	}
+
+	printList("skipped", report.Skipped)
+	fmt.Printf("unchanged: %d\n", len(report.Unchanged))
+	printList("new categories", report.Categories)
+	printList("images written", report.Images)
+	printList("images skipped", report.SkippedImages)
+}
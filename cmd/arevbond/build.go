@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/app"
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog"
+)
+
+const buildUsage = `usage: arevbond build [-o DIR] [-url URL] [-clean]
+
+Renders the public site to DIR (public by default) for static hosting:
+the index, the post list with all its pages, every published post,
+category pages, robots.txt and the sitemap, plus static files and
+post images. Text static files get .br and .gz copies for servers that
+serve precompressed files (nginx gzip_static, brotli_static). Comments are shown read-only; reactions and the comment
+form need the server and are left out.
+
+  -url    absolute site url for the sitemap and og tags, PUBLIC_URL by default
+  -clean  remove DIR before building, otherwise files are overwritten`
+
+func runBuild(ctx context.Context, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+
+	flags := newFlagSet("build", buildUsage)
+	output := flags.String("o", "public", "output directory")
+	publicURL := flags.String("url", cfg.Server.PublicURL, "absolute site url")
+	clean := flags.Bool("clean", false, "remove the output directory first")
+
+	if err = flags.Parse(args); err != nil || flags.NArg() != 0 || *output == "" {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	cfg.Server.PublicURL = strings.TrimSuffix(*publicURL, "/")
+
+	if *clean {
+		if dir := filepath.Clean(*output); dir == "." || dir == string(filepath.Separator) {
+			return errors.New("refusing to clean the current or root directory")
+		}
+
+		if err = os.RemoveAll(*output); err != nil {
+			return fmt.Errorf("can't clean output directory: %w", err)
+		}
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		report, err := tools.SiteBuilder(cfg.Server).Build(ctx, *output, blog.ImagesDir)
+		if err != nil {
+			return fmt.Errorf("can't build site: %w", err)
+		}
+
+		fmt.Printf("built %s: %d pages, %d other files, %d static files, %d images\n",
+			*output, report.Pages, report.Files, report.Static, report.Images)
+
+		return nil
+	})
+}
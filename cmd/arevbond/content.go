@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/app"
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+const postsUsage = `usage: arevbond posts COMMAND
+
+  list [-status published|draft]
+        list posts, newest first
+  import -category NAME|ID [-title T] [-slug S] [-description D] [-author USERNAME] [-publish] FILE.md
+        create a post from a markdown file
+  export [-o FILE] SLUG
+        write post markdown to stdout or FILE
+  publish SLUG
+  unpublish SLUG`
+
+const categoriesUsage = `usage: arevbond categories add NAME`
+
+const rerenderUsage = `usage: arevbond rerender
+
+Runs the content of every post through the current processing rules
+(image links) and saves the posts that changed.`
+
+// withTools собирает сервисы приложения для команды CLI. Логи пишутся
+// в stderr, чтобы не смешиваться с выводом команды.
+func withTools(ctx context.Context, run func(ctx context.Context, tools *app.Tools) error) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+
+	//nolint: exhaustruct // default slog handler
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	tools, err := app.NewTools(logger, cfg)
+	if err != nil {
+		return err
+	}
+	defer tools.Close()
+
+	return run(ctx, tools)
+}
+
+func runPosts(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, postsUsage)
+
+		return errUsage
+	}
+
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "list":
+		return postsList(ctx, args)
+	case "import":
+		return postsImport(ctx, args)
+	case "export":
+		return postsExport(ctx, args)
+	case "publish", "unpublish":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, postsUsage)
+
+			return errUsage
+		}
+
+		return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+			return setPublished(ctx, tools, args[0], command == "publish")
+		})
+	default:
+		fmt.Fprintln(os.Stderr, postsUsage)
+
+		return errUsage
+	}
+}
+
+func postsList(ctx context.Context, args []string) error {
+	flags := newFlagSet("posts list", postsUsage)
+	status := flags.String("status", "", "published or draft, all posts by default")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || !domain.PostStatus(*status).Valid() {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		//nolint:mnd // column padding
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tCREATED\tCATEGORY\tSLUG\tTITLE")
+
+		filter := domain.PostsFilter{
+			Limit:      0,
+			CategoryID: 0,
+			AuthorID:   0,
+			Status:     domain.PostStatus(*status),
+			After:      nil,
+		}
+
+		for {
+			posts, next, err := tools.Blog.ListPosts(ctx, filter)
+			if err != nil {
+				return fmt.Errorf("can't list posts: %w", err)
+			}
+
+			for _, post := range posts {
+				status := domain.PostStatusDraft
+				if post.IsPublished {
+					status = domain.PostStatusPublished
+				}
+
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", post.ID, status,
+					post.CreatedAt.Local().Format(time.DateOnly), post.CategoryName, post.Slug, post.Title)
+			}
+
+			if next == nil {
+				break
+			}
+
+			filter.After = next
+		}
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("can't print posts: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func postsImport(ctx context.Context, args []string) error {
+	flags := newFlagSet("posts import", postsUsage)
+	category := flags.String("category", "", "category name or id")
+	title := flags.String("title", "", "post title, the file name by default")
+	slug := flags.String("slug", "", "post slug, built from the title by default")
+	description := flags.String("description", "", "short description")
+	author := flags.String("author", "admin", "username of the post author")
+	publish := flags.Bool("publish", false, "publish the post right away")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *category == "" {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	path := flags.Arg(0)
+
+	if *title == "" {
+		*title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("can't read post: %w", err)
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		categoryID, err := findCategory(ctx, tools, *category)
+		if err != nil {
+			return err
+		}
+
+		authorID, err := findUser(ctx, tools, *author)
+		if err != nil {
+			return err
+		}
+
+		post, err := tools.Blog.CreatePost(ctx, domain.CreatePostParams{
+			Title:       *title,
+			Slug:        *slug,
+			Description: *description,
+			Filename:    filepath.Base(path),
+			CategoryID:  categoryID,
+			AuthorID:    authorID,
+			IsPublished: *publish,
+			Content:     content,
+		})
+		if err != nil {
+			return fmt.Errorf("can't import post: %w", err)
+		}
+
+		fmt.Printf("created post %d: /blog/posts/%s\n", post.ID, post.Slug)
+
+		return nil
+	})
+}
+
+func postsExport(ctx context.Context, args []string) error {
+	flags := newFlagSet("posts export", postsUsage)
+	output := flags.String("o", "", "output file, stdout by default")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		post, err := tools.Blog.PostBySlug(ctx, flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("can't find post: %w", err)
+		}
+
+		if *output == "" {
+			_, err = os.Stdout.Write(post.Content)
+		} else {
+			//nolint:mnd // rw-r--r--
+			err = os.WriteFile(*output, post.Content, 0o644)
+		}
+
+		if err != nil {
+			return fmt.Errorf("can't write post: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// setPublished публикует или скрывает пост. Повторный вызов ничего не меняет.
+func setPublished(ctx context.Context, tools *app.Tools, slug string, publish bool) error {
+	post, err := tools.Blog.PostBySlug(ctx, slug)
+	if err != nil {
+		return fmt.Errorf("can't find post: %w", err)
+	}
+
+	if post.IsPublished != publish {
+		if err = tools.Blog.ChangePublishStatus(ctx, post.ID, post.IsPublished); err != nil {
+			return fmt.Errorf("can't change publish status: %w", err)
+		}
+	}
+
+	state := "unpublished"
+	if publish {
+		state = "published"
+	}
+
+	fmt.Printf("post %s is %s\n", post.Slug, state)
+
+	return nil
+}
+
+func runCategories(ctx context.Context, args []string) error {
+	if len(args) != 2 || args[0] != "add" {
+		fmt.Fprintln(os.Stderr, categoriesUsage)
+
+		return errUsage
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		category, err := tools.Blog.CreateCategory(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("can't add category: %w", err)
+		}
+
+		fmt.Printf("created category %d: %s\n", category.ID, category.Name)
+
+		return nil
+	})
+}
+
+func runRerender(ctx context.Context, args []string) error {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, rerenderUsage)
+
+		return errUsage
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		changed, err := tools.Blog.RerenderPosts(ctx)
+		if err != nil {
+			return fmt.Errorf("can't rerender posts after %d changes: %w", changed, err)
+		}
+
+		fmt.Printf("%d posts changed\n", changed)
+
+		return nil
+	})
+}
+
+// findCategory ищет категорию по id или имени без учёта регистра.
+func findCategory(ctx context.Context, tools *app.Tools, nameOrID string) (int, error) {
+	categories, err := tools.Blog.Categories(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't get categories: %w", err)
+	}
+
+	id, _ := strconv.Atoi(nameOrID)
+
+	for _, category := range categories {
+		if category.ID == id || strings.EqualFold(category.Name, nameOrID) {
+			return category.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("category %q not found", nameOrID)
+}
+
+func findUser(ctx context.Context, tools *app.Tools, username string) (int, error) {
+	users, err := tools.Auth.Users(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't get users: %w", err)
+	}
+
+	for _, user := range users {
+		if user.Username == username {
+			return user.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("user %q not found", username)
+}
+
+// newFlagSet возвращает набор флагов подкоманды: ошибки разбора выводятся
+// вместе с usage всей команды.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+	}
+
+	return flags
+}
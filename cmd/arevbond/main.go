@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"log/slog"
@@ -16,9 +18,65 @@ import (
 
 var version = "unknown"
 
+const usage = `usage: arevbond [command]
+
+Without a command the blog server is started.
+
+commands:
+  migrate     manage the database schema, see arevbond migrate -h
+  posts       list, import, export and publish posts
+  categories  add categories
+  rerender    reprocess the content of all posts
+  export      write the whole blog to a zip archive
+  import      load posts, categories and images from an archive
+  sync        publish notes of an Obsidian vault
+  build       render the public site for static hosting`
+
 func main() {
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage) }
 	flag.Parse()
 
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "":
+		runServer(ctx)
+	case "migrate":
+		exitOnError(runMigrate(ctx, flag.Args()[1:]))
+	case "posts":
+		exitOnError(runPosts(ctx, flag.Args()[1:]))
+	case "categories":
+		exitOnError(runCategories(ctx, flag.Args()[1:]))
+	case "rerender":
+		exitOnError(runRerender(ctx, flag.Args()[1:]))
+	case "export":
+		exitOnError(runExport(ctx, flag.Args()[1:]))
+	case "import":
+		exitOnError(runImport(ctx, flag.Args()[1:]))
+	case "sync":
+		exitOnError(runSync(ctx, flag.Args()[1:]))
+	case "build":
+		exitOnError(runBuild(ctx, flag.Args()[1:]))
+	default:
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+}
+
+const exitUsage = 2
+
+// exitOnError завершает команду CLI: ошибки аргументов уже описаны в usage.
+func exitOnError(err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage):
+		os.Exit(exitUsage)
+	default:
+		log.Fatal(err)
+	}
+}
+
+func runServer(ctx context.Context) {
 	cfg, err := config.New()
 	if err != nil {
 		log.Fatal(err)
@@ -35,8 +93,6 @@ func main() {
 		log.Fatal(err)
 	}
 
-	ctx := context.Background()
-
 	if err = application.Run(ctx); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/db"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = `usage: arevbond migrate [-dir migrations] up|down|status|create NAME
+
+  up       apply all pending migrations
+  down     roll back the last applied migration
+  status   list migrations and when they were applied
+  create   add an empty SQL migration NAME to -dir`
+
+var errUsage = errors.New("invalid arguments")
+
+// runMigrate управляет схемой базы встроенными в бинарник миграциями.
+func runMigrate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), migrateUsage) }
+	dir := flags.String("dir", "migrations", "directory for new migrations")
+
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+
+	switch flags.Arg(0) {
+	case "create":
+		if flags.NArg() != 2 {
+			flags.Usage()
+
+			return errUsage
+		}
+
+		if err := goose.Create(nil, *dir, flags.Arg(1), "sql"); err != nil {
+			return fmt.Errorf("can't create migration: %w", err)
+		}
+
+		return nil
+	case "up", "down", "status":
+	default:
+		flags.Usage()
+
+		return errUsage
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+
+	conn, err := db.NewConn(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("can't connect to storage: %w", err)
+	}
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+
+	switch flags.Arg(0) {
+	case "up":
+		return migrateUp(ctx, migrator)
+	case "down":
+		result, downErr := migrator.Down(ctx)
+		if downErr != nil {
+			return fmt.Errorf("can't roll back migration: %w", downErr)
+		}
+
+		fmt.Println(result)
+
+		return nil
+	default:
+		return migrateStatus(ctx, migrator)
+	}
+}
+
+func migrateUp(ctx context.Context, migrator *goose.Provider) error {
+	results, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("can't apply migrations: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("no pending migrations")
+	}
+
+	for _, result := range results {
+		fmt.Println(result)
+	}
+
+	return nil
+}
+
+func migrateStatus(ctx context.Context, migrator *goose.Provider) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get migrations status: %w", err)
+	}
+
+	//nolint:mnd // column padding
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APPLIED AT\tMIGRATION")
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.State == goose.StateApplied {
+			appliedAt = status.AppliedAt.Local().Format(time.DateTime)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", appliedAt, status.Source.Path)
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("can't print status: %w", err)
+	}
+
+	return nil
+}
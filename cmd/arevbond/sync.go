@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/app"
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/vault"
+)
+
+const syncUsage = `usage: arevbond sync [-dry-run] [-watch] [-vault DIR] [-folder PATH] [-category NAME]
+
+Publishes notes of an Obsidian vault as posts. A note is linked to a post by
+"id" in its front matter or by its path; other front matter keys: title,
+slug, description, category, published. Embedded attachments are uploaded
+to the blog images; an image with the same name that was not uploaded from
+the vault is never replaced. Flags override VAULT_* settings.
+
+  -dry-run  show what would change without changing anything
+  -watch    keep running and sync after every change in the vault`
+
+// diffContext - сколько неизменных строк показывать вокруг правок текста.
+const diffContext = 2
+
+func runSync(ctx context.Context, args []string) error {
+	cfg, err := config.New()
+	if err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+
+	flags := newFlagSet("sync", syncUsage)
+	dryRun := flags.Bool("dry-run", false, "show changes only")
+	watch := flags.Bool("watch", false, "sync after every change")
+	dir := flags.String("vault", cfg.Vault.Dir, "vault directory")
+	folder := flags.String("folder", cfg.Vault.Folder, "folder with notes to publish")
+	category := flags.String("category", cfg.Vault.Category, "default category")
+
+	if err = flags.Parse(args); err != nil || flags.NArg() != 0 {
+		flags.Usage()
+
+		return errUsage
+	}
+
+	if *dir == "" {
+		return errors.New("vault directory is not set: use -vault or VAULT_DIR")
+	}
+
+	return withTools(ctx, func(ctx context.Context, tools *app.Tools) error {
+		authorID, err := findUser(ctx, tools, cfg.Vault.Author)
+		if err != nil {
+			return err
+		}
+
+		syncer := tools.VaultSyncer(vault.Options{
+			Dir:      *dir,
+			Folder:   *folder,
+			Category: *category,
+			AuthorID: authorID,
+		})
+
+		if !*watch {
+			report, err := syncer.Sync(ctx, *dryRun)
+			if err != nil {
+				return fmt.Errorf("can't sync vault: %w", err)
+			}
+
+			printSyncReport(report, *dryRun)
+
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return syncer.Watch(ctx, *dryRun, cfg.Vault.Debounce, func(report *vault.Report, err error) {
+			fmt.Printf("--- %s\n", time.Now().Format(time.TimeOnly))
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "can't sync vault: %v\n", err)
+
+				return
+			}
+
+			printSyncReport(report, *dryRun)
+		})
+	})
+}
+
+func printSyncReport(report *vault.Report, dryRun bool) {
+	for _, note := range report.Notes {
+		switch note.Action {
+		case vault.ActionCreate:
+			fmt.Printf("+ %s -> new post %s\n", note.Path, note.Slug)
+		case vault.ActionUpdate:
+			fmt.Printf("~ %s -> post %d %s\n", note.Path, note.PostID, note.Slug)
+			printChanges(note.Changes)
+		case vault.ActionFailed:
+			fmt.Printf("! %s: %v\n", note.Path, note.Err)
+		case vault.ActionUnchanged:
+		}
+	}
+
+	for _, name := range report.Attachments {
+		fmt.Printf("+ attachment %s\n", name)
+	}
+
+	for _, name := range report.Overwritten {
+		fmt.Printf("~ attachment %s\n", name)
+	}
+
+	for _, name := range report.Conflicts {
+		fmt.Printf("! attachment %s: an image with this name was not uploaded from the vault, rename the file\n", name)
+	}
+
+	if len(report.Missing) > 0 {
+		fmt.Printf("embeds without a file: %s\n", strings.Join(report.Missing, ", "))
+	}
+
+	verb := "synced"
+	if dryRun {
+		verb = "dry run"
+	}
+
+	fmt.Printf("%s: %d created, %d updated, %d unchanged, %d failed\n", verb,
+		report.Count(vault.ActionCreate), report.Count(vault.ActionUpdate),
+		report.Count(vault.ActionUnchanged), report.Count(vault.ActionFailed))
+}
+
+func printChanges(changes []domain.FieldChange) {
+	for _, change := range changes {
+		if change.Field != "content" {
+			fmt.Printf("    %s: %q -> %q\n", change.Field, change.Current, change.Yours)
+
+			continue
+		}
+
+		fmt.Println("    content:")
+
+		for _, line := range domain.DiffLines(change.Current, change.Yours, diffContext) {
+			fmt.Printf("    %s %s\n", line.Op, line.Text)
+		}
+	}
+}
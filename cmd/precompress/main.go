@@ -0,0 +1,141 @@
+// Команда precompress заранее сжимает статические файлы brotli и gzip с
+// максимальной степенью: на лету так сжимать слишком долго. Запускается
+// через go generate в internal/server, результат встраивается в бинарник.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/andybalholm/brotli"
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+)
+
+const usage = `usage: precompress <src> <dst>
+
+Writes <name>.br and <name>.gz to dst for every compressible file in src,
+only when the compressed variant is smaller. dst is recreated from scratch.`
+
+// encodedSuffixes - расширения сжатых вариантов, их же ищет сервер.
+//
+//nolint:gochecknoglobals // suffixes of precompressed files
+var encodedSuffixes = map[string]string{
+	middleware.EncodingBrotli: ".br",
+	middleware.EncodingGzip:   ".gz",
+}
+
+func main() {
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	if flag.NArg() != 2 { //nolint:mnd // src and dst
+		flag.Usage()
+		os.Exit(2) //nolint:mnd // usage error
+	}
+
+	if err := run(flag.Arg(0), flag.Arg(1)); err != nil {
+		fmt.Fprintln(os.Stderr, "precompress:", err)
+		os.Exit(1)
+	}
+}
+
+func run(src, dst string) error {
+	// старые варианты удалённых файлов не должны остаться во встроенной директории
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("can't clean %s: %w", dst, err)
+	}
+
+	fsys := os.DirFS(src)
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+
+		if !middleware.Compressible(contentType) {
+			return nil
+		}
+
+		for encoding, suffix := range encodedSuffixes {
+			compressed, err := precompress(encoding, data)
+			if err != nil {
+				return fmt.Errorf("can't compress %s: %w", name, err)
+			}
+
+			if len(compressed) >= len(data) {
+				continue
+			}
+
+			if err = writeFile(filepath.Join(dst, filepath.FromSlash(name+suffix)), compressed); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't precompress %s: %w", src, err)
+	}
+
+	return nil
+}
+
+func precompress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var encoder io.WriteCloser
+
+	switch encoding {
+	case middleware.EncodingBrotli:
+		encoder = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	default:
+		// заголовок gzip без имени и времени, чтобы результат не менялся от запуска к запуску
+		gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, fmt.Errorf("can't create gzip writer: %w", err)
+		}
+
+		encoder = gz
+	}
+
+	if _, err := encoder.Write(data); err != nil {
+		return nil, fmt.Errorf("can't write %s: %w", encoding, err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("can't close %s: %w", encoding, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("can't create directory: %w", err)
+	}
+
+	if err := os.WriteFile(file, data, 0o644); err != nil { //nolint:mnd,gosec // embedded into binary
+		return fmt.Errorf("can't write %s: %w", filepath.Base(file), err)
+	}
+
+	return nil
+}
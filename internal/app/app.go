@@ -4,17 +4,27 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"sync"
 
 	"github.com/arevbond/arevbond-blog/internal/config"
 	"github.com/arevbond/arevbond-blog/internal/db"
 	"github.com/arevbond/arevbond-blog/internal/server"
+	"github.com/arevbond/arevbond-blog/internal/service/analytics"
+	analyticsService "github.com/arevbond/arevbond-blog/internal/service/analytics/service"
 	"github.com/arevbond/arevbond-blog/internal/service/auth"
+	authService "github.com/arevbond/arevbond-blog/internal/service/auth/service"
 	"github.com/arevbond/arevbond-blog/internal/service/blog"
+	blogService "github.com/arevbond/arevbond-blog/internal/service/blog/service"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/vault"
+	"github.com/arevbond/arevbond-blog/internal/service/comments"
+	"github.com/jmoiron/sqlx"
 )
 
 // App contains all application dependency and launch http server.
 type App struct {
-	Server *server.Server
+	Server    *server.Server
+	Analytics *analyticsService.Analytics
 }
 
 func New(log *slog.Logger, cfg config.Config) (*App, error) {
@@ -23,18 +33,160 @@ func New(log *slog.Logger, cfg config.Config) (*App, error) {
 		return nil, fmt.Errorf("can't connect to storage: %w", err)
 	}
 
-	blogService := blog.NewBlogModule(log, conn)
-	authService := auth.NewAuthModule(log, cfg.AdminToken, cfg.SecretKeyJWT)
+	if err = prepareSchema(log, conn, cfg.MigrateOnStart); err != nil {
+		return nil, err
+	}
+
+	blogModule, err := newBlog(log, conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	authModule := auth.NewAuthModule(log, conn, cfg.AdminToken, cfg.SecretKeyJWT, cfg.SiteName, cfg.RequireTOTP)
+
+	if err = authModule.EnsureAdmin(context.Background()); err != nil {
+		return nil, fmt.Errorf("can't ensure admin user: %w", err)
+	}
+
+	var ownHost string
+	if publicURL, parseErr := url.Parse(cfg.Server.PublicURL); parseErr == nil {
+		ownHost = publicURL.Hostname()
+	}
+
+	analyticsModule := analytics.NewAnalyticsModule(log, conn, cfg.Analytics.FlushInterval, ownHost)
 
-	srv := server.New(log, cfg.Server, server.Services{Blog: blogService, Auth: authService})
+	commentsService := comments.NewCommentsModule(log, conn)
+
+	srv := server.New(log, cfg.Server, server.Services{
+		Blog:      blogModule,
+		Auth:      authModule,
+		Analytics: analyticsModule,
+		Comments:  commentsService,
+	})
 	srv.ConfigureRoutes()
 
 	return &App{
-		Server: srv,
+		Server:    srv,
+		Analytics: analyticsModule,
 	}, nil
 }
 
+// Tools - сервисы для команд CLI. Собираются так же, как для сервера,
+// поэтому проверки и правила те же, что в HTTP обработчиках.
+type Tools struct {
+	Blog *blogService.Blog
+	Auth *authService.Auth
+
+	log  *slog.Logger
+	conn *sqlx.DB
+}
+
+func NewTools(log *slog.Logger, cfg config.Config) (*Tools, error) {
+	conn, err := db.NewConn(cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to storage: %w", err)
+	}
+
+	if err = prepareSchema(log, conn, cfg.MigrateOnStart); err != nil {
+		return nil, err
+	}
+
+	blogModule, err := newBlog(log, conn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tools{
+		Blog: blogModule,
+		Auth: auth.NewAuthModule(log, conn, cfg.AdminToken, cfg.SecretKeyJWT, cfg.SiteName, cfg.RequireTOTP),
+		log:  log,
+		conn: conn,
+	}, nil
+}
+
+// VaultSyncer собирает синхронизацию с хранилищем Obsidian поверх Blog.
+func (t *Tools) VaultSyncer(opts vault.Options) *vault.Syncer {
+	return blog.NewVaultSyncer(t.log, t.conn, t.Blog, opts)
+}
+
+// SiteBuilder собирает статическую версию сайта с теми же сервисами,
+// что у сервера. Просмотры при сборке не считаются.
+func (t *Tools) SiteBuilder(cfg config.Server) *server.SiteBuilder {
+	return server.NewSiteBuilder(t.log, cfg, server.Services{
+		Blog:      t.Blog,
+		Auth:      t.Auth,
+		Analytics: nil,
+		Comments:  comments.NewCommentsModule(t.log, t.conn),
+	})
+}
+
+func (t *Tools) Close() error {
+	if err := t.conn.Close(); err != nil {
+		return fmt.Errorf("can't close storage: %w", err)
+	}
+
+	return nil
+}
+
+func newBlog(log *slog.Logger, conn *sqlx.DB, cfg config.Config) (*blogService.Blog, error) {
+	avatar, err := server.StaticFile("profile.jpg")
+	if err != nil {
+		return nil, fmt.Errorf("can't load avatar: %w", err)
+	}
+
+	module, err := blog.NewBlogModule(log, conn, cfg.SiteName, avatar, cfg.Slugs, cfg.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("can't create blog module: %w", err)
+	}
+
+	return module, nil
+}
+
+// prepareSchema применяет миграции, если это разрешено, и проверяет, что
+// схема базы не отстаёт от кода: иначе запросы упадут уже во время работы.
+func prepareSchema(log *slog.Logger, conn *sqlx.DB, migrate bool) error {
+	ctx := context.Background()
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return fmt.Errorf("can't create migrator: %w", err)
+	}
+
+	if migrate {
+		results, upErr := migrator.Up(ctx)
+		if upErr != nil {
+			return fmt.Errorf("can't apply migrations: %w", upErr)
+		}
+
+		for _, result := range results {
+			log.Info("migration applied", slog.String("migration", result.String()))
+		}
+	}
+
+	if err = db.CheckSchema(ctx, migrator); err != nil {
+		return fmt.Errorf("can't start: %w", err)
+	}
+
+	return nil
+}
+
 func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		a.Analytics.Run(ctx)
+	}()
+
+	// после остановки сервера дожидаемся сброса накопленных просмотров
+	defer wg.Wait()
+	defer cancel()
+
 	if err := a.Server.Run(ctx); err != nil {
 		return fmt.Errorf("app run: %w", err)
 	}
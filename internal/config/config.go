@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,15 +18,70 @@ const (
 
 type Config struct {
 	Env          string // "local", "prod"
-	AdminToken   string // need for authentication
+	AdminToken   string // initial password of the "admin" user
 	SecretKeyJWT string
-	Server       Server
-	Storage      Storage
+	RequireTOTP  bool   // admin login without second factor is rejected once TOTP is enrolled
+	SiteName     string // shown on generated preview images and in authenticator apps
+	// MigrateOnStart applies pending migrations before the server starts,
+	// otherwise the app refuses to start with an outdated schema.
+	MigrateOnStart bool
+	Server         Server
+	Storage        Storage
+	Analytics      Analytics
+	Slugs          Slugs
+	Vault          Vault
+	Cache          Cache
 }
 
 type Server struct {
-	Host string
-	Port int
+	Host      string
+	Port      int
+	PublicURL string // absolute site url, used in og meta tags and sitemap
+	Robots    Robots
+
+	// CookieSecret signs visitor cookies (e.g. reactions), defaults to SECRET_KEY_JWT.
+	CookieSecret string
+
+	// TrustProxyHeaders allows to take client ip from X-Forwarded-For / X-Real-IP.
+	// Enable only behind exactly one reverse proxy that appends to X-Forwarded-For
+	// or overwrites X-Real-IP, otherwise the headers can be spoofed.
+	TrustProxyHeaders bool
+
+	// HSTS asks browsers to use only https for the site, enabled in prod.
+	HSTS bool
+	// CSPReportOnly sends the Content Security Policy in report-only mode:
+	// violations are logged via /csp-report but nothing is blocked.
+	CSPReportOnly bool
+}
+
+type Robots struct {
+	AllowIndexing bool     // false closes the whole site, e.g. for staging
+	Disallow      []string // extra paths in addition to admin routes
+}
+
+type Analytics struct {
+	FlushInterval time.Duration // how often buffered page views are written to storage
+}
+
+type Slugs struct {
+	Transliteration string   // primary table: gost, uk, be or kk
+	StopWords       []string // words dropped from generated slugs, empty - built-in list
+	MaxLength       int      // 1-100, the size of posts.slug, checked by the blog module
+}
+
+// Cache configures the in-memory cache of posts for anonymous readers.
+type Cache struct {
+	MaxEntries int           // 0 disables the cache
+	TTL        time.Duration // bounds staleness after changes made by other processes, e.g. CLI commands
+}
+
+// Vault configures the Obsidian vault sync command.
+type Vault struct {
+	Dir      string        // vault root, attachments are looked up in the whole vault
+	Folder   string        // notes to publish, relative to Dir, empty - the whole vault
+	Category string        // category of notes without a category in front matter
+	Author   string        // username of the author of created posts
+	Debounce time.Duration // watch mode waits this long after the last change
 }
 
 type Storage struct {
@@ -46,9 +103,35 @@ func New() (Config, error) {
 		return Config{}, fmt.Errorf("can't convert server port to int: %w", err)
 	}
 
+	allowIndexing, err := strconv.ParseBool(getEnv("ROBOTS_ALLOW_INDEXING", "true"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert robots allow indexing to bool: %w", err)
+	}
+
+	trustProxy, err := strconv.ParseBool(getEnv("TRUST_PROXY_HEADERS", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert trust proxy headers to bool: %w", err)
+	}
+
+	cspReportOnly, err := strconv.ParseBool(getEnv("CSP_REPORT_ONLY", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert csp report only to bool: %w", err)
+	}
+
+	env := getEnv("ENV", EnvLocal)
+
 	server := Server{
-		Host: getEnv("SERVER_HOST", "0.0.0.0"),
-		Port: srvPort,
+		Host:      getEnv("SERVER_HOST", "0.0.0.0"),
+		Port:      srvPort,
+		PublicURL: strings.TrimSuffix(getEnv("PUBLIC_URL", "http://localhost:"+srvPortString), "/"),
+		Robots: Robots{
+			AllowIndexing: allowIndexing,
+			Disallow:      getEnvList("ROBOTS_DISALLOW"),
+		},
+		CookieSecret:      getEnv("COOKIE_SECRET", os.Getenv("SECRET_KEY_JWT")),
+		TrustProxyHeaders: trustProxy,
+		HSTS:              env == EnvProd,
+		CSPReportOnly:     cspReportOnly,
 	}
 
 	storagePortStr := getEnv("PG_PORT", "5432")
@@ -66,12 +149,68 @@ func New() (Config, error) {
 		DatabaseName: mustGetEnv("PG_DBNAME"),
 	}
 
+	flushInterval, err := time.ParseDuration(getEnv("ANALYTICS_FLUSH_INTERVAL", "1m"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't parse analytics flush interval: %w", err)
+	}
+
+	requireTOTP, err := strconv.ParseBool(getEnv("REQUIRE_TOTP", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert require totp to bool: %w", err)
+	}
+
+	slugMaxLength, err := strconv.Atoi(getEnv("SLUG_MAX_LENGTH", "80"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert slug max length to int: %w", err)
+	}
+
+	slugs := Slugs{
+		Transliteration: getEnv("SLUG_TRANSLITERATION", "gost"),
+		StopWords:       getEnvList("SLUG_STOP_WORDS"),
+		MaxLength:       slugMaxLength,
+	}
+
+	migrateOnStart, err := strconv.ParseBool(getEnv("MIGRATE_ON_START", "false"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert migrate on start to bool: %w", err)
+	}
+
+	cacheMaxEntries, err := strconv.Atoi(getEnv("CACHE_MAX_ENTRIES", "1000"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't convert cache max entries to int: %w", err)
+	}
+
+	cacheTTL, err := time.ParseDuration(getEnv("CACHE_TTL", "1m"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't parse cache ttl: %w", err)
+	}
+
+	vaultDebounce, err := time.ParseDuration(getEnv("VAULT_DEBOUNCE", "2s"))
+	if err != nil {
+		return Config{}, fmt.Errorf("can't parse vault debounce: %w", err)
+	}
+
+	vault := Vault{
+		Dir:      os.Getenv("VAULT_DIR"),
+		Folder:   os.Getenv("VAULT_FOLDER"),
+		Category: os.Getenv("VAULT_CATEGORY"),
+		Author:   getEnv("VAULT_AUTHOR", "admin"),
+		Debounce: vaultDebounce,
+	}
+
 	return Config{
-		Env:          getEnv("ENV", EnvLocal),
-		AdminToken:   mustGetEnv("ADMIN_TOKEN"),
-		SecretKeyJWT: mustGetEnv("SECRET_KEY_JWT"),
-		Server:       server,
-		Storage:      storage,
+		Env:            env,
+		AdminToken:     mustGetEnv("ADMIN_TOKEN"),
+		SecretKeyJWT:   mustGetEnv("SECRET_KEY_JWT"),
+		RequireTOTP:    requireTOTP,
+		SiteName:       getEnv("SITE_NAME", "arevbond"),
+		MigrateOnStart: migrateOnStart,
+		Server:         server,
+		Storage:        storage,
+		Analytics:      Analytics{FlushInterval: flushInterval},
+		Slugs:          slugs,
+		Vault:          vault,
+		Cache:          Cache{MaxEntries: cacheMaxEntries, TTL: cacheTTL},
 	}, nil
 }
 
@@ -84,6 +223,19 @@ func getEnv(key, fallback string) string {
 	return val
 }
 
+// getEnvList returns comma separated values of env var without empty items.
+func getEnvList(key string) []string {
+	var list []string
+
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
 func mustGetEnv(key string) string {
 	val := os.Getenv(key)
 	if val == "" {
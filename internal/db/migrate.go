@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/arevbond/arevbond-blog/migrations"
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+var ErrSchemaOutdated = errors.New("database schema is outdated")
+
+// NewMigrator применяет встроенные миграции. Блокировка в базе не даёт
+// нескольким экземплярам приложения мигрировать схему одновременно.
+func NewMigrator(conn *sqlx.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("can't create migrations locker: %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, conn.DB, migrations.FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, fmt.Errorf("can't create migrator: %w", err)
+	}
+
+	return provider, nil
+}
+
+// CheckSchema возвращает ErrSchemaOutdated, если в базе применены
+// не все миграции, встроенные в бинарник.
+func CheckSchema(ctx context.Context, migrator *goose.Provider) error {
+	current, target, err := migrator.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get schema version: %w", err)
+	}
+
+	if current < target {
+		return fmt.Errorf("%w: version %d, expected %d, run `arevbond migrate up` or set MIGRATE_ON_START=true",
+			ErrSchemaOutdated, current, target)
+	}
+
+	return nil
+}
@@ -5,33 +5,54 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 )
 
 type Auth interface {
-	VerifyJWT(tokenStr string) (bool, error)
+	VerifyJWT(ctx context.Context, tokenStr string) (*authdomain.Principal, error)
+	Refresh(ctx context.Context, refreshToken, userAgent string) (authdomain.Tokens, *authdomain.Principal, error)
+}
+
+// TOTPStatus сообщает, настроен ли у пользователя второй фактор.
+type TOTPStatus interface {
+	TOTPEnabled(ctx context.Context, userID int) (bool, error)
+}
+
+// TokenAuth проверяет API токены из заголовка Authorization.
+type TokenAuth interface {
+	VerifyAPIToken(ctx context.Context, tokenStr string) (*authdomain.Principal, error)
 }
 
 type contextKey string
 
-const IsAdminKey contextKey = "is-admin"
+const PrincipalKey contextKey = "principal"
+
+// PrincipalFrom возвращает пользователя запроса или nil для анонимного посетителя.
+func PrincipalFrom(ctx context.Context) *authdomain.Principal {
+	principal, _ := ctx.Value(PrincipalKey).(*authdomain.Principal)
+
+	return principal
+}
+
+const (
+	TokenCookieName   = "token"
+	RefreshCookieName = "refresh_token"
+)
 
 func RequireAuth(auth Auth, log *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		authHandler := func(w http.ResponseWriter, r *http.Request) {
-			token, err := r.Cookie("token")
-			if err != nil {
-				switch {
-				case errors.Is(err, http.ErrNoCookie):
-					http.Error(w, "cookie not found", http.StatusBadRequest)
-				default:
-					log.Error("can't get cookie", slog.Any("error", err))
-					http.Error(w, "server error", http.StatusInternalServerError)
-				}
+			if !hasAuthCookies(r) {
+				http.Error(w, "cookie not found", http.StatusBadRequest)
 
 				return
 			}
 
-			isValid, err := auth.VerifyJWT(token.Value)
+			principal, err := authenticate(w, r, auth)
 			if err != nil {
 				log.Error("can't verify jwt", slog.Any("error", err))
 				http.Error(w, "server error", http.StatusInternalServerError)
@@ -39,14 +60,13 @@ func RequireAuth(auth Auth, log *slog.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
-			if !isValid {
+			if principal == nil {
 				http.Error(w, "access denied", http.StatusForbidden)
 
 				return
 			}
 
-			// Only admin can create JWTs, so any valid JWT = admin user
-			newCtx := context.WithValue(r.Context(), IsAdminKey, true)
+			newCtx := context.WithValue(r.Context(), PrincipalKey, principal)
 
 			next.ServeHTTP(w, r.WithContext(newCtx))
 		}
@@ -55,17 +75,101 @@ func RequireAuth(auth Auth, log *slog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// RequireToken пропускает запросы с действующим API токеном в заголовке
+// Authorization: Bearer, у которого есть scope.
+func RequireToken(auth TokenAuth, log *slog.Logger, scope authdomain.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		tokenHandler := func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				http.Error(w, "bearer token required", http.StatusUnauthorized)
+
+				return
+			}
+
+			principal, err := auth.VerifyAPIToken(r.Context(), token)
+			if err != nil {
+				log.Error("can't verify api token", slog.Any("error", err))
+				http.Error(w, "server error", http.StatusInternalServerError)
+
+				return
+			}
+
+			if principal == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+
+				return
+			}
+
+			if !principal.Allows(scope) {
+				w.Header().Set("WWW-Authenticate",
+					`Bearer realm="api", error="insufficient_scope", scope="`+string(scope)+`"`)
+				http.Error(w, "insufficient scope", http.StatusForbidden)
+
+				return
+			}
+
+			newCtx := context.WithValue(r.Context(), PrincipalKey, principal)
+
+			next.ServeHTTP(w, r.WithContext(newCtx))
+		}
+
+		return http.HandlerFunc(tokenHandler)
+	}
+}
+
+// RequireAuthOrToken выбирает проверку по запросу: с заголовком Authorization
+// работает RequireToken, без него - RequireAuth по cookie.
+func RequireAuthOrToken(
+	auth interface {
+		Auth
+		TokenAuth
+	},
+	log *slog.Logger,
+	scope authdomain.Scope,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		byToken := RequireToken(auth, log, scope)(next)
+		byCookie := RequireAuth(auth, log)(next)
+
+		dispatchHandler := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := BearerToken(r); ok {
+				byToken.ServeHTTP(w, r)
+
+				return
+			}
+
+			byCookie.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(dispatchHandler)
+	}
+}
+
+// BearerToken достаёт токен из заголовка Authorization: Bearer.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
 func OptionalAuth(auth Auth, log *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		authHandler := func(w http.ResponseWriter, r *http.Request) {
-			token, err := r.Cookie("token")
-			if err != nil {
+			if !hasAuthCookies(r) {
 				next.ServeHTTP(w, r)
 
 				return
 			}
 
-			isValid, err := auth.VerifyJWT(token.Value)
+			principal, err := authenticate(w, r, auth)
 			if err != nil {
 				log.Error("can't verify jwt", slog.Any("error", err))
 				next.ServeHTTP(w, r)
@@ -73,8 +177,8 @@ func OptionalAuth(auth Auth, log *slog.Logger) func(http.Handler) http.Handler {
 				return
 			}
 
-			if isValid {
-				newCtx := context.WithValue(r.Context(), IsAdminKey, true)
+			if principal != nil {
+				newCtx := context.WithValue(r.Context(), PrincipalKey, principal)
 				next.ServeHTTP(w, r.WithContext(newCtx))
 
 				return
@@ -86,3 +190,153 @@ func OptionalAuth(auth Auth, log *slog.Logger) func(http.Handler) http.Handler {
 		return http.HandlerFunc(authHandler)
 	}
 }
+
+func hasAuthCookies(r *http.Request) bool {
+	_, tokenErr := r.Cookie(TokenCookieName)
+	_, refreshErr := r.Cookie(RefreshCookieName)
+
+	return tokenErr == nil || refreshErr == nil
+}
+
+// authenticate проверяет access токен, а если он истёк - прозрачно
+// обновляет пару токенов по refresh токену и выставляет новые cookie.
+func authenticate(w http.ResponseWriter, r *http.Request, auth Auth) (*authdomain.Principal, error) {
+	if token, err := r.Cookie(TokenCookieName); err == nil {
+		principal, err := auth.VerifyJWT(r.Context(), token.Value)
+		if err != nil || principal != nil {
+			return principal, err
+		}
+	}
+
+	refreshToken, err := r.Cookie(RefreshCookieName)
+	if err != nil {
+		return nil, nil
+	}
+
+	tokens, principal, err := auth.Refresh(r.Context(), refreshToken.Value, r.UserAgent())
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalid) {
+			ClearAuthCookies(w)
+
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	SetAuthCookies(w, tokens)
+
+	return principal, nil
+}
+
+// SetAuthCookies сохраняет токены в cookie. Cookie access токена живёт
+// столько же, сколько refresh, чтобы по ней можно было завершить сессию.
+func SetAuthCookies(w http.ResponseWriter, tokens authdomain.Tokens) {
+	maxAge := int(authdomain.RefreshTokenTTL.Seconds())
+
+	setCookie(w, TokenCookieName, tokens.Access, maxAge)
+
+	if tokens.Refresh != "" {
+		setCookie(w, RefreshCookieName, tokens.Refresh, maxAge)
+	}
+}
+
+func ClearAuthCookies(w http.ResponseWriter) {
+	setCookie(w, TokenCookieName, "", -1)
+	setCookie(w, RefreshCookieName, "", -1)
+}
+
+func setCookie(w http.ResponseWriter, name, value string, maxAge int) {
+	//nolint: exhaustruct // default cookie struct
+	cookie := http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	http.SetCookie(w, &cookie)
+}
+
+// RequireRole пропускает пользователей с ролью не ниже role.
+// Ставится после RequireAuth.
+func RequireRole(role authdomain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		roleHandler := func(w http.ResponseWriter, r *http.Request) {
+			principal := PrincipalFrom(r.Context())
+			if principal == nil || !principal.Role.AtLeast(role) {
+				http.Error(w, "insufficient role", http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(roleHandler)
+	}
+}
+
+// RequireFreshMFA пропускает только сессии, в которых второй фактор вводился
+// недавно. Ставится после RequireAuth на опасные действия.
+func RequireFreshMFA() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		mfaHandler := func(w http.ResponseWriter, r *http.Request) {
+			principal := PrincipalFrom(r.Context())
+			if principal == nil {
+				http.Error(w, "access denied", http.StatusForbidden)
+
+				return
+			}
+
+			if !principal.FreshMFA(time.Now()) {
+				http.Error(w, "fresh second factor required", http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(mfaHandler)
+	}
+}
+
+// RequireFreshMFAIfEnrolled - RequireFreshMFA для пользователей, у которых
+// настроен второй фактор. Остальных пропускает: ввести код им нечем, а
+// настройку 2FA можно потребовать через REQUIRE_TOTP.
+func RequireFreshMFAIfEnrolled(totp TOTPStatus, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		mfaHandler := func(w http.ResponseWriter, r *http.Request) {
+			principal := PrincipalFrom(r.Context())
+			if principal == nil {
+				http.Error(w, "access denied", http.StatusForbidden)
+
+				return
+			}
+
+			if !principal.FreshMFA(time.Now()) {
+				enrolled, err := totp.TOTPEnabled(r.Context(), principal.UserID)
+				if err != nil {
+					log.Error("can't check totp", slog.Any("error", err))
+					http.Error(w, "server error", http.StatusInternalServerError)
+
+					return
+				}
+
+				if enrolled {
+					http.Error(w, "fresh second factor required", http.StatusForbidden)
+
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(mfaHandler)
+	}
+}
@@ -0,0 +1,356 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+
+	// brotliLevel - быстрый уровень для ответов, которые сжимаются на лету.
+	brotliLevel = 4
+)
+
+// Сжатие на лету выделяет большие буферы, поэтому писатели переиспользуются.
+//
+//nolint:gochecknoglobals // pools of compressors
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+
+		return w
+	}}
+	brotliWriters = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotliLevel)
+	}}
+)
+
+// Compress сжимает ответы brotli или gzip, смотря что принимает клиент.
+// Ответ копится в буфере, пока не наберёт minSize байт: короткие ответы
+// отдаются как есть, сжатие там только добавляет заголовки и время.
+//
+// К ETag сжатого ответа добавляется сжатие, см. TrimETagEncoding.
+//
+// Сжимаются только текстовые типы: PNG, JPEG и другие уже сжатые форматы
+// идут без изменений. Ответы, у которых уже есть Content-Encoding, например
+// заранее сжатая статика, тоже не трогаются.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		compressHandler := func(w http.ResponseWriter, r *http.Request) {
+			// без подходящего сжатия ответ всё равно проходит через
+			// compressWriter, чтобы кэши получили Vary: Accept-Encoding
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       AcceptedEncoding(r),
+				minSize:        minSize,
+				status:         0,
+				buf:            nil,
+				encoder:        nil,
+				passthrough:    false,
+			}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		}
+
+		return http.HandlerFunc(compressHandler)
+	}
+}
+
+// AcceptedEncoding выбирает сжатие по Accept-Encoding: brotli, если клиент
+// ценит его не меньше gzip, иначе gzip. Пустая строка - сжимать нельзя.
+func AcceptedEncoding(r *http.Request) string {
+	header := r.Header.Get("Accept-Encoding")
+	if header == "" {
+		return ""
+	}
+
+	weights := map[string]float64{}
+
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(item, ";")
+
+		weight := 1.0
+
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+
+			weight = parsed
+		}
+
+		weights[strings.ToLower(strings.TrimSpace(name))] = weight
+	}
+
+	weight := func(name string) float64 {
+		if value, ok := weights[name]; ok {
+			return value
+		}
+
+		return weights["*"]
+	}
+
+	br, gz := weight(EncodingBrotli), weight(EncodingGzip)
+
+	switch {
+	case br > 0 && br >= gz:
+		return EncodingBrotli
+	case gz > 0:
+		return EncodingGzip
+	default:
+		return ""
+	}
+}
+
+// Compressible сообщает, есть ли смысл сжимать содержимое такого типа.
+func Compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	default:
+		return false
+	}
+}
+
+// AddVary добавляет value в Vary, если его там ещё нет.
+func AddVary(header http.Header, value string) {
+	for _, line := range header.Values("Vary") {
+		for _, existing := range strings.Split(line, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), value) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", value)
+}
+
+// etagWithEncoding добавляет к ETag сжатие: "v3" -> "v3-br", как у заранее
+// сжатой статики.
+func etagWithEncoding(etag, encoding string) string {
+	opaque, found := strings.CutSuffix(etag, `"`)
+	if !found {
+		return etag
+	}
+
+	return opaque + "-" + encoding + `"`
+}
+
+// TrimETagEncoding убирает из ETag сжатие, которое добавил Compress, чтобы
+// сравнить присланный клиентом ETag с ETag обработчика.
+func TrimETagEncoding(etag string) string {
+	for _, encoding := range []string{EncodingBrotli, EncodingGzip} {
+		if opaque, found := strings.CutSuffix(etag, "-"+encoding+`"`); found {
+			return opaque + `"`
+		}
+	}
+
+	return etag
+}
+
+// compressWriter откладывает заголовки, пока не станет ясно, сжимать ли
+// ответ: это зависит от типа и размера тела.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string // пусто, если клиент не принимает сжатие
+	minSize  int
+
+	status      int // 0 - обработчик ещё не выставил статус
+	buf         []byte
+	encoder     io.WriteCloser // не nil, когда ответ сжимается
+	passthrough bool           // заголовки отправлены, тело идёт без сжатия
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 || cw.passthrough || cw.encoder != nil {
+		return
+	}
+
+	// 1xx отправляются сразу и не заканчивают ответ
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+
+		return
+	}
+
+	cw.status = status
+
+	if !cw.canCompress() {
+		cw.startPassthrough()
+	}
+}
+
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	if cw.status == 0 && !cw.passthrough && cw.encoder == nil {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	switch {
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(data) //nolint:wrapcheck // transparent writer
+	case cw.encoder != nil:
+		return cw.encoder.Write(data) //nolint:wrapcheck // transparent writer
+	}
+
+	cw.buf = append(cw.buf, data...)
+
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+// Flush отправляет то, что накоплено: потоковые ответы сжимаются, не
+// дожидаясь minSize.
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 && !cw.passthrough && cw.encoder == nil {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.passthrough && cw.encoder == nil {
+		_ = cw.start()
+	}
+
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// canCompress проверяет то, что известно по заголовкам. Тип без заголовка
+// определяется по началу тела, как это делает net/http.
+func (cw *compressWriter) canCompress() bool {
+	header := cw.Header()
+
+	switch {
+	case cw.status == http.StatusNoContent, cw.status == http.StatusNotModified,
+		cw.status == http.StatusPartialContent:
+		return false
+	case header.Get("Content-Encoding") != "", header.Get("Content-Range") != "",
+		strings.Contains(header.Get("Cache-Control"), "no-transform"):
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+
+	return contentType == "" || Compressible(contentType)
+}
+
+// start отправляет заголовки и накопленное тело, сжатое или нет.
+func (cw *compressWriter) start() error {
+	header := cw.Header()
+	if header.Get("Content-Type") == "" {
+		// после Content-Encoding net/http определял бы тип по сжатым байтам
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.encoding == "" || !Compressible(header.Get("Content-Type")) {
+		cw.startPassthrough()
+
+		return cw.writeBuffered(cw.ResponseWriter)
+	}
+
+	AddVary(header, "Accept-Encoding")
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+
+	// сжатое тело отличается побайтно, поэтому у него свой ETag. Он остаётся
+	// сильным: API сверяет его в If-Match, как и несжатый.
+	if etag := header.Get("ETag"); etag != "" {
+		header.Set("ETag", etagWithEncoding(etag, cw.encoding))
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	switch cw.encoding {
+	case EncodingBrotli:
+		encoder := brotliWriters.Get().(*brotli.Writer) //nolint:forcetypeassert // pool of brotli writers
+		encoder.Reset(cw.ResponseWriter)
+		cw.encoder = encoder
+	default:
+		encoder := gzipWriters.Get().(*gzip.Writer) //nolint:forcetypeassert // pool of gzip writers
+		encoder.Reset(cw.ResponseWriter)
+		cw.encoder = encoder
+	}
+
+	return cw.writeBuffered(cw.encoder)
+}
+
+func (cw *compressWriter) startPassthrough() {
+	cw.passthrough = true
+
+	if Compressible(cw.Header().Get("Content-Type")) && cw.Header().Get("Content-Encoding") == "" {
+		// короткий ответ того же адреса может в другой раз оказаться длиннее
+		AddVary(cw.Header(), "Accept-Encoding")
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressWriter) writeBuffered(w io.Writer) error {
+	buf := cw.buf
+	cw.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := w.Write(buf)
+
+	return err //nolint:wrapcheck // transparent writer
+}
+
+// close дописывает ответ после обработчика.
+func (cw *compressWriter) close() {
+	switch {
+	case cw.encoder != nil:
+		_ = cw.encoder.Close()
+
+		switch encoder := cw.encoder.(type) {
+		case *brotli.Writer:
+			encoder.Reset(io.Discard)
+			brotliWriters.Put(encoder)
+		case *gzip.Writer:
+			encoder.Reset(io.Discard)
+			gzipWriters.Put(encoder)
+		}
+	case cw.passthrough, cw.status == 0:
+		// обработчик ничего не записал: net/http ответит 200 сам
+	default:
+		if cw.Header().Get("Content-Type") == "" && len(cw.buf) > 0 {
+			cw.Header().Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+
+		cw.startPassthrough()
+		_ = cw.writeBuffered(cw.ResponseWriter)
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+
+	csrfTokenKey  contextKey = "csrf-token"
+	csrfTokenSize            = 32
+	csrfCookieTTL            = 30 * 24 * 60 * 60 // 30 days
+)
+
+// CSRF защищает изменяющие запросы токеном double-submit: токен лежит в cookie
+// и должен прийти обратно в заголовке X-CSRF-Token, который выставляет htmx.
+// Токен подписан secret, поэтому подложить свою cookie через поддомен не
+// получится. Отклонённые запросы передаются в onFailure.
+//
+// Поле формы не принимается: иначе тело, в том числе multipart, пришлось бы
+// разбирать до проверки входа.
+//
+// Запросы с Authorization: Bearer не проверяются: браузер не подставляет
+// этот заголовок сам, а чужой сайт не может его выставить без CORS.
+func CSRF(secret []byte, log *slog.Logger, onFailure http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		csrfHandler := func(w http.ResponseWriter, r *http.Request) {
+			state := &csrfState{w: w, secret: secret, log: log, token: ""}
+			if cookie, err := r.Cookie(CSRFCookieName); err == nil && validCSRFToken(secret, cookie.Value) {
+				state.token = cookie.Value
+			}
+
+			_, bearer := BearerToken(r)
+
+			if !safeMethod(r.Method) && !bearer && !matchCSRFToken(r, state.token) {
+				log.Warn("csrf token mismatch",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Bool("has_cookie", state.token != ""))
+
+				onFailure.ServeHTTP(w, r)
+
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey, state)))
+		}
+
+		return http.HandlerFunc(csrfHandler)
+	}
+}
+
+// csrfState выпускает токен только для страниц, которые его используют,
+// чтобы статика и картинки отдавались без Set-Cookie.
+type csrfState struct {
+	w      http.ResponseWriter
+	secret []byte
+	log    *slog.Logger
+	token  string
+}
+
+// CSRFToken возвращает токен запроса для форм и заголовка hx-headers.
+// Если у посетителя ещё нет токена, выставляет cookie, поэтому вызывается
+// до записи ответа.
+func CSRFToken(ctx context.Context) string {
+	state, ok := ctx.Value(csrfTokenKey).(*csrfState)
+	if !ok {
+		return ""
+	}
+
+	if state.token == "" {
+		token, err := newCSRFToken(state.secret)
+		if err != nil {
+			state.log.Error("can't issue csrf token", slog.Any("error", err))
+
+			return ""
+		}
+
+		state.token = token
+		setCookie(state.w, CSRFCookieName, token, csrfCookieTTL)
+	}
+
+	return state.token
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// matchCSRFToken сверяет токен из заголовка с токеном из cookie.
+func matchCSRFToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(r.Header.Get(CSRFHeaderName)), []byte(token))
+}
+
+func newCSRFToken(secret []byte) (string, error) {
+	buf := make([]byte, csrfTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("can't generate csrf token: %w", err)
+	}
+
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	return nonce + "." + base64.RawURLEncoding.EncodeToString(csrfMAC(secret, nonce)), nil
+}
+
+func validCSRFToken(secret []byte, token string) bool {
+	nonce, signature, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+
+	gotMAC, err := base64.RawURLEncoding.DecodeString(signature)
+
+	return err == nil && hmac.Equal(gotMAC, csrfMAC(secret, nonce))
+}
+
+func csrfMAC(secret []byte, nonce string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("csrf:" + nonce))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSessionAuth пускает по cookie с access токеном, токен - ключ в sessions.
+type fakeSessionAuth struct {
+	Auth
+
+	sessions map[string]*authdomain.Principal
+	totp     map[int]bool
+}
+
+func (f *fakeSessionAuth) VerifyJWT(_ context.Context, token string) (*authdomain.Principal, error) {
+	return f.sessions[token], nil
+}
+
+func (f *fakeSessionAuth) TOTPEnabled(_ context.Context, userID int) (bool, error) {
+	return f.totp[userID], nil
+}
+
+func newAdminMFAServer(t *testing.T) http.Handler {
+	t.Helper()
+
+	admin := func(userID int, amr ...string) *authdomain.Principal {
+		return &authdomain.Principal{
+			UserID: userID, Username: "admin", Role: authdomain.RoleAdmin,
+			Claims: authdomain.Claims{SessionID: "s", AMR: amr, AuthTime: time.Now()},
+		}
+	}
+
+	auth := &fakeSessionAuth{
+		sessions: map[string]*authdomain.Principal{
+			"enrolled":     admin(1, authdomain.AMRPassword),
+			"fresh":        admin(1, authdomain.AMRPassword, authdomain.AMROTP),
+			"not-enrolled": admin(2, authdomain.AMRPassword),
+		},
+		totp: map[int]bool{1: true},
+	}
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{Auth: auth})
+
+	// маршруты без CSRF: проверяется только порядок middleware
+	mux := http.NewServeMux()
+	srv.registerAPITokensRoutes(mux)
+	srv.registerUsersRoutes(mux)
+
+	return mux
+}
+
+func TestAdminActionsRequireFreshMFA(t *testing.T) {
+	t.Parallel()
+
+	handler := newAdminMFAServer(t)
+
+	call := func(method, target, session string) int {
+		req := httptest.NewRequest(method, target, http.NoBody)
+		req.AddCookie(&http.Cookie{Name: middleware.TokenCookieName, Value: session})
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	// без формы обработчик отвечает 400, значит middleware его пропустил
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/admin/api-tokens", "enrolled"))
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/admin/api-tokens", "fresh"))
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/admin/api-tokens", "not-enrolled"))
+
+	for _, route := range []struct{ method, target string }{
+		{http.MethodPatch, "/admin/users/x/role"},
+		{http.MethodPost, "/admin/users/x/password"},
+		{http.MethodDelete, "/admin/users/x"},
+	} {
+		assert.Equal(t, http.StatusForbidden, call(route.method, route.target, "enrolled"), route.target)
+		assert.Equal(t, http.StatusBadRequest, call(route.method, route.target, "fresh"), route.target)
+		assert.Equal(t, http.StatusBadRequest, call(route.method, route.target, "not-enrolled"), route.target)
+	}
+
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/admin/users", "enrolled"))
+}
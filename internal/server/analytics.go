@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/domain"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+)
+
+type Analytics interface {
+	TrackView(view domain.PageView)
+	Dashboard(ctx context.Context, days int) (*domain.Dashboard, error)
+}
+
+func (s *Server) registerAnalyticsRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/analytics", middleware.RequireAuth(s.Auth, s.log)(
+		middleware.RequireRole(authdomain.RoleEditor)(http.HandlerFunc(s.analyticsPage))))
+}
+
+// trackView учитывает просмотр страницы. Просмотры вошедших пользователей не считаются.
+func (s *Server) trackView(r *http.Request, postID int) {
+	if s.Analytics == nil || middleware.PrincipalFrom(r.Context()) != nil {
+		return
+	}
+
+	s.Analytics.TrackView(domain.PageView{
+		Path:      r.URL.Path,
+		PostID:    postID,
+		Referrer:  r.Referer(),
+		IP:        s.clientIP(r),
+		UserAgent: r.UserAgent(),
+		At:        time.Now(),
+	})
+}
+
+func (s *Server) analyticsPage(w http.ResponseWriter, r *http.Request) {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 || days > maxAnalyticsDays {
+		days = defaultAnalyticsDays
+	}
+
+	dashboard, err := s.Analytics.Dashboard(r.Context(), days)
+	if err != nil {
+		s.renderError(w, "can't get analytics", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData := AnalyticsPageData{
+		Days:      days,
+		Since:     dashboard.Since.Format("02.01.2006"),
+		Posts:     dashboard.Posts,
+		Referrers: dashboard.Referrers,
+		Chart:     make([]ChartBar, 0, len(dashboard.Daily)),
+		Cache:     s.Blog.CacheStats(),
+		CSPNonce:  middleware.CSPNonce(r.Context()),
+	}
+
+	var maxViews int
+
+	for _, day := range dashboard.Daily {
+		tmplData.TotalViews += day.Views
+		tmplData.TotalVisitors += day.Visitors
+		maxViews = max(maxViews, day.Views)
+	}
+
+	for _, day := range dashboard.Daily {
+		bar := ChartBar{Label: day.Day.Format("02.01"), Views: day.Views, Visitors: day.Visitors, Height: 0}
+		if maxViews > 0 {
+			bar.Height = day.Views * 100 / maxViews //nolint: mnd // percent
+		}
+
+		tmplData.Chart = append(tmplData.Chart, bar)
+	}
+
+	s.renderTemplate(w, "analytics.html", tmplData)
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	blogdomain "github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+// openAPISpec описывает /api/v1. Тест сверяет его с apiRoutes, так что
+// новый маршрут без описания не пройдёт CI.
+//
+//go:embed openapi/v1.json
+var openAPISpec []byte
+
+const (
+	apiPrefix = "/api/v1"
+	// 1MB, как и у загрузки поста файлом
+	maxAPIBodySize = 1_000_000
+)
+
+// apiRoute - маршрут JSON API. Маршруты без scope доступны всем, остальные
+// требуют сессию или API токен с этим scope и роль не ниже role.
+type apiRoute struct {
+	method  string
+	path    string
+	scope   authdomain.Scope
+	role    authdomain.Role
+	handler http.HandlerFunc
+}
+
+func (s *Server) apiRoutes() []apiRoute {
+	var (
+		noScope authdomain.Scope
+		anyRole = authdomain.RoleAuthor
+	)
+
+	return []apiRoute{
+		{http.MethodGet, "/posts", noScope, anyRole, s.apiListPosts},
+		{http.MethodPost, "/posts", authdomain.ScopePostsWrite, anyRole, s.apiCreatePost},
+		{http.MethodGet, "/posts/{id}", noScope, anyRole, s.apiGetPost},
+		{http.MethodGet, "/posts/by-slug/{slug}", noScope, anyRole, s.apiGetPostBySlug},
+		{http.MethodPut, "/posts/{id}", authdomain.ScopePostsWrite, anyRole, s.apiUpdatePost},
+		{http.MethodDelete, "/posts/{id}", authdomain.ScopePostsWrite, anyRole, s.apiDeletePost},
+		{http.MethodPost, "/posts/{id}/publish", authdomain.ScopePostsPublish, authdomain.RoleEditor, s.apiPublishPost},
+		{http.MethodPost, "/posts/{id}/unpublish", authdomain.ScopePostsPublish, authdomain.RoleEditor, s.apiUnpublishPost},
+
+		{http.MethodGet, "/categories", noScope, anyRole, s.apiListCategories},
+		{http.MethodPost, "/categories", authdomain.ScopePostsWrite, authdomain.RoleEditor, s.apiCreateCategory},
+		{http.MethodGet, "/categories/{id}", noScope, anyRole, s.apiGetCategory},
+		{http.MethodPut, "/categories/{id}", authdomain.ScopePostsWrite, authdomain.RoleEditor, s.apiUpdateCategory},
+		{http.MethodDelete, "/categories/{id}", authdomain.ScopePostsWrite, authdomain.RoleEditor, s.apiDeleteCategory},
+	}
+}
+
+func (s *Server) registerAPIRoutes(mux *http.ServeMux) {
+	for _, route := range s.apiRoutes() {
+		mux.Handle(route.method+" "+apiPrefix+route.path, s.apiAuth(route))
+	}
+
+	mux.HandleFunc("GET "+apiPrefix+"/openapi.json", s.apiSpec)
+	// без метода шаблон конфликтует с "GET /", поэтому неизвестные пути
+	// перехватываются для каждого метода отдельно
+	notFound := func(w http.ResponseWriter, _ *http.Request) {
+		s.writeAPIError(w, http.StatusNotFound, "not_found", "unknown endpoint", nil)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		mux.HandleFunc(method+" "+apiPrefix+"/", notFound)
+	}
+}
+
+func (s *Server) apiSpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := w.Write(openAPISpec); err != nil {
+		s.log.Error("can't write openapi spec", slog.Any("error", err))
+	}
+}
+
+// apiAuth определяет пользователя по API токену или cookie сессии и
+// проверяет права маршрута. Ошибки, в отличие от RequireToken, отдаются в JSON.
+func (s *Server) apiAuth(route apiRoute) http.Handler {
+	checkAccess := func(w http.ResponseWriter, r *http.Request) {
+		principal := middleware.PrincipalFrom(r.Context())
+
+		if route.scope != "" {
+			switch {
+			case principal == nil:
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				s.writeAPIError(w, http.StatusUnauthorized, "unauthorized", "authentication required", nil)
+
+				return
+			case !principal.Allows(route.scope):
+				w.Header().Set("WWW-Authenticate",
+					`Bearer realm="api", error="insufficient_scope", scope="`+string(route.scope)+`"`)
+				s.writeAPIError(w, http.StatusForbidden, "insufficient_scope",
+					"token lacks scope "+string(route.scope), nil)
+
+				return
+			case !principal.Role.AtLeast(route.role):
+				s.writeAPIError(w, http.StatusForbidden, "forbidden", "insufficient role", nil)
+
+				return
+			}
+		}
+
+		route.handler(w, r)
+	}
+
+	byCookie := middleware.OptionalAuth(s.Auth, s.log)(http.HandlerFunc(checkAccess))
+
+	authHandler := func(w http.ResponseWriter, r *http.Request) {
+		token, ok := middleware.BearerToken(r)
+		if !ok {
+			byCookie.ServeHTTP(w, r)
+
+			return
+		}
+
+		principal, err := s.Auth.VerifyAPIToken(r.Context(), token)
+		if err != nil {
+			s.apiServerError(w, "can't verify api token", err)
+
+			return
+		}
+
+		if principal == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			s.writeAPIError(w, http.StatusUnauthorized, "invalid_token", "token is invalid, expired or revoked", nil)
+
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), middleware.PrincipalKey, principal)
+
+		checkAccess(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(authHandler)
+}
+
+// apiErrorBody - единый формат ошибок API.
+type apiErrorBody struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields - сообщения к полям запроса, которые не прошли проверку
+	Fields map[string]string `json:"fields,omitempty"`
+	// Details - подробности ошибки, формат зависит от code
+	Details any `json:"details,omitempty"`
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.log.Error("can't encode json response", slog.Any("error", err))
+	}
+}
+
+func (s *Server) writeAPIError(w http.ResponseWriter, status int, code, message string, fields map[string]string) {
+	s.writeJSON(w, status, apiErrorBody{Error: apiError{Code: code, Message: message, Fields: fields, Details: nil}})
+}
+
+func (s *Server) apiServerError(w http.ResponseWriter, message string, err error) {
+	s.log.Error(message, slog.Any("error", err))
+	s.writeAPIError(w, http.StatusInternalServerError, "internal", "internal server error", nil)
+}
+
+// apiServiceError переводит ошибку сервиса в HTTP статус по её типу.
+func (s *Server) apiServiceError(w http.ResponseWriter, message string, err error) {
+	var invalidPost *blogdomain.ValidationError
+
+	switch {
+	case errors.As(err, &invalidPost):
+		s.validationFailed(w, invalidPost.Fields)
+	case errors.Is(err, errs.ErrNotFound):
+		s.writeAPIError(w, http.StatusNotFound, "not_found", "resource not found", nil)
+	case errors.Is(err, errs.ErrDuplicate):
+		s.writeAPIError(w, http.StatusConflict, "duplicate", "resource already exists", nil)
+	case errors.Is(err, errs.ErrConflict):
+		s.writeAPIError(w, http.StatusConflict, "conflict", "resource conflicts with its current state", nil)
+	case errors.Is(err, errs.ErrInvalid):
+		// тексты ошибок проверки в сервисах написаны для людей
+		s.writeAPIError(w, http.StatusUnprocessableEntity, "invalid", err.Error(), nil)
+	default:
+		s.apiServerError(w, message, err)
+	}
+}
+
+// decodeJSON читает тело запроса. Неизвестные поля - ошибка: опечатка
+// в имени поля не должна молча терять данные.
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		s.writeAPIError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("invalid json body: %v", err), nil)
+
+		return false
+	}
+
+	return true
+}
+
+// validationFailed отвечает 422 со списком ошибок по полям.
+func (s *Server) validationFailed(w http.ResponseWriter, fields map[string]string) {
+	s.writeAPIError(w, http.StatusUnprocessableEntity, "validation_failed", "request validation failed", fields)
+}
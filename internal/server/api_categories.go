@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+type apiCategory struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiCategoriesList struct {
+	Categories []apiCategory `json:"categories"`
+}
+
+// apiCategoryInput - тело POST и PUT /categories.
+type apiCategoryInput struct {
+	Name string `json:"name"`
+}
+
+func newAPICategory(category *domain.Category) apiCategory {
+	return apiCategory{ID: category.ID, Name: category.Name}
+}
+
+func (s *Server) apiListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.Blog.Categories(r.Context())
+	if err != nil {
+		s.apiServiceError(w, "can't get categories", err)
+
+		return
+	}
+
+	list := apiCategoriesList{Categories: make([]apiCategory, 0, len(categories))}
+	for _, category := range categories {
+		list.Categories = append(list.Categories, newAPICategory(category))
+	}
+
+	s.writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) apiGetCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := s.apiPathID(w, r)
+	if !ok {
+		return
+	}
+
+	category, err := s.Blog.Category(r.Context(), categoryID)
+	if err != nil {
+		s.apiServiceError(w, "can't get category", err)
+
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, newAPICategory(category))
+}
+
+func (s *Server) apiCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var input apiCategoryInput
+	if !s.decodeJSON(w, r, &input) {
+		return
+	}
+
+	category, err := s.Blog.CreateCategory(r.Context(), input.Name)
+	if err != nil {
+		s.apiServiceError(w, "can't create category", err)
+
+		return
+	}
+
+	w.Header().Set("Location", apiPrefix+"/categories/"+strconv.Itoa(category.ID))
+	s.writeJSON(w, http.StatusCreated, newAPICategory(category))
+}
+
+func (s *Server) apiUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := s.apiPathID(w, r)
+	if !ok {
+		return
+	}
+
+	var input apiCategoryInput
+	if !s.decodeJSON(w, r, &input) {
+		return
+	}
+
+	if err := s.Blog.RenameCategory(r.Context(), categoryID, input.Name); err != nil {
+		s.apiServiceError(w, "can't rename category", err)
+
+		return
+	}
+
+	category, err := s.Blog.Category(r.Context(), categoryID)
+	if err != nil {
+		s.apiServiceError(w, "can't get category", err)
+
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, newAPICategory(category))
+}
+
+// apiDeleteCategory удаляет категорию. Для категории с постами - 409.
+func (s *Server) apiDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, ok := s.apiPathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.Blog.DeleteCategory(r.Context(), categoryID); err != nil {
+		s.apiServiceError(w, "can't delete category", err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPIMatchesRoutes не даёт описанию API разойтись с обработчиками.
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	t.Parallel()
+
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+
+	documented := make(map[string]bool)
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+
+	for _, route := range srv.apiRoutes() {
+		key := route.method + " " + route.path
+		assert.True(t, documented[key], "route %s is not described in openapi/v1.json", key)
+
+		delete(documented, key)
+	}
+
+	assert.Empty(t, documented, "openapi/v1.json describes routes without handlers")
+}
+
+type fakeAPIBlog struct {
+	Blog
+
+	posts      map[int]*domain.Post
+	lastFilter domain.PostsFilter
+}
+
+func (f *fakeAPIBlog) ListPosts(_ context.Context, filter domain.PostsFilter) ([]*domain.Post, *domain.PostsCursor, error) {
+	f.lastFilter = filter
+
+	return []*domain.Post{f.posts[1]}, domain.CursorOf(f.posts[1]), nil
+}
+
+func (f *fakeAPIBlog) Post(_ context.Context, id int) (*domain.Post, error) {
+	post, ok := f.posts[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return post, nil
+}
+
+func (f *fakeAPIBlog) CreatePost(_ context.Context, params domain.CreatePostParams) (*domain.Post, error) {
+	if fields := params.Validate(); len(fields) > 0 {
+		return nil, &domain.ValidationError{Fields: fields}
+	}
+
+	return nil, errs.ErrDuplicate
+}
+
+// UpdatePost проверяет версию, как хранилище: 0 - без проверки.
+func (f *fakeAPIBlog) UpdatePost(_ context.Context, params domain.UpdatePostParams) error {
+	post := f.posts[params.ID]
+	if params.Version != 0 && params.Version != post.Version {
+		return &domain.VersionConflictError{Current: post, Changes: params.Diff(post)}
+	}
+
+	post.Title = params.Title
+	post.Version++
+
+	return nil
+}
+
+type fakeAPIAuth struct {
+	Auth
+
+	tokens map[string]*authdomain.Principal
+}
+
+func (f *fakeAPIAuth) VerifyAPIToken(_ context.Context, token string) (*authdomain.Principal, error) {
+	return f.tokens[token], nil
+}
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+
+	blog := &fakeAPIBlog{posts: map[int]*domain.Post{
+		1: {ID: 1, Title: "Published", Slug: "published", IsPublished: true, Version: 1},
+		2: {ID: 2, Title: "Draft", Slug: "draft", IsPublished: false, AuthorID: 2, Version: 4},
+	}}
+
+	auth := &fakeAPIAuth{tokens: map[string]*authdomain.Principal{
+		"abt_read":   {UserID: 2, Role: authdomain.RoleAuthor, Scopes: []authdomain.Scope{authdomain.ScopePostsRead}},
+		"abt_other":  {UserID: 3, Role: authdomain.RoleAuthor, Scopes: []authdomain.Scope{authdomain.ScopePostsRead}},
+		"abt_editor": {UserID: 4, Role: authdomain.RoleEditor, Scopes: []authdomain.Scope{authdomain.ScopePostsRead}},
+		"abt_write": {
+			UserID: 2, Role: authdomain.RoleAuthor,
+			Scopes: []authdomain.Scope{authdomain.ScopePostsRead, authdomain.ScopePostsWrite},
+		},
+	}}
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{Blog: blog, Auth: auth})
+	srv.ConfigureRoutes()
+
+	call := func(method, target, token, body string, headers ...string) (*httptest.ResponseRecorder, apiErrorBody) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		var errBody apiErrorBody
+		if rr.Code >= http.StatusBadRequest {
+			assert.Contains(t, rr.Header().Get("Content-Type"), "application/json")
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errBody))
+		}
+
+		return rr, errBody
+	}
+
+	// анонимам видны только опубликованные посты
+	rr, _ := call(http.MethodGet, "/api/v1/posts?limit=5", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, domain.PostStatusPublished, blog.lastFilter.Status)
+	assert.Equal(t, 5, blog.lastFilter.Limit)
+
+	var page apiPostsPage
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	require.Len(t, page.Posts, 1)
+	assert.Nil(t, page.Posts[0].Content)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// авторам видны только свои черновики, редакторам - все
+	rr, _ = call(http.MethodGet, "/api/v1/posts", "abt_read", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, domain.PostStatusAny, blog.lastFilter.Status)
+	assert.Equal(t, 2, blog.lastFilter.DraftsAuthorID)
+
+	rr, _ = call(http.MethodGet, "/api/v1/posts?status=draft", "abt_editor", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, domain.PostStatusDraft, blog.lastFilter.Status)
+	assert.Equal(t, 0, blog.lastFilter.DraftsAuthorID)
+
+	_, errBody := call(http.MethodGet, "/api/v1/posts?limit=x&cursor=%25", "", "")
+	assert.Equal(t, "validation_failed", errBody.Error.Code)
+	assert.Contains(t, errBody.Error.Fields, "limit")
+	assert.Contains(t, errBody.Error.Fields, "cursor")
+
+	rr, errBody = call(http.MethodGet, "/api/v1/posts/2", "", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "not_found", errBody.Error.Code)
+
+	rr, _ = call(http.MethodGet, "/api/v1/posts/2", "abt_read", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"v4"`, rr.Header().Get("ETag"))
+
+	rr, _ = call(http.MethodGet, "/api/v1/posts/2", "abt_other", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "drafts of other authors are hidden")
+
+	rr, _ = call(http.MethodGet, "/api/v1/posts/2", "abt_editor", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr, errBody = call(http.MethodPost, "/api/v1/posts", "", `{}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "cookie requests without csrf token are rejected")
+	assert.Equal(t, "csrf_rejected", errBody.Error.Code)
+
+	rr, errBody = call(http.MethodPost, "/api/v1/posts", "abt_unknown", `{}`)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "invalid_token", errBody.Error.Code)
+
+	rr, errBody = call(http.MethodPost, "/api/v1/posts", "abt_read", `{}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "insufficient_scope", errBody.Error.Code)
+
+	rr, errBody = call(http.MethodPost, "/api/v1/posts", "abt_write", `{"title": "", "category_id": 1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, map[string]string{"title": "required", "content": "required"}, errBody.Error.Fields)
+
+	// проверки сервиса возвращаются так же, по полям
+	rr, errBody = call(http.MethodPost, "/api/v1/posts", "abt_write",
+		`{"title": "Post", "slug": "a/b", "category_id": 1, "content": "text"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "validation_failed", errBody.Error.Code)
+	assert.Equal(t, map[string]string{"slug": domain.FieldInvalidFormat}, errBody.Error.Fields)
+
+	_, errBody = call(http.MethodPost, "/api/v1/posts", "abt_write", `{"titel": "typo"}`)
+	assert.Equal(t, "bad_request", errBody.Error.Code)
+
+	update := `{"title": "Edited", "category_id": 1, "content": "text"}`
+
+	rr, errBody = call(http.MethodPut, "/api/v1/posts/2", "abt_write", update)
+	assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+	assert.Equal(t, "precondition_required", errBody.Error.Code)
+
+	rr, errBody = call(http.MethodPut, "/api/v1/posts/2", "abt_write", update, "If-Match", `"v3"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Equal(t, "version_conflict", errBody.Error.Code)
+	assert.Equal(t, `"v4"`, rr.Header().Get("ETag"))
+	assert.Contains(t, string(rr.Body.Bytes()), `"current_version":4`)
+	assert.Contains(t, string(rr.Body.Bytes()), `{"field":"title","yours":"Edited","current":"Draft"}`)
+
+	rr, _ = call(http.MethodPut, "/api/v1/posts/2", "abt_write", update, "If-Match", `"v4"`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"v5"`, rr.Header().Get("ETag"))
+
+	// форма не может обойти проверку версии: без неё правка затёрла бы чужую
+	updateForm := func(version string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+
+		form := multipart.NewWriter(&body)
+		require.NoError(t, form.WriteField("title", "Form"))
+		require.NoError(t, form.WriteField("category_id", "1"))
+		require.NoError(t, form.WriteField("version", version))
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPut, "/blog/posts?post_id=2", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer abt_write")
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, updateForm("0").Code)
+	assert.Equal(t, http.StatusBadRequest, updateForm("").Code)
+	assert.Equal(t, "Edited", blog.posts[2].Title)
+
+	assert.Equal(t, http.StatusOK, updateForm("5").Code)
+	assert.Equal(t, "Form", blog.posts[2].Title)
+
+	// любая версия - только по явному If-Match: * в API
+	rr, _ = call(http.MethodPut, "/api/v1/posts/2", "abt_write", update, "If-Match", "*")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"v7"`, rr.Header().Get("ETag"))
+
+	rr, errBody = call(http.MethodDelete, "/api/v1/posts/2", "abt_write", "", "If-Match", `"v4"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Equal(t, "version_conflict", errBody.Error.Code)
+
+	// публиковать могут только редакторы
+	rr, _ = call(http.MethodPost, "/api/v1/posts/2/publish", "abt_write", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr, errBody = call(http.MethodGet, "/api/v1/unknown", "", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "not_found", errBody.Error.Code)
+}
+
+// TestAPIIfMatchCompressed проверяет, что ETag сжатого ответа принимается
+// в If-Match: клиенты с Accept-Encoding получают его от Compress.
+func TestAPIIfMatchCompressed(t *testing.T) {
+	t.Parallel()
+
+	blog := &fakeAPIBlog{posts: map[int]*domain.Post{
+		1: {ID: 1, Title: "Draft", Slug: "draft", AuthorID: 2, Version: 3, Content: []byte(strings.Repeat("текст ", 500))},
+	}}
+
+	auth := &fakeAPIAuth{tokens: map[string]*authdomain.Principal{
+		"abt_write": {
+			UserID: 2, Role: authdomain.RoleAuthor,
+			Scopes: []authdomain.Scope{authdomain.ScopePostsRead, authdomain.ScopePostsWrite},
+		},
+	}}
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{Blog: blog, Auth: auth})
+	srv.ConfigureRoutes()
+
+	call := func(method, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/posts/1", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer abt_write")
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := call(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+	etag := rr.Header().Get("ETag")
+	assert.Equal(t, `"v3-gzip"`, etag)
+
+	update := `{"title": "Edited", "category_id": 1, "content": "text"}`
+
+	rr = call(http.MethodPut, update, "If-Match", etag)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Edited", blog.posts[1].Title)
+
+	// устаревший ETag сжатого ответа по-прежнему даёт 412
+	rr = call(http.MethodPut, update, "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+}
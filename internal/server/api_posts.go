@@ -0,0 +1,511 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+type apiPost struct {
+	ID           int                   `json:"id"`
+	Title        string                `json:"title"`
+	Slug         string                `json:"slug"`
+	Description  string                `json:"description"`
+	URL          string                `json:"url"`
+	CategoryID   int                   `json:"category_id"`
+	CategoryName string                `json:"category_name"`
+	AuthorID     int                   `json:"author_id"`
+	AuthorName   string                `json:"author_name"`
+	IsPublished  bool                  `json:"is_published"`
+	Reactions    domain.ReactionCounts `json:"reactions"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	Version      int                   `json:"version"`
+	// Content - markdown поста, в списках не передаётся
+	Content *string `json:"content,omitempty"`
+}
+
+type apiPostsPage struct {
+	Posts []apiPost `json:"posts"`
+	// NextCursor передаётся в параметре cursor за следующей страницей,
+	// на последней странице отсутствует
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// apiPostUpdate - тело PUT /posts/{id}. Пост заменяется целиком,
+// пустой slug оставляет прежний.
+type apiPostUpdate struct {
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	CategoryID  int    `json:"category_id"`
+	Content     string `json:"content"`
+}
+
+// apiPostCreate - тело POST /posts. Пустой slug строится из заголовка.
+type apiPostCreate struct {
+	apiPostUpdate
+
+	IsPublished bool `json:"is_published"`
+}
+
+func newAPIPost(post *domain.Post, withContent bool) apiPost {
+	result := apiPost{
+		ID:           post.ID,
+		Title:        post.Title,
+		Slug:         post.Slug,
+		Description:  post.Description,
+		URL:          "/blog/posts/" + post.Slug,
+		CategoryID:   post.CategoryID,
+		CategoryName: post.CategoryName,
+		AuthorID:     post.AuthorID,
+		AuthorName:   post.AuthorName,
+		IsPublished:  post.IsPublished,
+		Reactions:    post.Reactions,
+		CreatedAt:    post.CreatedAt,
+		UpdatedAt:    post.UpdatedAt,
+		Version:      post.Version,
+		Content:      nil,
+	}
+
+	if result.Reactions == nil {
+		result.Reactions = domain.ReactionCounts{}
+	}
+
+	if withContent {
+		content := string(post.Content)
+		result.Content = &content
+	}
+
+	return result
+}
+
+// canReadDraft - черновик виден тем, кто может его править, токенам - только с posts:read.
+func canReadDraft(principal *authdomain.Principal, authorID int) bool {
+	return principal.Allows(authdomain.ScopePostsRead) && principal.CanEditPost(authorID)
+}
+
+func (s *Server) apiListPosts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	principal := middleware.PrincipalFrom(r.Context())
+	fields := make(map[string]string)
+
+	filter := domain.PostsFilter{
+		Limit:          0,
+		CategoryID:     queryInt(query.Get("category_id"), "category_id", fields),
+		AuthorID:       queryInt(query.Get("author_id"), "author_id", fields),
+		Status:         domain.PostStatus(query.Get("status")),
+		After:          nil,
+		DraftsAuthorID: 0,
+	}
+
+	if limit := queryInt(query.Get("limit"), "limit", fields); limit < 0 {
+		fields["limit"] = "must be positive"
+	} else {
+		filter.Limit = limit
+	}
+
+	if !filter.Status.Valid() {
+		fields["status"] = "must be one of: published, draft"
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := domain.DecodeCursor(cursor)
+		if err != nil {
+			fields["cursor"] = "malformed cursor"
+		}
+
+		filter.After = after
+	}
+
+	if len(fields) > 0 {
+		s.validationFailed(w, fields)
+
+		return
+	}
+
+	if principal.Allows(authdomain.ScopePostsRead) {
+		_, filter.DraftsAuthorID = draftsVisibleTo(principal)
+	} else {
+		if filter.Status == domain.PostStatusDraft {
+			s.writeAPIError(w, http.StatusForbidden, "forbidden", "drafts require posts:read scope", nil)
+
+			return
+		}
+
+		filter.Status = domain.PostStatusPublished
+	}
+
+	posts, next, err := s.Blog.ListPosts(r.Context(), filter)
+	if err != nil {
+		s.apiServiceError(w, "can't list posts", err)
+
+		return
+	}
+
+	page := apiPostsPage{Posts: make([]apiPost, 0, len(posts)), NextCursor: ""}
+	for _, post := range posts {
+		page.Posts = append(page.Posts, newAPIPost(post, false))
+	}
+
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+
+	s.writeJSON(w, http.StatusOK, page)
+}
+
+// queryInt разбирает необязательный числовой параметр, пустой - 0.
+func queryInt(value, name string, fields map[string]string) int {
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		fields[name] = "must be an integer"
+	}
+
+	return number
+}
+
+func (s *Server) apiGetPost(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.apiFindPost(w, r)
+	if !ok {
+		return
+	}
+
+	s.writePost(w, http.StatusOK, post)
+}
+
+func (s *Server) apiGetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	post, err := s.Blog.PostBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		s.apiServiceError(w, "can't get post by slug", err)
+
+		return
+	}
+
+	if !post.IsPublished && !canReadDraft(middleware.PrincipalFrom(r.Context()), post.AuthorID) {
+		s.writeAPIError(w, http.StatusNotFound, "not_found", "resource not found", nil)
+
+		return
+	}
+
+	s.writePost(w, http.StatusOK, post)
+}
+
+// apiFindPost загружает пост из пути. Черновики для посторонних не существуют.
+func (s *Server) apiFindPost(w http.ResponseWriter, r *http.Request) (*domain.Post, bool) {
+	postID, ok := s.apiPathID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	post, err := s.Blog.Post(r.Context(), postID)
+	if err != nil {
+		s.apiServiceError(w, "can't get post", err)
+
+		return nil, false
+	}
+
+	if !post.IsPublished && !canReadDraft(middleware.PrincipalFrom(r.Context()), post.AuthorID) {
+		s.writeAPIError(w, http.StatusNotFound, "not_found", "resource not found", nil)
+
+		return nil, false
+	}
+
+	return post, true
+}
+
+// apiEditablePost загружает пост из пути и проверяет, что пользователь может его править.
+func (s *Server) apiEditablePost(w http.ResponseWriter, r *http.Request) (*domain.Post, bool) {
+	post, ok := s.apiFindPost(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if !middleware.PrincipalFrom(r.Context()).CanEditPost(post.AuthorID) {
+		s.writeAPIError(w, http.StatusForbidden, "forbidden", "can't edit someone else's post", nil)
+
+		return nil, false
+	}
+
+	return post, true
+}
+
+func (s *Server) apiPathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		s.writeAPIError(w, http.StatusBadRequest, "bad_request", "invalid id in path", nil)
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (s *Server) apiCreatePost(w http.ResponseWriter, r *http.Request) {
+	var input apiPostCreate
+	if !s.decodeJSON(w, r, &input) {
+		return
+	}
+
+	if fields := input.validate(); len(fields) > 0 {
+		s.validationFailed(w, fields)
+
+		return
+	}
+
+	principal := middleware.PrincipalFrom(r.Context())
+
+	if input.IsPublished && (!principal.CanPublish() || !principal.Allows(authdomain.ScopePostsPublish)) {
+		s.writeAPIError(w, http.StatusForbidden, "forbidden", "publishing requires editor role and posts:publish scope", nil)
+
+		return
+	}
+
+	post, err := s.Blog.CreatePost(r.Context(), domain.CreatePostParams{
+		Title:       strings.TrimSpace(input.Title),
+		Slug:        strings.TrimSpace(input.Slug),
+		Description: input.Description,
+		Filename:    "post.md",
+		CategoryID:  input.CategoryID,
+		AuthorID:    principal.UserID,
+		IsPublished: input.IsPublished,
+		Content:     []byte(input.Content),
+	})
+	if err != nil {
+		s.apiServiceError(w, "can't create post", err)
+
+		return
+	}
+
+	// перечитываем пост, чтобы вернуть имена категории и автора
+	s.respondWithPost(w, r, post.ID, http.StatusCreated)
+}
+
+func (s *Server) apiUpdatePost(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.apiEditablePost(w, r)
+	if !ok {
+		return
+	}
+
+	var input apiPostUpdate
+	if !s.decodeJSON(w, r, &input) {
+		return
+	}
+
+	if fields := input.validate(); len(fields) > 0 {
+		s.validationFailed(w, fields)
+
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		s.writeAPIError(w, http.StatusPreconditionRequired, "precondition_required",
+			"send the post ETag in If-Match to avoid overwriting concurrent changes", nil)
+
+		return
+	}
+
+	version, ok := ifMatchVersion(ifMatch)
+	if !ok {
+		s.versionConflict(w, post, nil)
+
+		return
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	if slug == "" {
+		slug = post.Slug
+	}
+
+	err := s.Blog.UpdatePost(r.Context(), domain.UpdatePostParams{
+		ID:          post.ID,
+		Title:       strings.TrimSpace(input.Title),
+		Slug:        slug,
+		Description: input.Description,
+		CategoryID:  input.CategoryID,
+		Content:     []byte(input.Content),
+		Version:     version,
+	})
+
+	var conflict *domain.VersionConflictError
+	if errors.As(err, &conflict) {
+		s.versionConflict(w, conflict.Current, conflict.Changes)
+
+		return
+	}
+
+	if err != nil {
+		s.apiServiceError(w, "can't update post", err)
+
+		return
+	}
+
+	s.respondWithPost(w, r, post.ID, http.StatusOK)
+}
+
+func (s *Server) apiDeletePost(w http.ResponseWriter, r *http.Request) {
+	post, ok := s.apiEditablePost(w, r)
+	if !ok || !s.checkIfMatch(w, r, post) {
+		return
+	}
+
+	if err := s.Blog.DeletePost(r.Context(), post.ID); err != nil {
+		s.apiServiceError(w, "can't delete post", err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiPublishPost(w http.ResponseWriter, r *http.Request) {
+	s.apiSetPublished(w, r, true)
+}
+
+func (s *Server) apiUnpublishPost(w http.ResponseWriter, r *http.Request) {
+	s.apiSetPublished(w, r, false)
+}
+
+// apiSetPublished идемпотентен: повторная публикация ничего не меняет.
+func (s *Server) apiSetPublished(w http.ResponseWriter, r *http.Request, published bool) {
+	post, ok := s.apiFindPost(w, r)
+	if !ok || !s.checkIfMatch(w, r, post) {
+		return
+	}
+
+	if post.IsPublished != published {
+		if err := s.Blog.ChangePublishStatus(r.Context(), post.ID, post.IsPublished); err != nil {
+			s.apiServiceError(w, "can't change publish status", err)
+
+			return
+		}
+	}
+
+	s.respondWithPost(w, r, post.ID, http.StatusOK)
+}
+
+func (s *Server) respondWithPost(w http.ResponseWriter, r *http.Request, postID int, status int) {
+	post, err := s.Blog.Post(r.Context(), postID)
+	if err != nil {
+		s.apiServiceError(w, "can't get post", err)
+
+		return
+	}
+
+	if status == http.StatusCreated {
+		w.Header().Set("Location", apiPrefix+"/posts/"+strconv.Itoa(post.ID))
+	}
+
+	s.writePost(w, status, post)
+}
+
+// writePost отдаёт пост с ETag его версии. ETag передаётся обратно
+// в If-Match при изменении поста.
+func (s *Server) writePost(w http.ResponseWriter, status int, post *domain.Post) {
+	w.Header().Set("ETag", postETag(post.Version))
+	s.writeJSON(w, status, newAPIPost(post, true))
+}
+
+func postETag(version int) string {
+	return `"v` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion разбирает If-Match: "*" - любая версия (0), иначе ETag
+// из postETag, в том числе сжатого ответа. ok = false для чужих и слабых ETag.
+func ifMatchVersion(header string) (int, bool) {
+	header = middleware.TrimETagEncoding(strings.TrimSpace(header))
+	if header == "*" {
+		return 0, true
+	}
+
+	tag, found := strings.CutPrefix(header, `"v`)
+	if !found {
+		return 0, false
+	}
+
+	tag, found = strings.CutSuffix(tag, `"`)
+	if !found {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+type apiFieldChange struct {
+	Field   string `json:"field"`
+	Yours   string `json:"yours"`
+	Current string `json:"current"`
+}
+
+type apiVersionConflict struct {
+	CurrentVersion int              `json:"current_version"`
+	Changes        []apiFieldChange `json:"changes,omitempty"`
+}
+
+// versionConflict отвечает 412: пост изменили после того, как клиент его загрузил.
+// В ответе - актуальная версия и поля, в которых правка расходится с ней.
+func (s *Server) versionConflict(w http.ResponseWriter, current *domain.Post, changes []domain.FieldChange) {
+	details := apiVersionConflict{CurrentVersion: current.Version, Changes: make([]apiFieldChange, 0, len(changes))}
+	for _, change := range changes {
+		details.Changes = append(details.Changes, apiFieldChange(change))
+	}
+
+	w.Header().Set("ETag", postETag(current.Version))
+	s.writeJSON(w, http.StatusPreconditionFailed, apiErrorBody{Error: apiError{
+		Code:    "version_conflict",
+		Message: "post was modified since it was loaded",
+		Fields:  nil,
+		Details: details,
+	}})
+}
+
+// checkIfMatch проверяет необязательный If-Match для удаления и публикации.
+func (s *Server) checkIfMatch(w http.ResponseWriter, r *http.Request, post *domain.Post) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+
+	version, ok := ifMatchVersion(header)
+	if ok && (version == 0 || version == post.Version) {
+		return true
+	}
+
+	s.versionConflict(w, post, nil)
+
+	return false
+}
+
+func (p *apiPostUpdate) validate() map[string]string {
+	fields := make(map[string]string)
+
+	if strings.TrimSpace(p.Title) == "" {
+		fields["title"] = "required"
+	}
+
+	if p.CategoryID <= 0 {
+		fields["category_id"] = "required"
+	}
+
+	if strings.TrimSpace(p.Content) == "" {
+		fields["content"] = "required"
+	}
+
+	return fields
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+type APITokens interface {
+	CreateAPIToken(ctx context.Context, params authdomain.CreateAPITokenParams) (*authdomain.APIToken, string, error)
+	APITokens(ctx context.Context) ([]*authdomain.APIToken, error)
+	RevokeAPIToken(ctx context.Context, id int) error
+}
+
+const defaultAPITokenTTLDays = 90
+
+func (s *Server) registerAPITokensRoutes(mux *http.ServeMux) {
+	requireAdmin := func(h http.Handler) http.Handler {
+		return middleware.RequireAuth(s.Auth, s.log)(middleware.RequireRole(authdomain.RoleAdmin)(h))
+	}
+
+	// токен действует без второго фактора, поэтому выпускается только после свежего кода
+	freshMFA := middleware.RequireFreshMFAIfEnrolled(s.Auth, s.log)
+
+	mux.Handle("GET /admin/api-tokens", requireAdmin(http.HandlerFunc(s.apiTokensPage)))
+	mux.Handle("POST /admin/api-tokens", requireAdmin(freshMFA(http.HandlerFunc(s.createAPIToken))))
+	mux.Handle("DELETE /admin/api-tokens/{id}", requireAdmin(http.HandlerFunc(s.revokeAPIToken)))
+}
+
+func (s *Server) apiTokensPage(w http.ResponseWriter, r *http.Request) {
+	tmplData, err := s.apiTokensData(r, true, "")
+	if err != nil {
+		s.renderError(w, "can't get api tokens", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.renderTemplate(w, "api_tokens.html", tmplData)
+}
+
+func (s *Server) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.renderError(w, "can't parse form", err, http.StatusBadRequest)
+
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("user_id"))
+	if err != nil {
+		s.renderError(w, "invalid user id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	days, err := strconv.Atoi(r.FormValue("ttl_days"))
+	if err != nil {
+		days = defaultAPITokenTTLDays
+	}
+
+	scopes := make([]authdomain.Scope, 0, len(r.Form["scopes"]))
+	for _, scope := range r.Form["scopes"] {
+		scopes = append(scopes, authdomain.Scope(scope))
+	}
+
+	_, plain, err := s.Auth.CreateAPIToken(r.Context(), authdomain.CreateAPITokenParams{
+		UserID: userID,
+		Name:   r.FormValue("name"),
+		Scopes: scopes,
+		TTL:    time.Duration(days) * 24 * time.Hour,
+	})
+
+	s.renderAPITokensResult(w, r, err, plain, "Токен создан. Скопируйте его сейчас, больше он показан не будет.")
+}
+
+func (s *Server) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid token id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	err = s.Auth.RevokeAPIToken(r.Context(), tokenID)
+
+	s.renderAPITokensResult(w, r, err, "", "Токен отозван.")
+}
+
+// renderAPITokensResult перерисовывает таблицу токенов с сообщением о результате действия.
+func (s *Server) renderAPITokensResult(
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+	newToken string,
+	successMsg string,
+) {
+	success, message := true, successMsg
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errs.ErrNotFound):
+		success, message = false, "Токен или пользователь не найден."
+	case errors.Is(err, errs.ErrInvalid):
+		success, message = false, "Укажите название до 100 символов, хотя бы один scope и срок до года."
+	default:
+		s.renderError(w, "can't update api tokens", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData, err := s.apiTokensData(r, success, message)
+	if err != nil {
+		s.renderError(w, "can't get api tokens", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData.NewToken = newToken
+
+	s.renderTemplate(w, "api-tokens-table", tmplData)
+}
+
+func (s *Server) apiTokensData(r *http.Request, success bool, message string) (APITokensPageData, error) {
+	tokens, err := s.Auth.APITokens(r.Context())
+	if err != nil {
+		return APITokensPageData{}, fmt.Errorf("can't get api tokens: %w", err)
+	}
+
+	users, err := s.Auth.Users(r.Context())
+	if err != nil {
+		return APITokensPageData{}, fmt.Errorf("can't get users: %w", err)
+	}
+
+	tmplData := APITokensPageData{
+		CSRFToken: middleware.CSRFToken(r.Context()),
+		Tokens:    make([]APITokenView, 0, len(tokens)),
+		Users:     make([]UserView, 0, len(users)),
+		Scopes:    authdomain.Scopes,
+		Success:   success,
+		Message:   message,
+		NewToken:  "",
+	}
+
+	now := time.Now()
+
+	for _, token := range tokens {
+		view := APITokenView{
+			ID:         token.ID,
+			Name:       token.Name,
+			Username:   token.Username,
+			Prefix:     token.Prefix,
+			Scopes:     token.ScopeList(),
+			CreatedAt:  token.CreatedAt.Format("02.01.2006"),
+			ExpiresAt:  token.ExpiresAt.Format("02.01.2006"),
+			LastUsedAt: "",
+			Active:     token.Active(now),
+		}
+
+		if token.LastUsedAt != nil {
+			view.LastUsedAt = token.LastUsedAt.Format("02.01.2006 15:04")
+		}
+
+		tmplData.Tokens = append(tmplData.Tokens, view)
+	}
+
+	for _, user := range users {
+		tmplData.Users = append(tmplData.Users, UserView{
+			ID:          user.ID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			Role:        user.Role,
+			CreatedAt:   "",
+			Current:     false,
+		})
+	}
+
+	return tmplData, nil
+}
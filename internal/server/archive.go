@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+// maxArchiveSize ограничивает загружаемый архив.
+const maxArchiveSize = 256 << 20
+
+func (s *Server) registerArchiveRoutes(mux *http.ServeMux) {
+	requireAdmin := func(h http.HandlerFunc) http.Handler {
+		return middleware.RequireAuth(s.Auth, s.log)(middleware.RequireRole(authdomain.RoleAdmin)(h))
+	}
+
+	mux.Handle("GET /admin/archive", requireAdmin(s.archivePage))
+	mux.Handle("GET /admin/archive/export", requireAdmin(s.exportArchive))
+	mux.Handle("POST /admin/archive/import", requireAdmin(s.importArchive))
+}
+
+func (s *Server) archivePage(w http.ResponseWriter, r *http.Request) {
+	s.renderTemplate(w, "archive.html", ArchivePageData{
+		CSRFToken: middleware.CSRFToken(r.Context()),
+		Policies:  []domain.ConflictPolicy{domain.ConflictSkip, domain.ConflictOverwrite, domain.ConflictRename},
+	})
+}
+
+// exportArchive собирает архив в памяти: ошибку посреди выгрузки
+// уже нельзя было бы отдать статусом ответа.
+func (s *Server) exportArchive(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	if _, err := s.Blog.Export(r.Context(), &buf); err != nil {
+		s.renderError(w, "can't export blog", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+domain.ArchiveFilename(time.Now())+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	if _, err := buf.WriteTo(w); err != nil {
+		s.log.Error("can't send archive", slog.Any("error", err))
+	}
+}
+
+func (s *Server) importArchive(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveSize)
+
+	// 32MB в памяти, остальное во временных файлах
+	const maxMemory = 32 << 20
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		s.renderArchiveResult(w, nil, "Не удалось прочитать архив: файл больше 256 МБ или форма повреждена.")
+
+		return
+	}
+
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		s.renderArchiveResult(w, nil, "Выберите файл архива.")
+
+		return
+	}
+	defer file.Close()
+
+	report, err := s.Blog.Import(r.Context(), file, header.Size, domain.ImportOptions{
+		Policy:   domain.ConflictPolicy(r.FormValue("policy")),
+		AuthorID: middleware.PrincipalFrom(r.Context()).UserID,
+	})
+
+	switch {
+	case err == nil:
+		s.renderArchiveResult(w, report, "")
+	case errors.Is(err, errs.ErrInvalid):
+		s.renderArchiveResult(w, report, "Архив не загружен: "+err.Error())
+	default:
+		s.log.Error("can't import archive", slog.Any("error", err))
+		s.renderArchiveResult(w, report,
+			"Импорт прерван, часть данных могла загрузиться. Повторный импорт того же архива безопасен.")
+	}
+}
+
+func (s *Server) renderArchiveResult(w http.ResponseWriter, report *domain.ImportReport, message string) {
+	s.renderTemplate(w, "archive-import-result", ArchiveImportData{Report: report, Error: message})
+}
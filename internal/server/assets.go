@@ -0,0 +1,244 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+)
+
+// precompressedFS - сжатые варианты views/static, их готовит cmd/precompress.
+// После изменения статических файлов нужно запустить go generate.
+//
+//go:generate go run ../../cmd/precompress views/static precompressed
+//go:embed precompressed
+var precompressedFS embed.FS
+
+const (
+	// fingerprintLen - сколько символов sha256 попадает в имя файла.
+	fingerprintLen = 10
+
+	immutableCache = "public, max-age=31536000, immutable"
+)
+
+// staticAssets раздаёт views/static из памяти. В шаблонах ссылки строятся
+// через функцию static: в имя файла добавляется отпечаток содержимого
+// (style.css -> style.1a2b3c4d5e.css), поэтому такие адреса кэшируются
+// навсегда, а после изменения файла меняется и адрес. Старые адреса без
+// отпечатка продолжают работать, но проверяются браузером каждый раз.
+//
+// Сжатые brotli и gzip варианты текстовых файлов готовятся при сборке
+// (go generate) и берутся из encoded под именами style.css.br и style.css.gz.
+type staticAssets struct {
+	files         map[string]*staticFile
+	fingerprinted map[string]string // style.css -> style.1a2b3c4d5e.css
+	originals     map[string]string // style.1a2b3c4d5e.css -> style.css
+}
+
+type staticFile struct {
+	data        []byte
+	contentType string
+	hash        string
+	encoded     map[string][]byte // заранее сжатые варианты, только если они меньше
+}
+
+func newStaticAssets(fsys, encoded fs.FS) (*staticAssets, error) {
+	assets := &staticAssets{
+		files:         make(map[string]*staticFile),
+		fingerprinted: make(map[string]string),
+		originals:     make(map[string]string),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		file, err := newStaticFile(name, data, encoded)
+		if err != nil {
+			return err
+		}
+
+		ext := path.Ext(name)
+		withHash := name[:len(name)-len(ext)] + "." + file.hash + ext
+
+		assets.files[name] = file
+		assets.fingerprinted[name] = withHash
+		assets.originals[withHash] = name
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't prepare static files: %w", err)
+	}
+
+	return assets, nil
+}
+
+func newStaticFile(name string, data []byte, encoded fs.FS) (*staticFile, error) {
+	sum := sha256.Sum256(data)
+
+	file := &staticFile{
+		data:        data,
+		contentType: mime.TypeByExtension(path.Ext(name)),
+		hash:        hex.EncodeToString(sum[:])[:fingerprintLen],
+		encoded:     make(map[string][]byte),
+	}
+
+	if file.contentType == "" {
+		file.contentType = http.DetectContentType(data)
+	}
+
+	for encoding, suffix := range encodedSuffixes {
+		compressed, err := fs.ReadFile(encoded, name+suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("can't read %s: %w", name+suffix, err)
+		}
+
+		file.encoded[encoding] = compressed
+	}
+
+	return file, nil
+}
+
+// URL возвращает адрес файла с отпечатком. Для неизвестного файла -
+// обычный адрес, чтобы опечатка в шаблоне давала 404, а не панику.
+func (a *staticAssets) URL(name string) string {
+	if withHash, ok := a.fingerprinted[name]; ok {
+		return "/static/" + withHash
+	}
+
+	return "/static/" + name
+}
+
+func (a *staticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	cacheControl := "no-cache"
+	if original, ok := a.originals[name]; ok {
+		cacheControl = immutableCache
+		name = original
+	}
+
+	file, ok := a.files[name]
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	header := w.Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("Content-Type", file.contentType)
+
+	data, etag := file.data, file.hash
+
+	if len(file.encoded) > 0 {
+		middleware.AddVary(header, "Accept-Encoding")
+
+		encoding := middleware.AcceptedEncoding(r)
+		if encoded, found := file.encoded[encoding]; found {
+			header.Set("Content-Encoding", encoding)
+
+			data, etag = encoded, etag+"-"+encoding
+		}
+	}
+
+	// ServeContent сам отвечает 304 по If-None-Match
+	header.Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// copyTo записывает файлы в dir под обоими именами: страницы статической
+// сборки ссылаются на адреса с отпечатками. Рядом кладутся сжатые варианты
+// .br и .gz для веб-сервера. Возвращает число исходных файлов.
+func (a *staticAssets) copyTo(dir string) (int, error) {
+	for name, withHash := range a.fingerprinted {
+		file := a.files[name]
+
+		variants := map[string][]byte{"": file.data}
+		for encoding, data := range file.encoded {
+			variants[encodedSuffixes[encoding]] = data
+		}
+
+		for _, target := range []string{name, withHash} {
+			for suffix, data := range variants {
+				if err := writeStaticFile(filepath.Join(dir, filepath.FromSlash(target+suffix)), data); err != nil {
+					return 0, err
+				}
+			}
+		}
+	}
+
+	return len(a.fingerprinted), nil
+}
+
+// encodedSuffixes - расширения сжатых файлов, которые ищут gzip_static и brotli_static.
+//
+//nolint:gochecknoglobals // suffixes of precompressed files
+var encodedSuffixes = map[string]string{
+	middleware.EncodingBrotli: ".br",
+	middleware.EncodingGzip:   ".gz",
+}
+
+func writeStaticFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("can't create static directory: %w", err)
+	}
+
+	if err := os.WriteFile(file, data, 0o644); err != nil { //nolint:mnd,gosec // files for web server
+		return fmt.Errorf("can't write static file %s: %w", filepath.Base(file), err)
+	}
+
+	return nil
+}
+
+// contentVersion - хэш всех файлов fsys. Входит в ETag страниц, чтобы после
+// обновления шаблонов браузеры не показывали старую вёрстку.
+func contentVersion(fsys fs.FS) (string, error) {
+	hash := sha256.New()
+
+	// WalkDir обходит файлы в лексическом порядке, поэтому хэш стабилен
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+		defer file.Close()
+
+		_, _ = io.WriteString(hash, name+"\x00")
+		_, err = io.Copy(hash, file)
+
+		return err //nolint:wrapcheck // wrapped below
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't hash views: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:fingerprintLen], nil
+}
@@ -2,46 +2,71 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
 	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 )
 
 type Blog interface {
 	Posts(ctx context.Context, params domain.SelectPostsParams) ([]*domain.Post, error)
+	ListPosts(ctx context.Context, filter domain.PostsFilter) ([]*domain.Post, *domain.PostsCursor, error)
+	PublishedPosts(ctx context.Context) ([]*domain.Post, error)
 	Post(ctx context.Context, id int) (*domain.Post, error)
 	PostBySlug(ctx context.Context, slug string) (*domain.Post, error)
 	CreatePost(ctx context.Context, params domain.CreatePostParams) (*domain.Post, error)
 	UpdatePost(ctx context.Context, params domain.UpdatePostParams) error
 	DeletePost(ctx context.Context, id int) error
 	ChangePublishStatus(ctx context.Context, id int, curPublishStatus bool) error
+	PostPreviewImage(ctx context.Context, post *domain.Post) ([]byte, error)
+	React(ctx context.Context, postID int, reaction string, add bool) (domain.ReactionCounts, error)
 
 	Categories(ctx context.Context) ([]*domain.Category, error)
+	Category(ctx context.Context, id int) (*domain.Category, error)
+	CreateCategory(ctx context.Context, name string) (*domain.Category, error)
+	RenameCategory(ctx context.Context, id int, name string) error
+	DeleteCategory(ctx context.Context, id int) error
+
+	Export(ctx context.Context, w io.Writer) (*domain.ExportReport, error)
+	Import(ctx context.Context, r io.ReaderAt, size int64, opts domain.ImportOptions) (*domain.ImportReport, error)
 
 	MdToHTML(md []byte) []byte
+	CacheStats() domain.CacheStats
 }
 
 func (s *Server) registerBlogRoutes(mux *http.ServeMux) {
 	mux.Handle("GET /blog/posts", middleware.OptionalAuth(s.Auth, s.log)(http.HandlerFunc(s.postsPage)))
 	mux.Handle("GET /blog/posts/more", middleware.OptionalAuth(s.Auth, s.log)(http.HandlerFunc(s.posts)))
 	mux.Handle("GET /blog/posts/{slug}", middleware.OptionalAuth(s.Auth, s.log)(http.HandlerFunc(s.postPage)))
+	mux.Handle("GET /blog/posts/{slug}/og.png",
+		middleware.OptionalAuth(s.Auth, s.log)(http.HandlerFunc(s.postPreviewImage)))
 
 	mux.Handle("GET /blog/posts/form-create", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.createPostPage)))
-	mux.Handle("POST /blog/posts", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.createPost)))
 	mux.Handle("GET /blog/posts/form-update", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.updatePostPage)))
-	mux.Handle("PUT /blog/posts", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.updatePost)))
-	mux.Handle("DELETE /blog/posts/{id}", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.deletePost)))
-	mux.Handle("PATCH /blog/posts/{id}/toggle-publication",
-		middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.togglePostPublication)))
+
+	// изменения доступны и скриптам по API токену
+	write := middleware.RequireAuthOrToken(s.Auth, s.log, authdomain.ScopePostsWrite)
+	publish := middleware.RequireAuthOrToken(s.Auth, s.log, authdomain.ScopePostsPublish)
+
+	mux.Handle("POST /blog/posts", write(http.HandlerFunc(s.createPost)))
+	mux.Handle("PUT /blog/posts", write(http.HandlerFunc(s.updatePost)))
+	mux.Handle("DELETE /blog/posts/{id}", write(http.HandlerFunc(s.deletePost)))
+	mux.Handle("PATCH /blog/posts/{id}/toggle-publication", publish(
+		middleware.RequireRole(authdomain.RoleEditor)(http.HandlerFunc(s.togglePostPublication))))
 }
 
 func (s *Server) postsPage(w http.ResponseWriter, r *http.Request) {
-	isAdmin := r.Context().Value(middleware.IsAdminKey) != nil
+	user := middleware.PrincipalFrom(r.Context())
 
 	categoryIDStr := r.URL.Query().Get("category_id")
 
@@ -50,9 +75,23 @@ func (s *Server) postsPage(w http.ResponseWriter, r *http.Request) {
 		categoryID = 0
 	}
 
-	params := domain.SelectPostsParams{Limit: s.pageLimit + 1, Offset: 0, IsAdmin: isAdmin, CategoryID: categoryID}
+	// offset приходит из ссылки "Load More", когда htmx недоступен
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	includeDrafts, draftsAuthorID := draftsVisibleTo(user)
+
+	params := domain.SelectPostsParams{
+		Limit:              s.pageLimit + 1,
+		Offset:             offset,
+		IncludeUnpublished: includeDrafts,
+		DraftsAuthorID:     draftsAuthorID,
+		CategoryID:         categoryID,
+	}
 
-	posts, err := s.Blog.Posts(r.Context(), params)
+	posts, err := s.Blog.Posts(blogContext(r), params)
 	if err != nil {
 		s.log.Error("can't get posts from db", slog.Any("error", err))
 
@@ -68,28 +107,62 @@ func (s *Server) postsPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.trackView(r, 0)
+
+	validator := s.newPageValidator(r)
+	validator.add(offset, categoryID, categories, s.static)
+
+	var lastModified time.Time
+
+	for _, post := range posts {
+		validator.addPost(post)
+
+		if post.UpdatedAt.After(lastModified) {
+			lastModified = post.UpdatedAt
+		}
+	}
+
+	if notModified(w, r, validator.etag(), lastModified) {
+		return
+	}
+
 	tmplData := PostsPageData{
+		CSRFToken:  middleware.CSRFToken(r.Context()),
 		Categories: categories,
 		PostsData: PostsData{
 			SelectedCategoryID: categoryID,
 			Posts:              posts,
-			IsAdmin:            isAdmin,
+			User:               user,
 			HasNextPages:       false,
-			NextOffset:         len(posts),
+			NextOffset:         offset + len(posts),
+			Static:             s.static,
 		},
 	}
 
 	if len(posts) == s.pageLimit+1 {
 		tmplData.HasNextPages = true
 		tmplData.Posts = tmplData.Posts[:len(tmplData.Posts)-1]
-		tmplData.NextOffset = len(tmplData.Posts)
+		tmplData.NextOffset = offset + len(tmplData.Posts)
 	}
 
 	s.renderTemplate(w, "posts.html", tmplData)
 }
 
+// draftsVisibleTo сообщает, видны ли user черновики и чьи: редакторы видят
+// все черновики (0), авторы - только свои.
+func draftsVisibleTo(user *authdomain.Principal) (bool, int) {
+	switch {
+	case user == nil:
+		return false, 0
+	case user.Role.AtLeast(authdomain.RoleEditor):
+		return true, 0
+	default:
+		return true, user.UserID
+	}
+}
+
 func (s *Server) posts(w http.ResponseWriter, r *http.Request) {
-	isAdmin := r.Context().Value(middleware.IsAdminKey) != nil
+	user := middleware.PrincipalFrom(r.Context())
 
 	offsetStr := r.URL.Query().Get("offset")
 
@@ -107,9 +180,17 @@ func (s *Server) posts(w http.ResponseWriter, r *http.Request) {
 		categoryID = 0
 	}
 
-	params := domain.SelectPostsParams{Limit: s.pageLimit + 1, Offset: offset, IsAdmin: isAdmin, CategoryID: categoryID}
+	includeDrafts, draftsAuthorID := draftsVisibleTo(user)
 
-	posts, err := s.Blog.Posts(r.Context(), params)
+	params := domain.SelectPostsParams{
+		Limit:              s.pageLimit + 1,
+		Offset:             offset,
+		IncludeUnpublished: includeDrafts,
+		DraftsAuthorID:     draftsAuthorID,
+		CategoryID:         categoryID,
+	}
+
+	posts, err := s.Blog.Posts(blogContext(r), params)
 	if err != nil {
 		s.log.Error("can't get posts from db", slog.Any("error", err))
 
@@ -121,9 +202,10 @@ func (s *Server) posts(w http.ResponseWriter, r *http.Request) {
 	tmplData := PostsData{
 		SelectedCategoryID: categoryID,
 		Posts:              posts,
-		IsAdmin:            isAdmin,
+		User:               user,
 		HasNextPages:       false,
 		NextOffset:         offset + len(posts),
+		Static:             s.static,
 	}
 
 	if len(posts) == s.pageLimit+1 {
@@ -136,11 +218,11 @@ func (s *Server) posts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) postPage(w http.ResponseWriter, r *http.Request) {
-	isAdmin := r.Context().Value(middleware.IsAdminKey) != nil
+	user := middleware.PrincipalFrom(r.Context())
 
 	slug := r.PathValue("slug")
 
-	post, err := s.Blog.PostBySlug(r.Context(), slug)
+	post, err := s.Blog.PostBySlug(blogContext(r), slug)
 	if err != nil {
 		s.log.Error("can't process service post method", slog.Any("error", err))
 
@@ -149,7 +231,7 @@ func (s *Server) postPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !isAdmin && !post.IsPublished {
+	if !post.IsPublished && !user.CanEditPost(post.AuthorID) {
 		s.log.Warn("user find hidden post")
 
 		http.Error(w, "can't find post by slug", http.StatusNotFound)
@@ -157,33 +239,51 @@ func (s *Server) postPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	comments, err := s.approvedComments(r.Context(), post.ID)
+	if err != nil {
+		s.renderError(w, "can't get comments", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	reactions := s.postReactions(r, post)
+
+	s.trackView(r, post.ID)
+
+	// проверка до рендера markdown: вернувшемуся читателю хватит 304
+	validator := s.newPageValidator(r)
+	validator.addPost(post)
+	validator.add(reactions, comments, s.static)
+
+	if notModified(w, r, validator.etag(), post.UpdatedAt) {
+		return
+	}
+
 	content := s.Blog.MdToHTML(post.Content)
 
 	// #nosec G203 - Content is from trusted markdown stored in database
 	tmplContent := template.HTML(content)
 
-	tmplData := struct {
-		ID           int
-		Title        string
-		Description  string
-		Content      template.HTML
-		Slug         string
-		CategoryName string
-		CreatedAt    string
-		UpdatedAt    string
-		IsPublished  bool
-		IsAdmin      bool
-	}{
+	tmplData := PostPageData{
+		CSRFToken:    middleware.CSRFToken(r.Context()),
 		ID:           post.ID,
 		Title:        post.Title,
 		Description:  post.Description,
 		Content:      tmplContent,
 		Slug:         post.Slug,
 		CategoryName: post.CategoryName,
+		AuthorID:     post.AuthorID,
+		AuthorName:   post.AuthorName,
 		CreatedAt:    post.CreatedAt.Format("02.01.2006"),
 		UpdatedAt:    post.UpdatedAt.Format("02.01.2006"),
 		IsPublished:  post.IsPublished,
-		IsAdmin:      isAdmin,
+		User:         user,
+		PageURL:      s.publicURL + "/blog/posts/" + post.Slug,
+		OGImageURL:   s.publicURL + "/blog/posts/" + post.Slug + "/og.png",
+		Comments:     comments,
+		CommentForm:  CommentFormData{PostID: post.ID, ParentID: 0},
+		Reactions:    reactions,
+		Static:       s.static,
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -191,6 +291,44 @@ func (s *Server) postPage(w http.ResponseWriter, r *http.Request) {
 	s.renderTemplate(w, "post.html", tmplData)
 }
 
+func (s *Server) postPreviewImage(w http.ResponseWriter, r *http.Request) {
+	user := middleware.PrincipalFrom(r.Context())
+
+	slug := r.PathValue("slug")
+
+	post, err := s.Blog.PostBySlug(blogContext(r), slug)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			s.renderError(w, "can't find post by slug", err, http.StatusNotFound)
+		} else {
+			s.renderError(w, "can't get post", err, http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	if !post.IsPublished && !user.CanEditPost(post.AuthorID) {
+		http.Error(w, "can't find post by slug", http.StatusNotFound)
+
+		return
+	}
+
+	img, err := s.Blog.PostPreviewImage(r.Context(), post)
+	if err != nil {
+		s.renderError(w, "can't render preview image", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	const previewMaxAge = "public, max-age=86400"
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", previewMaxAge)
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write(img)
+}
+
 func (s *Server) createPostPage(w http.ResponseWriter, r *http.Request) {
 	categories, err := s.Blog.Categories(r.Context())
 	if err != nil {
@@ -199,10 +337,14 @@ func (s *Server) createPostPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmplData := struct {
-		Categories []*domain.Category
-	}{
-		Categories: categories,
+	tmplData := PostFormData{
+		CSRFToken:   middleware.CSRFToken(r.Context()),
+		Categories:  categories,
+		Title:       "",
+		Slug:        "",
+		Description: "",
+		CategoryID:  0,
+		Errors:      nil,
 	}
 
 	s.renderTemplate(w, "create_post.html", tmplData)
@@ -225,6 +367,10 @@ func (s *Server) updatePostPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.canEditPost(w, r, post) {
+		return
+	}
+
 	categories, err := s.Blog.Categories(r.Context())
 	if err != nil {
 		s.renderError(w, "can't get categories", err, http.StatusInternalServerError)
@@ -233,9 +379,11 @@ func (s *Server) updatePostPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tmplData := struct {
+		CSRFToken  string
 		Categories []*domain.Category
 		Post       *domain.Post
 	}{
+		CSRFToken:  middleware.CSRFToken(r.Context()),
 		Categories: categories,
 		Post:       post,
 	}
@@ -260,6 +408,10 @@ func (s *Server) updatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.canEditPost(w, r, post) {
+		return
+	}
+
 	const maxRequestSize = 1_000_000 // 1MB
 	if err = r.ParseMultipartForm(maxRequestSize); err != nil {
 		s.renderError(w, "can't parse file", err, http.StatusBadRequest)
@@ -279,6 +431,19 @@ func (s *Server) updatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// версия 0 в хранилище означает любую, её принимает только API с If-Match: *.
+	// Форма всегда знает, какую версию правят, иначе затёрла бы чужую правку.
+	version, err := strconv.Atoi(r.FormValue("version"))
+	if err == nil && version < 1 {
+		err = fmt.Errorf("post version %d: %w", version, errs.ErrInvalid)
+	}
+
+	if err != nil {
+		s.renderError(w, "invalid post version", err, http.StatusBadRequest)
+
+		return
+	}
+
 	var content []byte
 
 	file, _, err := r.FormFile("file")
@@ -302,16 +467,32 @@ func (s *Server) updatePost(w http.ResponseWriter, r *http.Request) {
 		Description: description,
 		CategoryID:  categoryID,
 		Content:     content,
+		Version:     version,
 	}
 
 	err = s.Blog.UpdatePost(r.Context(), postParms)
-	if err != nil {
+
+	var (
+		conflict *domain.VersionConflictError
+		invalid  *domain.ValidationError
+	)
+
+	switch {
+	case errors.As(err, &conflict):
+		s.renderPostConflict(w, r, conflict)
+
+		return
+	case errors.As(err, &invalid):
+		s.renderPostFormErrors(w, invalid)
+
+		return
+	case err != nil:
 		s.renderError(w, "can't update post", err, http.StatusInternalServerError)
 
 		return
 	}
 
-	w.Header().Set("HX-Redirect", "/blog/posts/"+slug)
+	w.Header().Set("HX-Redirect", "/blog/posts/"+strings.TrimSpace(slug))
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -360,17 +541,34 @@ func (s *Server) createPost(w http.ResponseWriter, r *http.Request) {
 		Description: description,
 		Filename:    header.Filename,
 		CategoryID:  categoryID,
+		AuthorID:    middleware.PrincipalFrom(r.Context()).UserID,
 		Content:     content,
 		IsPublished: false,
 	}
 
 	post, err := s.Blog.CreatePost(r.Context(), postParms)
+
+	var invalid *domain.ValidationError
+	if errors.As(err, &invalid) {
+		s.renderCreatePostErrors(w, r, postParms, invalid)
+
+		return
+	}
+
 	if err != nil {
 		s.renderError(w, "can't create post", err, http.StatusInternalServerError)
 
 		return
 	}
 
+	// форма отправляется через htmx, скрипты по API токену получают обычный редирект
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/blog/posts/"+post.Slug)
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
 	http.Redirect(w, r, "/blog/posts/"+post.Slug, http.StatusFound)
 }
 
@@ -384,6 +582,17 @@ func (s *Server) deletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	post, err := s.Blog.Post(r.Context(), postID)
+	if err != nil {
+		s.renderError(w, "can't get post", err, http.StatusNotFound)
+
+		return
+	}
+
+	if !s.canEditPost(w, r, post) {
+		return
+	}
+
 	err = s.Blog.DeletePost(r.Context(), postID)
 	if err != nil {
 		s.renderError(w, "can't delete post", err, http.StatusInternalServerError)
@@ -425,3 +634,153 @@ func (s *Server) togglePostPublication(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("HX-Redirect", "/blog/posts/"+slug)
 	w.WriteHeader(http.StatusOK)
 }
+
+// renderPostConflict показывает, чем правка расходится с постом, который
+// успели изменить. Форма получает новую версию, так что повторная отправка
+// сознательно перезапишет чужие изменения.
+func (s *Server) renderPostConflict(w http.ResponseWriter, r *http.Request, conflict *domain.VersionConflictError) {
+	categoryNames := make(map[string]string)
+
+	if categories, err := s.Blog.Categories(r.Context()); err == nil {
+		for _, category := range categories {
+			categoryNames[strconv.Itoa(category.ID)] = category.Name
+		}
+	}
+
+	tmplData := PostConflictData{
+		CurrentVersion: conflict.Current.Version,
+		Changes:        make([]PostChangeView, 0, len(conflict.Changes)),
+	}
+
+	for _, change := range conflict.Changes {
+		view := PostChangeView{
+			Label:   postFieldLabels[change.Field],
+			Yours:   change.Yours,
+			Current: change.Current,
+			Diff:    nil,
+		}
+
+		switch change.Field {
+		case "content":
+			view.Diff = domain.DiffLines(change.Current, change.Yours, conflictDiffContext)
+		case "category_id":
+			view.Yours, view.Current = categoryNames[change.Yours], categoryNames[change.Current]
+		}
+
+		tmplData.Changes = append(tmplData.Changes, view)
+	}
+
+	w.WriteHeader(http.StatusConflict)
+	s.renderTemplate(w, "post-conflict", tmplData)
+}
+
+const conflictDiffContext = 2
+
+//nolint:gochecknoglobals // labels of post form fields
+var postFieldLabels = map[string]string{
+	"title":       "Название",
+	"slug":        "Slug",
+	"description": "Краткое описание",
+	"category_id": "Категория",
+	"content":     "Содержимое",
+}
+
+// renderCreatePostErrors показывает форму нового поста снова, с введёнными
+// значениями и ошибками под полями. Файл браузер не сохраняет, его
+// придётся выбрать заново.
+func (s *Server) renderCreatePostErrors(
+	w http.ResponseWriter, r *http.Request, params domain.CreatePostParams, invalid *domain.ValidationError,
+) {
+	categories, err := s.Blog.Categories(r.Context())
+	if err != nil {
+		s.renderError(w, "can't get categories", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData := PostFormData{
+		CSRFToken:   middleware.CSRFToken(r.Context()),
+		Categories:  categories,
+		Title:       params.Title,
+		Slug:        params.Slug,
+		Description: params.Description,
+		CategoryID:  params.CategoryID,
+		Errors:      make(map[string]string, len(invalid.Fields)),
+	}
+
+	for field, code := range invalid.Fields {
+		tmplData.Errors[postFormField(field)] = postFieldMessage(field, code)
+	}
+
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	s.renderTemplate(w, "create_post.html", tmplData)
+}
+
+// renderPostFormErrors отдаёт форме правки ошибки по полям.
+func (s *Server) renderPostFormErrors(w http.ResponseWriter, invalid *domain.ValidationError) {
+	messages := make(map[string]string, len(invalid.Fields))
+	for field, code := range invalid.Fields {
+		messages[postFormField(field)] = postFieldMessage(field, code)
+	}
+
+	tmplData := PostFormErrorsData{Fields: make([]PostFieldError, 0, len(postFormFields))}
+	for _, field := range postFormFields {
+		tmplData.Fields = append(tmplData.Fields, PostFieldError{Field: field, Message: messages[field]})
+	}
+
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	s.renderTemplate(w, "post-form-errors", tmplData)
+}
+
+// postFormFields - поля форм поста, под которыми выводятся ошибки.
+//
+//nolint:gochecknoglobals // fields of post forms
+var postFormFields = []string{"title", "slug", "description", "category_id", "content"}
+
+// postFormField возвращает поле формы, к которому относится ошибка:
+// расширение и содержимое проверяются у одного и того же файла.
+func postFormField(field string) string {
+	if field == "filename" {
+		return "content"
+	}
+
+	return field
+}
+
+func postFieldMessage(field, code string) string {
+	switch code {
+	case domain.FieldRequired:
+		return "Обязательное поле."
+	case domain.FieldTooLong:
+		return fmt.Sprintf("Не длиннее %d символов.", postFieldMaxLen[field])
+	case domain.FieldInvalidFormat:
+		return "Только латинские буквы в нижнем регистре, цифры и дефисы между ними."
+	case domain.FieldInvalidUTF8:
+		return "Текст должен быть в кодировке UTF-8."
+	case domain.FieldUnsupported:
+		return "Поддерживаются файлы " + strings.Join(domain.AllowedExtensions, ", ") + "."
+	case domain.FieldNotFound:
+		return "Категория не найдена."
+	default:
+		return code
+	}
+}
+
+//nolint:gochecknoglobals // limits of post form fields
+var postFieldMaxLen = map[string]int{
+	"title":       domain.MaxTitleLen,
+	"slug":        domain.MaxSlugLen,
+	"description": domain.MaxDescriptionLen,
+}
+
+// canEditPost отвечает 403, если пользователь не может править пост:
+// авторы правят только свои посты, редакторы и админы - любые.
+func (s *Server) canEditPost(w http.ResponseWriter, r *http.Request, post *domain.Post) bool {
+	if middleware.PrincipalFrom(r.Context()).CanEditPost(post.AuthorID) {
+		return true
+	}
+
+	http.Error(w, "can't edit someone else's post", http.StatusForbidden)
+
+	return false
+}
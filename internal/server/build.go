@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+// SiteBuilder рендерит публичные страницы блога в директорию для
+// статического хостинга. Страницы отдают те же обработчики и шаблоны,
+// что и на сервере, от имени анонимного посетителя.
+type SiteBuilder struct {
+	srv *Server
+}
+
+// BuildReport - что записано в директорию сборки.
+type BuildReport struct {
+	Pages  int // html страницы
+	Files  int // карта сайта, robots.txt и картинки превью
+	Static int // файлы views/static
+	Images int // картинки постов
+}
+
+func NewSiteBuilder(log *slog.Logger, cfg config.Server, dependency Services) *SiteBuilder {
+	srv := New(log, cfg, dependency)
+	srv.static = true
+
+	return &SiteBuilder{srv: srv}
+}
+
+// Ссылки на список постов с параметрами: статический хостинг не различает
+// страницы по query, поэтому у каждой страницы списка свой путь.
+//
+//nolint:gochecknoglobals // compiled once
+var postsQueryLink = regexp.MustCompile(`/blog/posts\?[^"'<\s]*`)
+
+// Build записывает в dir главную, страницы списка постов, посты с картинками
+// превью, страницы категорий, карту сайта и robots.txt, затем копирует
+// views/static и картинки из imagesDir. Существующие файлы перезаписываются.
+func (b *SiteBuilder) Build(ctx context.Context, dir, imagesDir string) (*BuildReport, error) {
+	report := &BuildReport{Pages: 0, Files: 0, Static: 0, Images: 0}
+
+	posts, err := b.srv.Blog.PublishedPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get published posts: %w", err)
+	}
+
+	if err = b.buildPages(ctx, dir, posts, report); err != nil {
+		return nil, err
+	}
+
+	if err = b.buildSEO(ctx, dir, report); err != nil {
+		return nil, err
+	}
+
+	if report.Static, err = b.srv.assets.copyTo(filepath.Join(dir, "static")); err != nil {
+		return nil, err
+	}
+
+	report.Images, err = copyTree(os.DirFS(imagesDir), filepath.Join(dir, "images"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (b *SiteBuilder) buildPages(ctx context.Context, dir string, posts []*domain.Post, report *BuildReport) error {
+	if err := b.write(ctx, dir, "/", "index.html", b.srv.htmlIndex, nil); err != nil {
+		return err
+	}
+
+	report.Pages++
+
+	categories, err := b.srv.Blog.Categories(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get categories: %w", err)
+	}
+
+	counts := map[int]int{0: len(posts)}
+	for _, post := range posts {
+		counts[post.CategoryID]++
+	}
+
+	// у категории без постов тоже есть страница: на неё ссылается список категорий
+	listings := []int{0}
+	for _, category := range categories {
+		listings = append(listings, category.ID)
+	}
+
+	for _, categoryID := range listings {
+		for offset := 0; offset == 0 || offset < counts[categoryID]; offset += b.srv.pageLimit {
+			target := fmt.Sprintf("/blog/posts?offset=%d&category_id=%d", offset, categoryID)
+
+			err = b.write(ctx, dir, target, staticPageFile(b.staticPostsPath(target)), b.srv.postsPage, nil)
+			if err != nil {
+				return err
+			}
+
+			report.Pages++
+		}
+	}
+
+	for _, post := range posts {
+		target := "/blog/posts/" + post.Slug
+		values := map[string]string{"slug": post.Slug}
+
+		if err = b.write(ctx, dir, target, staticPageFile(target+"/"), b.srv.postPage, values); err != nil {
+			return err
+		}
+
+		if err = b.write(ctx, dir, target+"/og.png", target+"/og.png", b.srv.postPreviewImage, values); err != nil {
+			return err
+		}
+
+		report.Pages++
+		report.Files++
+	}
+
+	return nil
+}
+
+func (b *SiteBuilder) buildSEO(ctx context.Context, dir string, report *BuildReport) error {
+	if err := b.write(ctx, dir, "/robots.txt", "robots.txt", b.srv.robots, nil); err != nil {
+		return err
+	}
+
+	if err := b.write(ctx, dir, "/sitemap.xml", "sitemap.xml", b.srv.sitemap, nil); err != nil {
+		return err
+	}
+
+	report.Files += 2
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/sitemap.xml", http.NoBody)
+
+	urls, err := b.srv.sitemapURLs(req)
+	if err != nil {
+		return fmt.Errorf("can't build sitemap: %w", err)
+	}
+
+	// при большом числе ссылок sitemap.xml - индекс частей
+	chunks := splitSitemap(urls, sitemapMaxURLs, sitemapMaxBytes)
+	if len(chunks) <= 1 {
+		return nil
+	}
+
+	for i := range chunks {
+		name := strconv.Itoa(i+1) + ".xml"
+
+		err = b.write(ctx, dir, "/sitemaps/"+name, "sitemaps/"+name, b.srv.sitemapPage, map[string]string{"name": name})
+		if err != nil {
+			return err
+		}
+
+		report.Files++
+	}
+
+	return nil
+}
+
+// write выполняет обработчик для target и сохраняет ответ в файл name
+// относительно dir.
+func (b *SiteBuilder) write(
+	ctx context.Context, dir, target, name string, handler http.HandlerFunc, pathValues map[string]string,
+) error {
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	for key, value := range pathValues {
+		req.SetPathValue(key, value)
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("can't render %s: status %d: %s", target, rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+
+	body := rec.Body.Bytes()
+
+	contentType := rec.Header().Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		body = postsQueryLink.ReplaceAllFunc(body, func(link []byte) []byte {
+			return []byte(b.staticPostsPath(string(link)))
+		})
+	}
+
+	file := filepath.Join(dir, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+		return fmt.Errorf("can't create directory for %s: %w", name, err)
+	}
+
+	if err := os.WriteFile(file, body, 0o644); err != nil { //nolint:mnd,gosec // files for web server
+		return fmt.Errorf("can't write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// staticPostsPath переводит ссылку /blog/posts?offset=N&category_id=C в путь
+// страницы статической сборки: /blog/posts/page/2/, /blog/categories/C/.
+func (b *SiteBuilder) staticPostsPath(link string) string {
+	_, rawQuery, _ := strings.Cut(html.UnescapeString(link), "?")
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return link
+	}
+
+	categoryID, _ := strconv.Atoi(query.Get("category_id"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	base := "/blog/posts/"
+	if categoryID > 0 {
+		base = "/blog/categories/" + strconv.Itoa(categoryID) + "/"
+	}
+
+	if page := offset/b.srv.pageLimit + 1; page > 1 {
+		base += "page/" + strconv.Itoa(page) + "/"
+	}
+
+	return base
+}
+
+func staticPageFile(pagePath string) string {
+	return path.Join(strings.TrimPrefix(pagePath, "/"), "index.html")
+}
+
+// copyTree копирует файлы src в dir и возвращает их число.
+func copyTree(src fs.FS, dir string) (int, error) {
+	count := 0
+
+	err := fs.WalkDir(src, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755) //nolint:mnd // rwxr-xr-x
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(src, name)
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		count++
+
+		return os.WriteFile(target, data, 0o644) //nolint:mnd,gosec // files for web server
+	})
+	if err != nil {
+		return count, fmt.Errorf("can't copy files to %s: %w", dir, err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSiteBlog struct {
+	Blog
+
+	posts []*domain.Post
+}
+
+func (f *fakeSiteBlog) PublishedPosts(context.Context) ([]*domain.Post, error) {
+	return f.posts, nil
+}
+
+func (f *fakeSiteBlog) Posts(_ context.Context, params domain.SelectPostsParams) ([]*domain.Post, error) {
+	var selected []*domain.Post
+
+	for _, post := range f.posts {
+		if params.CategoryID == 0 || post.CategoryID == params.CategoryID {
+			selected = append(selected, post)
+		}
+	}
+
+	selected = selected[min(params.Offset, len(selected)):]
+
+	return selected[:min(params.Limit, len(selected))], nil
+}
+
+func (f *fakeSiteBlog) PostBySlug(_ context.Context, slug string) (*domain.Post, error) {
+	for _, post := range f.posts {
+		if post.Slug == slug {
+			return post, nil
+		}
+	}
+
+	return nil, errs.ErrNotFound
+}
+
+func (f *fakeSiteBlog) Categories(context.Context) ([]*domain.Category, error) {
+	return []*domain.Category{{ID: 1, Name: "go"}, {ID: 2, Name: "life"}, {ID: 3, Name: "empty"}}, nil
+}
+
+func (f *fakeSiteBlog) PostPreviewImage(context.Context, *domain.Post) ([]byte, error) {
+	return []byte("png"), nil
+}
+
+func (f *fakeSiteBlog) MdToHTML(md []byte) []byte {
+	return md
+}
+
+func TestSiteBuilderBuild(t *testing.T) {
+	t.Parallel()
+
+	blog := &fakeSiteBlog{Blog: nil, posts: nil}
+	for i := 1; i <= 7; i++ {
+		blog.posts = append(blog.posts, &domain.Post{
+			ID:          i,
+			Title:       fmt.Sprintf("Пост %d", i),
+			Content:     []byte("<p>текст</p>"),
+			IsPublished: true,
+			Slug:        fmt.Sprintf("post-%d", i),
+			CategoryID:  min(i/7+1, 2),
+			CreatedAt:   time.Date(2025, 1, i, 0, 0, 0, 0, time.UTC),
+			UpdatedAt:   time.Date(2025, 1, i, 0, 0, 0, 0, time.UTC),
+		})
+	}
+
+	images := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(images, "scheme one.png"), []byte("img"), 0o600))
+
+	dir := t.TempDir()
+	builder := NewSiteBuilder(slog.Default(), config.Server{PublicURL: "https://example.com"}, Services{Blog: blog})
+
+	report, err := builder.Build(t.Context(), dir, images)
+	require.NoError(t, err)
+
+	// главная, 2 страницы списка, 2 + 1 + 1 страниц категорий, 7 постов
+	assert.Equal(t, 14, report.Pages)
+	assert.Equal(t, 1, report.Images)
+	assert.Positive(t, report.Static)
+
+	read := func(name string) string {
+		t.Helper()
+
+		data, readErr := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		require.NoError(t, readErr)
+
+		return string(data)
+	}
+
+	first := read("blog/posts/index.html")
+	assert.Contains(t, first, `href="/blog/posts/page/2/"`)
+	assert.Contains(t, first, `href="/blog/categories/1/"`)
+	assert.NotContains(t, first, "hx-get")
+	assert.NotContains(t, first, "/blog/posts?")
+
+	second := read("blog/posts/page/2/index.html")
+	assert.Contains(t, second, "Пост 6")
+	assert.NotContains(t, second, "Пост 5")
+	assert.NotContains(t, second, "Load More")
+
+	assert.Contains(t, read("blog/categories/1/index.html"), `href="/blog/categories/1/page/2/"`)
+	assert.Contains(t, read("blog/categories/3/index.html"), "Пока нет ни одного поста.")
+
+	post := read("blog/posts/post-3/index.html")
+	assert.Contains(t, post, "<p>текст</p>")
+	assert.NotContains(t, post, "hx-post")
+
+	assert.Equal(t, "png", read("blog/posts/post-3/og.png"))
+	assert.Contains(t, read("sitemap.xml"), "<loc>https://example.com/blog/categories/2/</loc>")
+	assert.Contains(t, read("robots.txt"), "Sitemap: https://example.com/sitemap.xml")
+	assert.Equal(t, "img", read("images/scheme one.png"))
+	assert.Contains(t, read("static/style.css"), "{")
+	assert.Equal(t, read("static/style.css"), read(builder.srv.assets.URL("style.css")))
+	assert.FileExists(t, filepath.Join(dir, "static", "style.css.br"))
+	assert.FileExists(t, filepath.Join(dir, "static", "style.css.gz"))
+	assert.NoFileExists(t, filepath.Join(dir, "static", "navbar.png.gz"))
+	assert.Contains(t, read("index.html"), "/blog/posts")
+}
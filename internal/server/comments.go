@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	commentsdomain "github.com/arevbond/arevbond-blog/internal/service/comments/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+type Comments interface {
+	Create(ctx context.Context, params commentsdomain.CreateCommentParams) (*commentsdomain.Comment, error)
+	Approved(ctx context.Context, postID int) ([]*commentsdomain.Comment, error)
+	Queue(ctx context.Context, status commentsdomain.Status) ([]*commentsdomain.Comment, error)
+	Approve(ctx context.Context, id int) error
+	Reject(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int) error
+}
+
+func (s *Server) registerCommentsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /blog/posts/{id}/comments", s.createComment)
+
+	moderate := func(h http.HandlerFunc) http.Handler {
+		return middleware.RequireAuth(s.Auth, s.log)(middleware.RequireRole(authdomain.RoleEditor)(h))
+	}
+
+	mux.Handle("GET /admin/comments", moderate(s.moderationPage))
+	mux.Handle("PATCH /admin/comments/{id}/approve", moderate(s.approveComment))
+	mux.Handle("PATCH /admin/comments/{id}/reject", moderate(s.rejectComment))
+	mux.Handle("DELETE /admin/comments/{id}", moderate(s.deleteComment))
+}
+
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid post id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	const maxRequestSize = 64 << 10 // 64KB
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	if err = r.ParseForm(); err != nil {
+		s.renderError(w, "can't parse form", err, http.StatusBadRequest)
+
+		return
+	}
+
+	// honeypot: поле скрыто от людей, его заполняют только боты.
+	// Боту отвечаем так же, как человеку, но ничего не сохраняем.
+	if r.PostForm.Get("website") != "" {
+		s.log.Info("comment honeypot triggered", slog.String("ip", s.clientIP(r)))
+		s.renderTemplate(w, "comment-result", CommentResultData{Success: true, Message: commentSentMessage})
+
+		return
+	}
+
+	parentID, err := strconv.Atoi(r.PostForm.Get("parent_id"))
+	if err != nil {
+		parentID = 0
+	}
+
+	_, err = s.Comments.Create(r.Context(), commentsdomain.CreateCommentParams{
+		PostID:     postID,
+		ParentID:   parentID,
+		AuthorName: r.PostForm.Get("author_name"),
+		Content:    r.PostForm.Get("content"),
+		IP:         s.clientIP(r),
+	})
+
+	switch {
+	case err == nil:
+		s.renderTemplate(w, "comment-result", CommentResultData{Success: true, Message: commentSentMessage})
+	case errors.Is(err, errs.ErrInvalid):
+		s.renderTemplate(w, "comment-result", CommentResultData{
+			Success: false,
+			Message: "Укажите имя (до 100 символов) и текст комментария (до 5000 символов).",
+		})
+	case errors.Is(err, errs.ErrRateLimited):
+		s.renderTemplate(w, "comment-result", CommentResultData{
+			Success: false,
+			Message: "Слишком много комментариев. Попробуйте позже.",
+		})
+	case errors.Is(err, errs.ErrNotFound):
+		s.renderError(w, "post not found", err, http.StatusNotFound)
+	default:
+		s.renderError(w, "can't create comment", err, http.StatusInternalServerError)
+	}
+}
+
+const commentSentMessage = "Спасибо! Комментарий появится после модерации."
+
+func (s *Server) moderationPage(w http.ResponseWriter, r *http.Request) {
+	status := commentsdomain.Status(r.URL.Query().Get("status"))
+
+	switch status {
+	case commentsdomain.StatusPending, commentsdomain.StatusApproved, commentsdomain.StatusRejected:
+	default:
+		status = commentsdomain.StatusPending
+	}
+
+	comments, err := s.Comments.Queue(r.Context(), status)
+	if err != nil {
+		s.renderError(w, "can't get comments", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData := ModerationPageData{
+		CSRFToken: middleware.CSRFToken(r.Context()),
+		Status:    string(status),
+		Comments:  make([]CommentView, 0, len(comments)),
+	}
+
+	for _, comment := range comments {
+		tmplData.Comments = append(tmplData.Comments, newCommentView(comment))
+	}
+
+	s.renderTemplate(w, "comments_moderation.html", tmplData)
+}
+
+func (s *Server) approveComment(w http.ResponseWriter, r *http.Request) {
+	s.moderateComment(w, r, s.Comments.Approve)
+}
+
+func (s *Server) rejectComment(w http.ResponseWriter, r *http.Request) {
+	s.moderateComment(w, r, s.Comments.Reject)
+}
+
+func (s *Server) deleteComment(w http.ResponseWriter, r *http.Request) {
+	s.moderateComment(w, r, s.Comments.Delete)
+}
+
+// moderateComment выполняет действие над комментарием. Пустой ответ
+// удаляет строку комментария из очереди на странице модерации.
+func (s *Server) moderateComment(w http.ResponseWriter, r *http.Request, action func(context.Context, int) error) {
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid comment id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	if err = action(r.Context(), commentID); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			s.renderError(w, "comment not found", err, http.StatusNotFound)
+
+			return
+		}
+
+		s.renderError(w, "can't moderate comment", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// approvedComments подготавливает одобренные комментарии поста для шаблона.
+func (s *Server) approvedComments(ctx context.Context, postID int) ([]CommentView, error) {
+	if s.Comments == nil {
+		return nil, nil
+	}
+
+	comments, err := s.Comments.Approved(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get approved comments: %w", err)
+	}
+
+	views := make([]CommentView, 0, len(comments))
+	for _, comment := range comments {
+		views = append(views, newCommentView(comment))
+	}
+
+	return views, nil
+}
+
+func newCommentView(comment *commentsdomain.Comment) CommentView {
+	view := CommentView{
+		ID:         comment.ID,
+		AuthorName: comment.AuthorName,
+		// #nosec G203 - rendered by restricted renderer without raw html
+		Content:   template.HTML(comment.HTML),
+		CreatedAt: comment.CreatedAt.Format("02.01.2006 15:04"),
+		PostTitle: comment.PostTitle,
+		PostSlug:  comment.PostSlug,
+		IsReply:   comment.ParentID != 0,
+		Replies:   make([]CommentView, 0, len(comment.Replies)),
+		ReplyForm: CommentFormData{PostID: comment.PostID, ParentID: comment.ID},
+	}
+
+	for _, reply := range comment.Replies {
+		view.Replies = append(view.Replies, newCommentView(reply))
+	}
+
+	return view
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var reader io.Reader
+
+	switch encoding {
+	case middleware.EncodingBrotli:
+		reader = brotli.NewReader(bytes.NewReader(body))
+	case middleware.EncodingGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+
+		reader = gz
+	default:
+		return string(body)
+	}
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	page := "<!DOCTYPE html><html><body>" + strings.Repeat("<p>текст поста</p>", 200) + "</body></html>"
+	image := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 4096)...)
+
+	handler := middleware.Compress(compressMinSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = io.WriteString(w, page[:len(page)/2])
+			_, _ = io.WriteString(w, page[len(page)/2:])
+		case "/short":
+			_, _ = io.WriteString(w, "<p>коротко</p>")
+		case "/image":
+			_, _ = w.Write(image)
+		case "/empty":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	get := func(target, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	for _, tc := range []struct{ accept, encoding string }{
+		{"gzip, deflate, br", middleware.EncodingBrotli},
+		{"gzip", middleware.EncodingGzip},
+		{"br;q=0.5, gzip", middleware.EncodingGzip},
+		{"*", middleware.EncodingBrotli},
+		{"br;q=0, gzip;q=0", ""},
+		{"identity", ""},
+		{"", ""},
+	} {
+		rr := get("/page", tc.accept)
+		require.Equal(t, http.StatusOK, rr.Code, tc.accept)
+		assert.Equal(t, tc.encoding, rr.Header().Get("Content-Encoding"), tc.accept)
+		assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"), tc.accept)
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/html", tc.accept)
+		assert.Equal(t, page, decompress(t, tc.encoding, rr.Body.Bytes()), tc.accept)
+
+		if tc.encoding != "" {
+			assert.Less(t, rr.Body.Len(), len(page)/4, tc.accept)
+			assert.Equal(t, `"v1-`+tc.encoding+`"`, rr.Header().Get("ETag"), tc.accept)
+		}
+	}
+
+	rr := get("/short", "br")
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Equal(t, "<p>коротко</p>", rr.Body.String())
+
+	// уже сжатые форматы не сжимаются повторно
+	rr = get("/image", "br")
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Header().Get("Vary"))
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	assert.Equal(t, image, rr.Body.Bytes())
+
+	rr = get("/empty", "br")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+}
+
+func TestStaticAssetsPrecompressed(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+	srv.ConfigureRoutes()
+
+	style, err := StaticFile("style.css")
+	require.NoError(t, err)
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.Header = header
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	etags := map[string]bool{}
+
+	for _, encoding := range []string{middleware.EncodingBrotli, middleware.EncodingGzip, ""} {
+		rr := get("/static/style.css", http.Header{"Accept-Encoding": {encoding}})
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, encoding, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/css")
+		assert.Equal(t, string(style), decompress(t, encoding, rr.Body.Bytes()))
+
+		etags[rr.Header().Get("ETag")] = true
+
+		// у каждого варианта свой ETag, и по нему приходит 304
+		revisit := get("/static/style.css", http.Header{
+			"Accept-Encoding": {encoding},
+			"If-None-Match":   {rr.Header().Get("ETag")},
+		})
+		assert.Equal(t, http.StatusNotModified, revisit.Code)
+	}
+
+	assert.Len(t, etags, 3)
+
+	rr := get(srv.assets.URL("style.css"), http.Header{"Accept-Encoding": {"br"}})
+	assert.Equal(t, middleware.EncodingBrotli, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, immutableCache, rr.Header().Get("Cache-Control"))
+
+	rr = get("/static/navbar.png", http.Header{"Accept-Encoding": {"br"}})
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+
+	assert.Equal(t, http.StatusNotFound, get("/static/missing.css", http.Header{}).Code)
+}
+
+// TestPrecompressedAssetsUpToDate напоминает запустить go generate после
+// правки статических файлов: сжатый вариант должен совпадать с исходником.
+func TestPrecompressedAssetsUpToDate(t *testing.T) {
+	t.Parallel()
+
+	static := mustSub(templatesFS, "views/static")
+
+	err := fs.WalkDir(precompressedFS, "precompressed", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		encoded, err := precompressedFS.ReadFile(name)
+		require.NoError(t, err)
+
+		original, ext := strings.TrimPrefix(name, "precompressed/"), path.Ext(name)
+		original = strings.TrimSuffix(original, ext)
+
+		data, err := fs.ReadFile(static, original)
+		require.NoError(t, err, "%s has no source file, run go generate", name)
+
+		encoding := middleware.EncodingGzip
+		if ext == encodedSuffixes[middleware.EncodingBrotli] {
+			encoding = middleware.EncodingBrotli
+		}
+
+		assert.Equal(t, string(data), decompress(t, encoding, encoded), "%s is stale, run go generate", name)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
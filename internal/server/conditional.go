@@ -0,0 +1,105 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+// pageCache - заголовки страниц, которые зависят от посетителя: CSRF токен,
+// реакции и права зависят от cookie, поэтому кэшировать их может только
+// браузер и только с проверкой у сервера.
+const pageCache = "private, no-cache"
+
+// pageValidator собирает ETag страницы из всего, что попадает в её html:
+// версии шаблонов и markdown рендера, посетителя и данных страницы.
+// Так ETag проверяется без рендера.
+type pageValidator struct {
+	hash hash.Hash
+}
+
+// newPageValidator начинает ETag для запроса. Выпускает CSRF токен,
+// поэтому вызывается до записи ответа.
+func (s *Server) newPageValidator(r *http.Request) *pageValidator {
+	v := &pageValidator{hash: sha256.New()}
+	v.add(s.viewsVersion, domain.RendererVersion, middleware.CSRFToken(r.Context()))
+
+	// токены в Claims обновляются, поэтому учитывается только то, что видно на странице
+	if user := middleware.PrincipalFrom(r.Context()); user != nil {
+		v.add(user.UserID, user.Username, user.DisplayName, user.Role)
+	}
+
+	return v
+}
+
+func (v *pageValidator) add(parts ...any) {
+	for _, part := range parts {
+		_, _ = fmt.Fprintf(v.hash, "%v\x00", part)
+	}
+}
+
+// addPost учитывает поля поста, которые меняются без изменения Version.
+func (v *pageValidator) addPost(post *domain.Post) {
+	v.add(post.ID, post.Version, post.UpdatedAt.UnixNano(), post.IsPublished,
+		post.CategoryName, post.AuthorName, post.Reactions)
+}
+
+// etag - слабый: страница совпадает по смыслу, а не побайтно.
+func (v *pageValidator) etag() string {
+	return `W/"` + hex.EncodeToString(v.hash.Sum(nil))[:32] + `"`
+}
+
+// notModified выставляет валидаторы и кэширование страницы и отвечает 304,
+// если копия у браузера актуальна. Как требует RFC 9110, If-Modified-Since
+// учитывается, только если нет If-None-Match: дата не отражает смену
+// шаблонов и реакций, которые учтены в ETag.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", pageCache)
+	header.Add("Vary", "Cookie")
+
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	fresh := false
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		fresh = etagMatches(match, etag)
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.IsZero() {
+		fresh = !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if fresh {
+		w.WriteHeader(http.StatusNotModified)
+	}
+
+	return fresh
+}
+
+// etagMatches - слабое сравнение для If-None-Match: "*" или любой из списка.
+// Сжатие в ETag не учитывается: страница та же, меняется только кодировка.
+func etagMatches(header, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = middleware.TrimETagEncoding(strings.TrimSpace(candidate))
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+
+	return false
+}
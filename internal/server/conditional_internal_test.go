@@ -0,0 +1,136 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostPageConditionalGet(t *testing.T) {
+	t.Parallel()
+
+	updated := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	post := &domain.Post{
+		ID: 1, Title: "Пост", Content: []byte("<p>текст</p>"), IsPublished: true, Slug: "post",
+		CreatedAt: updated, UpdatedAt: updated, Version: 1,
+	}
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{
+		Blog: &fakeSiteBlog{Blog: nil, posts: []*domain.Post{post}},
+	})
+	srv.ConfigureRoutes()
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/blog/posts/post", http.NoBody)
+		for key := range header {
+			req.Header.Set(key, header.Get(key))
+		}
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	first := get(http.Header{})
+	require.Equal(t, http.StatusOK, first.Code)
+
+	etag := first.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`))
+	assert.Equal(t, "Sat, 01 Mar 2025 10:00:00 GMT", first.Header().Get("Last-Modified"))
+	assert.Equal(t, "Cookie", first.Header().Get("Vary"))
+	assert.Equal(t, pageCache, first.Header().Get("Cache-Control"))
+
+	// ETag привязан к CSRF токену, поэтому браузер приходит со своей cookie
+	cookie := first.Result().Cookies()[0]
+	require.Equal(t, middleware.CSRFCookieName, cookie.Name)
+
+	revisit := http.Header{"Cookie": {cookie.String()}, "If-None-Match": {etag}}
+
+	rr := get(revisit)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	// без cookie посетитель получает новый токен и полную страницу
+	assert.Equal(t, http.StatusOK, get(http.Header{"If-None-Match": {etag}}).Code)
+
+	// сжатая страница получает свой ETag, и по нему тоже приходит 304
+	compressed := get(http.Header{"Cookie": {cookie.String()}, "Accept-Encoding": {"gzip"}})
+	require.Equal(t, middleware.EncodingGzip, compressed.Header().Get("Content-Encoding"))
+	assert.Equal(t, strings.TrimSuffix(etag, `"`)+`-gzip"`, compressed.Header().Get("ETag"))
+
+	rr = get(http.Header{
+		"Cookie":          {cookie.String()},
+		"Accept-Encoding": {"gzip"},
+		"If-None-Match":   {compressed.Header().Get("ETag")},
+	})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	rr = get(http.Header{"Cookie": {cookie.String()}, "If-Modified-Since": {"Sat, 01 Mar 2025 10:00:00 GMT"}})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	post.Version, post.UpdatedAt = 2, updated.Add(time.Hour)
+
+	assert.Equal(t, http.StatusOK, get(revisit).Code)
+}
+
+func TestEtagMatches(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, etagMatches(`W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"x", W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`*`, `W/"abc"`))
+	assert.False(t, etagMatches(`W/"abd"`, `W/"abc"`))
+
+	// ETag сжатого ответа подходит к той же странице
+	assert.True(t, etagMatches(`W/"abc-br"`, `W/"abc"`))
+	assert.True(t, etagMatches(`W/"abc-gzip"`, `W/"abc"`))
+	assert.False(t, etagMatches(`W/"abc-zstd"`, `W/"abc"`))
+}
+
+func TestStaticAssetsFingerprint(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+	srv.ConfigureRoutes()
+
+	url := srv.assets.URL("style.css")
+	assert.Regexp(t, `^/static/style\.[0-9a-f]{10}\.css$`, url)
+	assert.Equal(t, "/static/missing.css", srv.assets.URL("missing.css"))
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.Header = header
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := get(url, http.Header{})
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, immutableCache, rr.Header().Get("Cache-Control"))
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/css")
+
+	rr = get("/static/style.css", http.Header{})
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+
+	rr = get("/static/style.css", http.Header{"If-None-Match": {rr.Header().Get("ETag")}})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	// шаблоны ссылаются на адреса с отпечатками
+	rr = get("/", http.Header{})
+	assert.Contains(t, rr.Body.String(), `href="`+url+`"`)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+)
+
+// csrfRejectedHeader помечает ответ 403 от CSRF защиты, чтобы скрипт на
+// странице показал его вместо молча проигнорированного htmx ответа.
+const csrfRejectedHeader = "X-CSRF-Rejected"
+
+func (s *Server) csrfFailed(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		s.writeAPIError(w, http.StatusForbidden, "csrf_rejected",
+			"send the csrf token in the "+middleware.CSRFHeaderName+" header or use an api token", nil)
+
+		return
+	}
+
+	w.Header().Set(csrfRejectedHeader, "true")
+	w.WriteHeader(http.StatusForbidden)
+
+	s.renderTemplate(w, "forbidden.html", nil)
+}
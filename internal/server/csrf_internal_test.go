@@ -0,0 +1,89 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+
+	var pageToken string
+
+	handler := middleware.CSRF(srv.cookieSecret, srv.log, http.HandlerFunc(srv.csrfFailed))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				pageToken = middleware.CSRFToken(r.Context())
+			}
+
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	// страница выдаёт токен и cookie с ним
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/blog/posts", http.NoBody))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotEmpty(t, pageToken)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, pageToken, cookies[0].Value)
+
+	post := func(cookie, header, field string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		if field != "" {
+			form.Set("csrf_token", field)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/blog/posts", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: middleware.CSRFCookieName, Value: cookie})
+		}
+
+		if header != "" {
+			req.Header.Set(middleware.CSRFHeaderName, header)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, post(pageToken, pageToken, "").Code)
+
+	// токен из формы не принимается: тело не разбирается до входа
+	assert.Equal(t, http.StatusForbidden, post(pageToken, "", pageToken).Code)
+
+	rejected := post(pageToken, "", "")
+	assert.Equal(t, http.StatusForbidden, rejected.Code)
+	assert.Equal(t, "true", rejected.Header().Get(csrfRejectedHeader))
+	assert.Contains(t, rejected.Body.String(), "403")
+
+	assert.Equal(t, http.StatusForbidden, post("", pageToken, "").Code)
+
+	// токен, подписанный чужим ключом, не принимается даже при совпадении с cookie
+	forged := "nonce.c2lnbmF0dXJl"
+	assert.Equal(t, http.StatusForbidden, post(forged, forged, "").Code)
+
+	// скрипты с API токеном работают без csrf токена
+	req := httptest.NewRequest(http.MethodPost, "/blog/posts", http.NoBody)
+	req.Header.Set("Authorization", "Bearer abt_token")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
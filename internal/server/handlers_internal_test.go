@@ -1,12 +1,17 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,3 +31,139 @@ func TestHandlers_ping(t *testing.T) {
 	body := rr.Body.String()
 	assert.Equal(t, "pong", body)
 }
+
+func TestPostFormErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+
+	invalid := &domain.ValidationError{Fields: map[string]string{
+		"slug":     domain.FieldInvalidFormat,
+		"filename": domain.FieldUnsupported,
+	}}
+
+	// форма правки получает ошибки по полям, остальные поля очищаются
+	rr := httptest.NewRecorder()
+	srv.renderPostFormErrors(rr, invalid)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, `<div id="slug-error" class="invalid-feedback d-block" hx-swap-oob="true">Только латинские`)
+	assert.Contains(t, body, `<div id="content-error" class="invalid-feedback d-block" hx-swap-oob="true">Поддерживаются файлы .md, .markdown.`)
+	assert.Contains(t, body, `<div id="title-error" class="invalid-feedback d-block" hx-swap-oob="true"></div>`)
+
+	// форма создания показывается снова с введёнными значениями
+	rr = httptest.NewRecorder()
+	srv.renderTemplate(rr, "create_post.html", PostFormData{
+		CSRFToken:   "token",
+		Categories:  []*domain.Category{{ID: 1, Name: "go"}, {ID: 2, Name: "life"}},
+		Title:       "Пост",
+		Slug:        "a/b",
+		Description: "",
+		CategoryID:  2,
+		Errors:      map[string]string{"slug": postFieldMessage("slug", domain.FieldInvalidFormat)},
+	})
+
+	body = rr.Body.String()
+	assert.Contains(t, body, `value="a/b"`)
+	assert.Contains(t, body, `class="form-control is-invalid" id="slug"`)
+	assert.Contains(t, body, `<option value="2" selected>life</option>`)
+
+	// токен уходит в заголовке htmx, а не в поле формы
+	assert.Contains(t, body, `hx-post="/blog/posts"`)
+	assert.NotContains(t, body, `name="csrf_token"`)
+}
+
+func TestArchiveImportResult(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+
+	rr := httptest.NewRecorder()
+	srv.renderArchiveResult(rr, &domain.ImportReport{
+		Created:       []string{"new-post"},
+		Updated:       nil,
+		Renamed:       map[string]string{"slajsy": "slajsy-2"},
+		Skipped:       []string{"a", "b"},
+		Unchanged:     []string{"c"},
+		Categories:    nil,
+		Images:        nil,
+		SkippedImages: nil,
+	}, "")
+
+	body := rr.Body.String()
+	assert.Contains(t, body, "Архив загружен.")
+	assert.Contains(t, body, "Добавлены: new-post")
+	assert.Contains(t, body, "slajsy → slajsy-2")
+	assert.Contains(t, body, "Пропущены, slug занят: a, b")
+	assert.NotContains(t, body, "Перезаписаны")
+
+	rr = httptest.NewRecorder()
+	srv.renderArchiveResult(rr, nil, "Архив не загружен")
+
+	assert.Contains(t, rr.Body.String(), `<p class="text-danger">Архив не загружен</p>`)
+}
+
+type failingBlog struct {
+	Blog
+
+	err error
+}
+
+func (f *failingBlog) PostBySlug(context.Context, string) (*domain.Post, error) {
+	return nil, f.err
+}
+
+func TestPostPreviewImageErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("post by slug: %w", errs.ErrNotFound), http.StatusNotFound},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	} {
+		srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{
+			Blog: &failingBlog{Blog: nil, err: tc.err},
+		})
+		srv.ConfigureRoutes()
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/blog/posts/post/og.png", http.NoBody))
+		assert.Equal(t, tc.code, rr.Code, tc.err.Error())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	behindProxy := New(slog.Default(), config.Server{TrustProxyHeaders: true}, Services{})
+	direct := New(slog.Default(), config.Server{TrustProxyHeaders: false}, Services{})
+
+	request := func(header http.Header) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.RemoteAddr = "10.0.0.2:51234"
+		req.Header = header
+
+		return req
+	}
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"proxy appended", http.Header{"X-Forwarded-For": {"203.0.113.7"}}, "203.0.113.7"},
+		// клиент прислал свой X-Forwarded-For, прокси дописал настоящий адрес
+		{"spoofed prefix", http.Header{"X-Forwarded-For": {"1.2.3.4, 5.6.7.8, 203.0.113.7"}}, "203.0.113.7"},
+		{"spoofed header line", http.Header{"X-Forwarded-For": {"1.2.3.4", "203.0.113.7"}}, "203.0.113.7"},
+		{"real ip", http.Header{"X-Real-Ip": {"203.0.113.8"}}, "203.0.113.8"},
+		{"garbage", http.Header{"X-Forwarded-For": {"1.2.3.4, not-an-ip"}}, "10.0.0.2"},
+		{"no headers", http.Header{}, "10.0.0.2"},
+	} {
+		assert.Equal(t, tc.want, behindProxy.clientIP(request(tc.header)), tc.name)
+	}
+
+	assert.Equal(t, "10.0.0.2", direct.clientIP(request(http.Header{"X-Forwarded-For": {"203.0.113.7"}})))
+}
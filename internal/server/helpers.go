@@ -1,8 +1,14 @@
 package server
 
 import (
+	"context"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
 )
 
 func (s *Server) renderTemplate(w http.ResponseWriter, templateName string, data any) {
@@ -24,3 +30,41 @@ func (s *Server) renderError(w http.ResponseWriter, errorMsg string, err error,
 
 	http.Error(w, errorMsg, statusCode)
 }
+
+// blogContext - контекст для чтения постов: вошедшие пользователи читают
+// мимо кэша, чтобы сразу видеть свои правки и скрытые посты.
+func blogContext(r *http.Request) context.Context {
+	if middleware.PrincipalFrom(r.Context()) != nil {
+		return domain.SkipCache(r.Context())
+	}
+
+	return r.Context()
+}
+
+// clientIP возвращает ip клиента. Заголовки прокси учитываются,
+// только если это разрешено в конфигурации.
+//
+// Из X-Forwarded-For берётся последний адрес: его дописал наш прокси.
+// Всё левее присылает сам клиент, и подменой этих адресов можно было бы
+// обойти ограничения попыток входа и комментариев.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); net.ParseIP(last) != nil {
+				return last
+			}
+		}
+
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(realIP) != nil {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
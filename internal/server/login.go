@@ -1,26 +1,44 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 )
 
 type Auth interface {
-	IsAdminToken(token string) bool
-	NewJWT() (string, error)
-	VerifyJWT(tokenStr string) (bool, error)
+	Login(ctx context.Context, username, password string, meta authdomain.SessionMeta) (*authdomain.User, error)
+	NewJWT(ctx context.Context, userID int, meta authdomain.SessionMeta, amr []string) (authdomain.Tokens, error)
+	VerifyJWT(ctx context.Context, tokenStr string) (*authdomain.Principal, error)
+	Refresh(ctx context.Context, refreshToken, userAgent string) (authdomain.Tokens, *authdomain.Principal, error)
+	TokenSessionID(tokenStr string) (string, error)
+	Sessions(ctx context.Context, userID int) ([]*authdomain.Session, error)
+	RevokeSession(ctx context.Context, id string, userID int) error
+	VerifyAPIToken(ctx context.Context, tokenStr string) (*authdomain.Principal, error)
+	TOTP
+	Users
+	APITokens
 }
 
 func (s *Server) registerAuthRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /login-admin", s.loginPage)
-	mux.HandleFunc("POST /verify-token", s.verifyAdminToken)
+	mux.HandleFunc("POST /login", s.loginUser)
+	mux.HandleFunc("POST /verify-totp", s.verifySecondFactor)
+	mux.HandleFunc("POST /logout", s.logout)
 }
 
 func (s *Server) loginPage(w http.ResponseWriter, r *http.Request) {
-	s.renderTemplate(w, "admin_login.html", nil)
+	s.renderTemplate(w, "admin_login.html", LoginPageData{CSRFToken: middleware.CSRFToken(r.Context())})
 }
 
-func (s *Server) verifyAdminToken(w http.ResponseWriter, r *http.Request) {
+func (s *Server) loginUser(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
 		http.Error(w, "can't parse form", http.StatusBadRequest)
@@ -28,41 +46,166 @@ func (s *Server) verifyAdminToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	incomeToken := r.Form.Get("token")
+	meta := authdomain.SessionMeta{
+		UserAgent: r.UserAgent(),
+		IP:        s.clientIP(r),
+	}
 
-	if s.Auth.IsAdminToken(incomeToken) {
-		var token string
+	user, err := s.Auth.Login(r.Context(), r.Form.Get("username"), r.Form.Get("password"), meta)
+	if err != nil {
+		s.renderLoginError(w, err, "Invalid username or password.")
 
-		token, err = s.Auth.NewJWT()
-		if err != nil {
-			s.log.Error("can't create jwt", slog.Any("error", err))
+		return
+	}
 
-			http.Error(w, "can't create jwt", http.StatusInternalServerError)
+	totpEnabled, err := s.Auth.TOTPEnabled(r.Context(), user.ID)
+	if err != nil {
+		s.renderError(w, "can't check totp", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	// с включённым вторым фактором сессия создаётся только после ввода кода
+	if totpEnabled {
+		var challenge string
+
+		challenge, err = s.Auth.NewMFAChallenge(user.ID)
+		if err != nil {
+			s.renderError(w, "can't create mfa challenge", err, http.StatusInternalServerError)
 
 			return
 		}
 
-		setTokenCookie(w, token)
+		setChallengeCookie(w, challenge, int(authdomain.MFAChallengeTTL.Seconds()))
+		s.renderTemplate(w, "totp_step", nil)
+
+		return
+	}
 
-		s.log.Debug("success set cookie")
+	if !s.login(w, r, user.ID, meta, []string{authdomain.AMRPassword}) {
+		return
+	}
 
-		s.renderTemplate(w, "success_admin_login", nil)
+	// обязательный второй фактор ещё не настроен - сразу отправляем его настраивать
+	if s.Auth.TOTPRequired() {
+		w.Header().Set("HX-Redirect", "/admin/2fa")
 	}
+
+	s.renderTemplate(w, "success_admin_login", nil)
 }
 
-func setTokenCookie(w http.ResponseWriter, token string) {
-	const TokenTTL = 3600 // 1 hour
+func (s *Server) verifySecondFactor(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "can't parse form", http.StatusBadRequest)
 
+		return
+	}
+
+	meta := authdomain.SessionMeta{
+		UserAgent: r.UserAgent(),
+		IP:        s.clientIP(r),
+	}
+
+	var challenge string
+	if cookie, cookieErr := r.Cookie(mfaChallengeCookieName); cookieErr == nil {
+		challenge = cookie.Value
+	}
+
+	user, err := s.Auth.VerifySecondFactor(r.Context(), challenge, r.Form.Get("code"), meta)
+	if err != nil {
+		// challenge одноразовый, после ошибки вход начинается с пароля
+		setChallengeCookie(w, "", -1)
+		s.renderLoginError(w, err, "Invalid code. Sign in again with your password.")
+
+		return
+	}
+
+	if !s.login(w, r, user.ID, meta, []string{authdomain.AMRPassword, authdomain.AMROTP}) {
+		return
+	}
+
+	setChallengeCookie(w, "", -1)
+
+	s.renderTemplate(w, "success_admin_login", nil)
+}
+
+// login создаёт сессию пользователя и выставляет cookie. При ошибке ответ уже записан.
+func (s *Server) login(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID int,
+	meta authdomain.SessionMeta,
+	amr []string,
+) bool {
+	tokens, err := s.Auth.NewJWT(r.Context(), userID, meta, amr)
+	if err != nil {
+		s.log.Error("can't create jwt", slog.Any("error", err))
+
+		http.Error(w, "can't create jwt", http.StatusInternalServerError)
+
+		return false
+	}
+
+	middleware.SetAuthCookies(w, tokens)
+
+	s.log.Debug("success set cookie")
+
+	return true
+}
+
+func (s *Server) renderLoginError(w http.ResponseWriter, err error, invalidMsg string) {
+	var lockedErr *authdomain.LoginLockedError
+
+	switch {
+	case errors.As(err, &lockedErr):
+		retryAfter := lockedErr.RetryAfter.Round(time.Second) + time.Second
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		w.WriteHeader(http.StatusTooManyRequests)
+		s.renderTemplate(w, "failed_admin_login", "Too many attempts. Try again in "+retryAfter.String()+".")
+	case errors.Is(err, errs.ErrInvalid):
+		w.WriteHeader(http.StatusUnauthorized)
+		s.renderTemplate(w, "failed_admin_login", invalidMsg)
+	default:
+		s.renderError(w, "can't verify login", err, http.StatusInternalServerError)
+	}
+}
+
+const mfaChallengeCookieName = "mfa_challenge"
+
+func setChallengeCookie(w http.ResponseWriter, value string, maxAge int) {
 	//nolint: exhaustruct // default cookie struct
 	cookie := http.Cookie{
-		Name:     "token",
-		Value:    token,
-		Path:     "/",
-		MaxAge:   TokenTTL,
+		Name:     mfaChallengeCookieName,
+		Value:    value,
+		Path:     "/verify-totp",
+		MaxAge:   maxAge,
 		HttpOnly: true,
 		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
+		SameSite: http.SameSiteStrictMode,
 	}
 
 	http.SetCookie(w, &cookie)
 }
+
+// logout отзывает текущую сессию и удаляет cookie. Невалидный токен
+// не считается ошибкой: cookie удаляется в любом случае.
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(middleware.TokenCookieName); err == nil {
+		sessionID, err := s.Auth.TokenSessionID(cookie.Value)
+		if err == nil {
+			// подпись токена проверена, значит сессия принадлежит владельцу cookie
+			if err = s.Auth.RevokeSession(r.Context(), sessionID, 0); err != nil {
+				s.renderError(w, "can't revoke session", err, http.StatusInternalServerError)
+
+				return
+			}
+		}
+	}
+
+	middleware.ClearAuthCookies(w)
+
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
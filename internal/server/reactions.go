@@ -0,0 +1,157 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+const (
+	reactionsCookieName = "reactions"
+	reactionsCookieTTL  = 365 * 24 * 60 * 60 // 1 year
+	// в cookie хранятся только последние реакции, чтобы она не превысила 4KB
+	maxStoredReactions = 200
+)
+
+func (s *Server) registerReactionsRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /blog/posts/{id}/reactions", s.toggleReaction)
+}
+
+// toggleReaction ставит реакцию или снимает её, если посетитель уже реагировал.
+// Какие реакции поставлены, хранится в подписанной cookie без учётных записей.
+func (s *Server) toggleReaction(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid post id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	reaction := r.FormValue("reaction")
+	key := reactionKey(postID, reaction)
+
+	given := s.readReactionsCookie(r)
+	alreadyGiven := slices.Contains(given, key)
+
+	counts, err := s.Blog.React(r.Context(), postID, reaction, !alreadyGiven)
+	if err != nil {
+		switch {
+		case errors.Is(err, errs.ErrInvalid):
+			s.renderError(w, "unknown reaction", err, http.StatusBadRequest)
+		case errors.Is(err, errs.ErrNotFound):
+			s.renderError(w, "post not found", err, http.StatusNotFound)
+		default:
+			s.renderError(w, "can't react to post", err, http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	if alreadyGiven {
+		given = slices.DeleteFunc(given, func(k string) bool { return k == key })
+	} else {
+		given = append(given, key)
+	}
+
+	s.setReactionsCookie(w, given)
+
+	s.renderTemplate(w, "reactions", newReactionsData(postID, counts, given))
+}
+
+// postReactions собирает данные для панели реакций на странице поста.
+func (s *Server) postReactions(r *http.Request, post *domain.Post) ReactionsData {
+	return newReactionsData(post.ID, post.Reactions, s.readReactionsCookie(r))
+}
+
+func newReactionsData(postID int, counts domain.ReactionCounts, given []string) ReactionsData {
+	data := ReactionsData{PostID: postID, Buttons: make([]ReactionButton, 0, len(domain.Reactions))}
+
+	for _, reaction := range domain.Reactions {
+		data.Buttons = append(data.Buttons, ReactionButton{
+			Reaction: reaction,
+			Count:    counts[reaction.Key],
+			Active:   slices.Contains(given, reactionKey(postID, reaction.Key)),
+		})
+	}
+
+	return data
+}
+
+func reactionKey(postID int, reaction string) string {
+	return strconv.Itoa(postID) + ":" + reaction
+}
+
+// readReactionsCookie возвращает реакции посетителя. Cookie с неверной подписью игнорируется.
+func (s *Server) readReactionsCookie(r *http.Request) []string {
+	cookie, err := r.Cookie(reactionsCookieName)
+	if err != nil {
+		return nil
+	}
+
+	payload, ok := s.verifySigned(cookie.Value)
+	if !ok || payload == "" {
+		return nil
+	}
+
+	return strings.Split(payload, ",")
+}
+
+func (s *Server) setReactionsCookie(w http.ResponseWriter, given []string) {
+	if len(given) > maxStoredReactions {
+		given = given[len(given)-maxStoredReactions:]
+	}
+
+	//nolint: exhaustruct // default cookie struct
+	cookie := http.Cookie{
+		Name:     reactionsCookieName,
+		Value:    s.sign(strings.Join(given, ",")),
+		Path:     "/",
+		MaxAge:   reactionsCookieTTL,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	http.SetCookie(w, &cookie)
+}
+
+// sign возвращает payload вместе с HMAC подписью в виде, пригодном для cookie.
+func (s *Server) sign(payload string) string {
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded))
+}
+
+func (s *Server) verifySigned(value string) (string, bool) {
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found {
+		return "", false
+	}
+
+	gotMAC, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(gotMAC, s.mac(encoded)) {
+		return "", false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+
+	return string(payload), true
+}
+
+func (s *Server) mac(data string) []byte {
+	mac := hmac.New(sha256.New, s.cookieSecret)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReactionsCookie(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+
+	rr := httptest.NewRecorder()
+	srv.setReactionsCookie(rr, []string{"1:like", "2:fire"})
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.AddCookie(cookies[0])
+	assert.Equal(t, []string{"1:like", "2:fire"}, srv.readReactionsCookie(req))
+
+	tampered := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	tampered.AddCookie(&http.Cookie{Name: reactionsCookieName, Value: "MTpsaWtl." + "forged"})
+	assert.Nil(t, srv.readReactionsCookie(tampered), "cookie with invalid signature must be ignored")
+
+	other := New(slog.Default(), config.Server{CookieSecret: "other"}, Services{})
+	assert.Nil(t, other.readReactionsCookie(req), "cookie signed with another secret must be ignored")
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	cspReportPath = "/csp-report"
+
+	// maxCSPReportSize - отчёт браузера занимает несколько сотен байт.
+	maxCSPReportSize = 16 << 10
+)
+
+// contentSecurityPolicy разрешает скрипты и стили только с сайта и из CDN,
+// которые подключают шаблоны, а встроенные - только с nonce запроса.
+// Атрибуты style остаются разрешены: их используют шаблоны для размеров,
+// а выполнить через них код нельзя.
+func contentSecurityPolicy(nonce string) string {
+	nonceSource := ""
+	if nonce != "" {
+		nonceSource = " 'nonce-" + nonce + "'"
+	}
+
+	const cdns = " https://cdn.jsdelivr.net https://cdnjs.cloudflare.com"
+
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self'" + nonceSource + cdns + " https://unpkg.com",
+		"style-src 'self'" + nonceSource + cdns + " https://fonts.googleapis.com",
+		"style-src-attr 'unsafe-inline'",
+		"font-src 'self'" + cdns + " https://fonts.gstatic.com",
+		// картинки в постах могут ссылаться на другие сайты, QR код 2FA - data: адрес
+		"img-src 'self' data: https:",
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+		"report-uri " + cspReportPath,
+	}, "; ")
+}
+
+// cspReport - тело отчёта report-uri (application/csp-report).
+type cspReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		EffectiveDirective string `json:"effective-directive"`
+		ViolatedDirective  string `json:"violated-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// cspReportHandler пишет в лог нарушения CSP, о которых сообщают браузеры.
+// Отчёты приходят без CSRF токена, поэтому маршрут стоит вне CSRF защиты,
+// а в ответ ничего не отдаётся.
+func (s *Server) cspReportHandler(w http.ResponseWriter, r *http.Request) {
+	var report cspReport
+
+	body := http.MaxBytesReader(w, r.Body, maxCSPReportSize)
+	if err := json.NewDecoder(body).Decode(&report); err != nil && !errors.Is(err, io.EOF) {
+		s.log.Debug("can't decode csp report", slog.Any("error", err))
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	directive := report.Report.EffectiveDirective
+	if directive == "" {
+		directive = report.Report.ViolatedDirective
+	}
+
+	s.log.Warn("csp violation",
+		slog.String("document", report.Report.DocumentURI),
+		slog.String("directive", directive),
+		slog.String("blocked", report.Report.BlockedURI),
+		slog.String("source", report.Report.SourceFile),
+		slog.Int("line", report.Report.LineNumber),
+		slog.String("disposition", report.Report.Disposition))
+
+	w.WriteHeader(http.StatusNoContent)
+}
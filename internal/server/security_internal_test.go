@@ -0,0 +1,102 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	t.Parallel()
+
+	get := func(srv *Server) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+
+		return rr
+	}
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+	srv.ConfigureRoutes()
+
+	rr := get(srv)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", rr.Header().Get("Referrer-Policy"))
+	assert.Contains(t, rr.Header().Get("Permissions-Policy"), "camera=()")
+	assert.Empty(t, rr.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, rr.Header().Get("Content-Security-Policy-Report-Only"))
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	assert.Contains(t, csp, "default-src 'self'")
+	assert.Contains(t, csp, "object-src 'none'")
+	assert.Contains(t, csp, "report-uri "+cspReportPath)
+
+	nonce := regexp.MustCompile(`script-src 'self' 'nonce-([^']+)'`).FindStringSubmatch(csp)
+	require.Len(t, nonce, 2)
+
+	// nonce новый для каждого ответа
+	assert.NotContains(t, get(srv).Header().Get("Content-Security-Policy"), nonce[1])
+
+	prod := New(slog.Default(), config.Server{CookieSecret: "secret", HSTS: true, CSPReportOnly: true}, Services{})
+	prod.ConfigureRoutes()
+
+	rr = get(prod)
+	assert.Equal(t, "max-age=63072000; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, rr.Header().Get("Content-Security-Policy"))
+	assert.Contains(t, rr.Header().Get("Content-Security-Policy-Report-Only"), "report-uri "+cspReportPath)
+}
+
+func TestCSPNonceInTemplates(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+
+	rr := httptest.NewRecorder()
+	srv.renderTemplate(rr, "analytics.html", AnalyticsPageData{Days: 30, CSPNonce: "abc+/123"})
+	assert.Contains(t, rr.Body.String(), `<style nonce="abc&#43;/123">`)
+
+	// без nonce встроенных скриптов и стилей быть не должно, их заблокирует CSP
+	for _, name := range []string{"index.html", "forbidden.html"} {
+		rr = httptest.NewRecorder()
+		require.NoError(t, srv.tmpl.ExecuteTemplate(rr, name, nil))
+
+		for _, tag := range regexp.MustCompile(`<(script|style)[^>]*>`).FindAllString(rr.Body.String(), -1) {
+			assert.True(t, strings.Contains(tag, " src=") || strings.Contains(tag, " nonce="), "%s: %s", name, tag)
+		}
+	}
+}
+
+func TestCSPReport(t *testing.T) {
+	t.Parallel()
+
+	srv := New(slog.Default(), config.Server{CookieSecret: "secret"}, Services{})
+	srv.ConfigureRoutes()
+
+	post := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, cspReportPath, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/csp-report")
+
+		rr := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	// отчёт принимается без CSRF токена
+	assert.Equal(t, http.StatusNoContent, post(`{"csp-report": {
+		"document-uri": "https://example.com/blog/posts/post",
+		"effective-directive": "script-src-elem",
+		"blocked-uri": "inline",
+		"line-number": 12
+	}}`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"csp-report":`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"csp-report": "`+strings.Repeat("x", maxCSPReportSize)+`"}`))
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+const (
+	// Ограничения протокола sitemaps.org для одного файла.
+	sitemapMaxURLs  = 50_000
+	sitemapMaxBytes = 50 * 1024 * 1024
+
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+type sitemapURL struct {
+	XMLName xml.Name `xml:"url"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+func (s *Server) registerSEORoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /robots.txt", s.robots)
+	mux.HandleFunc("GET /sitemap.xml", s.sitemap)
+	mux.HandleFunc("GET /sitemaps/{name}", s.sitemapPage)
+}
+
+func (s *Server) robots(w http.ResponseWriter, r *http.Request) {
+	var sb strings.Builder
+
+	sb.WriteString("User-agent: *\n")
+
+	if s.robotsCfg.AllowIndexing {
+		disallow := append([]string{"/login-admin", "/login", "/blog/posts/form-"}, s.robotsCfg.Disallow...)
+		for _, path := range disallow {
+			sb.WriteString("Disallow: " + path + "\n")
+		}
+	} else {
+		sb.WriteString("Disallow: /\n")
+	}
+
+	sb.WriteString("\nSitemap: " + s.publicURL + "/sitemap.xml\n")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = io.WriteString(w, sb.String())
+}
+
+// sitemap отдаёт карту сайта целиком или индекс, если ссылок больше,
+// чем помещается в один файл.
+func (s *Server) sitemap(w http.ResponseWriter, r *http.Request) {
+	urls, err := s.sitemapURLs(r)
+	if err != nil {
+		s.renderError(w, "can't build sitemap", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	chunks := splitSitemap(urls, sitemapMaxURLs, sitemapMaxBytes)
+	if len(chunks) <= 1 {
+		s.writeXML(w, sitemapURLSet{XMLName: xml.Name{}, Xmlns: sitemapNamespace, URLs: urls})
+
+		return
+	}
+
+	index := sitemapIndex{XMLName: xml.Name{}, Xmlns: sitemapNamespace, Sitemaps: make([]sitemapRef, 0, len(chunks))}
+
+	for i, chunk := range chunks {
+		index.Sitemaps = append(index.Sitemaps, sitemapRef{
+			Loc:     fmt.Sprintf("%s/sitemaps/%d.xml", s.publicURL, i+1),
+			LastMod: latestLastMod(chunk),
+		})
+	}
+
+	s.writeXML(w, index)
+}
+
+func (s *Server) sitemapPage(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("name"), ".xml"))
+	if err != nil || page < 1 {
+		http.Error(w, "sitemap not found", http.StatusNotFound)
+
+		return
+	}
+
+	urls, err := s.sitemapURLs(r)
+	if err != nil {
+		s.renderError(w, "can't build sitemap", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	chunks := splitSitemap(urls, sitemapMaxURLs, sitemapMaxBytes)
+	if page > len(chunks) {
+		http.Error(w, "sitemap not found", http.StatusNotFound)
+
+		return
+	}
+
+	s.writeXML(w, sitemapURLSet{XMLName: xml.Name{}, Xmlns: sitemapNamespace, URLs: chunks[page-1]})
+}
+
+// sitemapURLs собирает главную, список постов, страницы категорий и все опубликованные посты.
+func (s *Server) sitemapURLs(r *http.Request) ([]sitemapURL, error) {
+	posts, err := s.Blog.PublishedPosts(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("can't get published posts: %w", err)
+	}
+
+	categories, err := s.Blog.Categories(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("can't get categories: %w", err)
+	}
+
+	var lastUpdate time.Time
+
+	categoryUpdates := make(map[int]time.Time, len(categories))
+
+	for _, post := range posts {
+		if post.UpdatedAt.After(lastUpdate) {
+			lastUpdate = post.UpdatedAt
+		}
+
+		if post.UpdatedAt.After(categoryUpdates[post.CategoryID]) {
+			categoryUpdates[post.CategoryID] = post.UpdatedAt
+		}
+	}
+
+	urls := make([]sitemapURL, 0, len(posts)+len(categories)+2) //nolint: mnd // index and posts pages
+	urls = append(urls,
+		sitemapURL{XMLName: xml.Name{}, Loc: s.publicURL + "/", LastMod: formatLastMod(lastUpdate)},
+		sitemapURL{XMLName: xml.Name{}, Loc: s.publicURL + "/blog/posts", LastMod: formatLastMod(lastUpdate)},
+	)
+
+	for _, category := range categories {
+		urls = append(urls, sitemapURL{
+			XMLName: xml.Name{},
+			Loc:     s.publicURL + "/blog/posts?category_id=" + strconv.Itoa(category.ID),
+			LastMod: formatLastMod(categoryUpdates[category.ID]),
+		})
+	}
+
+	for _, post := range posts {
+		urls = append(urls, postSitemapURL(s.publicURL, post))
+	}
+
+	return urls, nil
+}
+
+func (s *Server) writeXML(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = io.WriteString(w, xml.Header)
+
+	if err := xml.NewEncoder(w).Encode(data); err != nil {
+		s.log.Error("can't encode xml", slog.Any("error", err))
+	}
+}
+
+func postSitemapURL(publicURL string, post *domain.Post) sitemapURL {
+	return sitemapURL{
+		XMLName: xml.Name{},
+		Loc:     publicURL + "/blog/posts/" + post.Slug,
+		LastMod: formatLastMod(post.UpdatedAt),
+	}
+}
+
+// splitSitemap делит ссылки на части, каждая из которых укладывается
+// в maxURLs ссылок и maxBytes байт после сериализации.
+func splitSitemap(urls []sitemapURL, maxURLs int, maxBytes int) [][]sitemapURL {
+	// заголовок xml и обёртка urlset
+	overhead := len(xml.Header) + len(`<urlset xmlns="`+sitemapNamespace+`"></urlset>`)
+
+	var (
+		chunks  [][]sitemapURL
+		current []sitemapURL
+	)
+
+	size := overhead
+
+	for _, u := range urls {
+		encoded, err := xml.Marshal(u)
+		if err != nil {
+			continue
+		}
+
+		if len(current) > 0 && (len(current) >= maxURLs || size+len(encoded) > maxBytes) {
+			chunks = append(chunks, current)
+			current = nil
+			size = overhead
+		}
+
+		current = append(current, u)
+		size += len(encoded)
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+func latestLastMod(urls []sitemapURL) string {
+	var latest string
+
+	// формат RFC 3339 в UTC сравнивается лексикографически
+	for _, u := range urls {
+		if u.LastMod > latest {
+			latest = u.LastMod
+		}
+	}
+
+	return latest
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
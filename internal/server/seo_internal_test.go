@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/xml"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSitemap(t *testing.T) {
+	t.Parallel()
+
+	urls := make([]sitemapURL, 0, 10)
+	for i := range 10 {
+		urls = append(urls, sitemapURL{XMLName: xml.Name{}, Loc: "https://example.com/" + strconv.Itoa(i), LastMod: ""})
+	}
+
+	tests := []struct {
+		name       string
+		maxURLs    int
+		maxBytes   int
+		wantChunks []int
+	}{
+		{name: "fits in one file", maxURLs: 50_000, maxBytes: sitemapMaxBytes, wantChunks: []int{10}},
+		{name: "split by url count", maxURLs: 4, maxBytes: sitemapMaxBytes, wantChunks: []int{4, 4, 2}},
+		{name: "split by size", maxURLs: 50_000, maxBytes: 300, wantChunks: []int{4, 4, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			chunks := splitSitemap(urls, tt.maxURLs, tt.maxBytes)
+
+			sizes := make([]int, 0, len(chunks))
+			for _, chunk := range chunks {
+				sizes = append(sizes, len(chunk))
+			}
+
+			assert.Equal(t, tt.wantChunks, sizes)
+		})
+	}
+}
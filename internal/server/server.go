@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/middleware"
 )
 
 //go:embed views/*
@@ -25,12 +26,17 @@ var templatesFS embed.FS
 const (
 	shutdownTimeout     = 5 * time.Second
 	readerHeaderTimeout = 5 * time.Second
+
+	// compressMinSize - ответы короче отдаются без сжатия.
+	compressMinSize = 1024
 )
 
 // Services содержит в себе зависимости для web сервера.
 type Services struct {
-	Blog Blog
-	Auth Auth
+	Blog      Blog
+	Auth      Auth
+	Analytics Analytics
+	Comments  Comments
 }
 
 type Server struct {
@@ -39,7 +45,17 @@ type Server struct {
 	log  *slog.Logger
 	tmpl *template.Template
 
-	pageLimit int
+	publicURL    string
+	robotsCfg    config.Robots
+	trustProxy   bool
+	cookieSecret []byte
+	pageLimit    int
+	static       bool // страницы рендерятся для статического хостинга, см. SiteBuilder
+
+	assets       *staticAssets
+	viewsVersion string // хэш шаблонов и статики для ETag страниц
+
+	security middleware.SecurityOptions
 }
 
 func New(log *slog.Logger, cfg config.Server, dependency Services) *Server {
@@ -53,37 +69,93 @@ func New(log *slog.Logger, cfg config.Server, dependency Services) *Server {
 
 	const pageLimit = 5
 
+	// встроенные файлы читаются всегда, ошибка здесь - ошибка сборки, как у template.Must
+	assets, err := newStaticAssets(mustSub(templatesFS, "views/static"), mustSub(precompressedFS, "precompressed"))
+	if err != nil {
+		panic(err)
+	}
+
+	viewsVersion, err := contentVersion(templatesFS)
+	if err != nil {
+		panic(err)
+	}
+
+	funcs := template.FuncMap{"static": assets.URL}
+
 	return &Server{
 		Server:   srv,
 		Services: dependency,
 		log:      log,
-		tmpl: template.Must(template.ParseFS(templatesFS,
-			"views/*.html", "views/blog/*.html")),
-		pageLimit: pageLimit,
+		tmpl: template.Must(template.New("views").Funcs(funcs).ParseFS(templatesFS,
+			"views/*.html", "views/blog/*.html", "views/admin/*.html")),
+		publicURL:    cfg.PublicURL,
+		robotsCfg:    cfg.Robots,
+		trustProxy:   cfg.TrustProxyHeaders,
+		cookieSecret: []byte(cfg.CookieSecret),
+		pageLimit:    pageLimit,
+		static:       false,
+		assets:       assets,
+		viewsVersion: viewsVersion,
+		security: middleware.SecurityOptions{
+			HSTS:       cfg.HSTS,
+			ReportOnly: cfg.CSPReportOnly,
+			Policy:     contentSecurityPolicy,
+		},
 	}
 }
 
-func (s *Server) ConfigureRoutes() {
-	mux := http.NewServeMux()
-
-	staticFS, err := fs.Sub(templatesFS, "views/static")
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
 	if err != nil {
-		s.log.Error("can't mount static directory", slog.Any("error", err))
+		panic(err)
 	}
 
-	if staticFS != nil {
-		mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(staticFS)))
+	return sub
+}
+
+// StaticFile возвращает содержимое файла из встроенной директории views/static.
+func StaticFile(name string) ([]byte, error) {
+	data, err := templatesFS.ReadFile("views/static/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("can't read static file %s: %w", name, err)
 	}
 
+	return data, nil
+}
+
+func (s *Server) ConfigureRoutes() {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /static/{name...}", s.assets)
+
 	mux.Handle("GET /images/", http.StripPrefix("/images/", http.FileServerFS(os.DirFS("images/"))))
 
 	mux.HandleFunc("GET /ping", s.ping)
 	mux.HandleFunc("GET /", s.htmlIndex)
 
 	s.registerBlogRoutes(mux)
+	s.registerSEORoutes(mux)
+	s.registerAnalyticsRoutes(mux)
+	s.registerCommentsRoutes(mux)
+	s.registerReactionsRoutes(mux)
 	s.registerAuthRoutes(mux)
+	s.registerSessionsRoutes(mux)
+	s.registerTOTPRoutes(mux)
+	s.registerUsersRoutes(mux)
+	s.registerAPITokensRoutes(mux)
+	s.registerArchiveRoutes(mux)
+	s.registerAPIRoutes(mux)
+
+	csrf := middleware.CSRF(s.cookieSecret, s.log, http.HandlerFunc(s.csrfFailed))
+
+	// браузеры отправляют отчёты CSP без CSRF токена
+	root := http.NewServeMux()
+	root.HandleFunc("POST "+cspReportPath, s.cspReportHandler)
+	root.Handle("/", csrf(mux))
+
+	security := middleware.SecurityHeaders(s.security, s.log)
 
-	s.Handler = mux
+	s.Handler = security(middleware.Compress(compressMinSize)(root))
 }
 
 func (s *Server) Run(ctx context.Context) error {
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+)
+
+func (s *Server) registerSessionsRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/sessions", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.sessionsPage)))
+	mux.Handle("DELETE /admin/sessions/{id}", middleware.RequireAuth(s.Auth, s.log)(http.HandlerFunc(s.revokeSession)))
+}
+
+func (s *Server) sessionsPage(w http.ResponseWriter, r *http.Request) {
+	user := middleware.PrincipalFrom(r.Context())
+
+	sessions, err := s.Auth.Sessions(r.Context(), sessionsOwner(user))
+	if err != nil {
+		s.renderError(w, "can't get sessions", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData := SessionsPageData{
+		CSRFToken: middleware.CSRFToken(r.Context()),
+		AllUsers:  user.CanManageUsers(),
+		Sessions:  make([]SessionView, 0, len(sessions)),
+	}
+
+	for _, session := range sessions {
+		tmplData.Sessions = append(tmplData.Sessions, SessionView{
+			ID:        session.ID,
+			Username:  session.Username,
+			UserAgent: session.UserAgent,
+			IP:        session.IP,
+			CreatedAt: session.CreatedAt.Format("02.01.2006 15:04"),
+			ExpiresAt: session.ExpiresAt.Format("02.01.2006 15:04"),
+			Current:   session.ID == user.SessionID,
+		})
+	}
+
+	s.renderTemplate(w, "sessions.html", tmplData)
+}
+
+// revokeSession отзывает сессию, пустой ответ удаляет её строку из таблицы.
+// После отзыва текущей сессии пользователь перенаправляется на главную.
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request) {
+	user := middleware.PrincipalFrom(r.Context())
+	sessionID := r.PathValue("id")
+
+	if err := s.Auth.RevokeSession(r.Context(), sessionID, sessionsOwner(user)); err != nil {
+		s.renderError(w, "can't revoke session", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	if sessionID == user.SessionID {
+		middleware.ClearAuthCookies(w)
+		w.Header().Set("HX-Redirect", "/")
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// sessionsOwner - чьи сессии видит пользователь: админ видит сессии всех (0),
+// остальные только свои.
+func sessionsOwner(user *authdomain.Principal) int {
+	if user.CanManageUsers() {
+		return 0
+	}
+
+	return user.UserID
+}
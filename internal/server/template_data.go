@@ -1,16 +1,232 @@
 package server
 
-import "github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+import (
+	"html/template"
+
+	analyticsdomain "github.com/arevbond/arevbond-blog/internal/service/analytics/domain"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
 
 type PostsPageData struct {
+	CSRFToken  string
 	Categories []*domain.Category
 	PostsData
 }
 
+type PostPageData struct {
+	CSRFToken    string
+	ID           int
+	Title        string
+	Description  string
+	Content      template.HTML
+	Slug         string
+	CategoryName string
+	AuthorID     int
+	AuthorName   string
+	CreatedAt    string
+	UpdatedAt    string
+	IsPublished  bool
+	User         *authdomain.Principal
+	PageURL      string
+	OGImageURL   string
+	Comments     []CommentView
+	CommentForm  CommentFormData
+	Reactions    ReactionsData
+	Static       bool
+}
+
+type LoginPageData struct {
+	CSRFToken string
+}
+
 type PostsData struct {
 	SelectedCategoryID int
 	Posts              []*domain.Post
-	IsAdmin            bool
+	User               *authdomain.Principal // nil для анонимного посетителя
 	HasNextPages       bool
 	NextOffset         int
+	Static             bool // страница собирается командой build, htmx запросов нет
+}
+
+type AnalyticsPageData struct {
+	Days          int
+	Since         string
+	TotalViews    int
+	TotalVisitors int
+	Posts         []*analyticsdomain.PostStats
+	Referrers     []*analyticsdomain.ReferrerStats
+	Chart         []ChartBar
+	Cache         domain.CacheStats
+	CSPNonce      string
+}
+
+// ChartBar - столбец графика просмотров, Height в процентах от максимума.
+type ChartBar struct {
+	Label    string
+	Views    int
+	Visitors int
+	Height   int
+}
+
+type CommentView struct {
+	ID         int
+	AuthorName string
+	Content    template.HTML
+	CreatedAt  string
+	PostTitle  string
+	PostSlug   string
+	IsReply    bool
+	Replies    []CommentView
+	ReplyForm  CommentFormData
+}
+
+// CommentFormData - данные формы комментария, ParentID = 0 для нового обсуждения.
+type CommentFormData struct {
+	PostID   int
+	ParentID int
+}
+
+type CommentResultData struct {
+	Success bool
+	Message string
+}
+
+type ModerationPageData struct {
+	CSRFToken string
+	Status    string
+	Comments  []CommentView
+}
+
+type ReactionsData struct {
+	PostID  int
+	Buttons []ReactionButton
+}
+
+type ReactionButton struct {
+	domain.Reaction
+	Count  int
+	Active bool // посетитель уже поставил эту реакцию
+}
+
+type SessionsPageData struct {
+	CSRFToken string
+	AllUsers  bool // админ видит сессии всех пользователей
+	Sessions  []SessionView
+}
+
+type SessionView struct {
+	ID        string
+	Username  string
+	UserAgent string
+	IP        string
+	CreatedAt string
+	ExpiresAt string
+	Current   bool // сессия, из которой открыта страница
+}
+
+type TOTPPageData struct {
+	CSRFToken     string
+	Enabled       bool
+	Required      bool // 2FA обязательна по конфигурации, отключить нельзя
+	RecoveryCodes int  // сколько кодов восстановления ещё не использовано
+	FreshMFA      bool // код вводился недавно, опасные действия доступны
+}
+
+type TOTPEnrollmentData struct {
+	Secret string
+	QRCode template.URL
+}
+
+type RecoveryCodesData struct {
+	Codes []string
+}
+
+type TOTPMessageData struct {
+	Success bool
+	Message string
+}
+
+type UsersPageData struct {
+	CSRFToken string
+	Users     []UserView
+	Roles     []authdomain.Role
+	Success   bool
+	Message   string // результат последнего действия, пусто при открытии страницы
+}
+
+type UserView struct {
+	ID          int
+	Username    string
+	DisplayName string
+	Role        authdomain.Role
+	CreatedAt   string
+	Current     bool // пользователь, который открыл страницу
+}
+
+type APITokensPageData struct {
+	CSRFToken string
+	Tokens    []APITokenView
+	Users     []UserView
+	Scopes    []authdomain.Scope
+	Success   bool
+	Message   string
+	NewToken  string // показывается один раз сразу после создания
+}
+
+type APITokenView struct {
+	ID         int
+	Name       string
+	Username   string
+	Prefix     string
+	Scopes     []authdomain.Scope
+	CreatedAt  string
+	ExpiresAt  string
+	LastUsedAt string // пусто, если токеном ещё не пользовались
+	Active     bool
+}
+
+type PostConflictData struct {
+	CurrentVersion int
+	Changes        []PostChangeView
+}
+
+type PostChangeView struct {
+	Label   string
+	Yours   string
+	Current string
+	Diff    []domain.DiffLine // только для содержимого: от сохранённого к вашему
+}
+
+// PostFormData - форма нового поста. При ошибках проверки форма
+// показывается снова с введёнными значениями и Errors по полям.
+type PostFormData struct {
+	CSRFToken   string
+	Categories  []*domain.Category
+	Title       string
+	Slug        string
+	Description string
+	CategoryID  int
+	Errors      map[string]string
+}
+
+// PostFormErrorsData - ошибки формы правки, каждое поле заменяется
+// отдельно, так что исправленные поля очищаются.
+type PostFormErrorsData struct {
+	Fields []PostFieldError
+}
+
+type PostFieldError struct {
+	Field   string
+	Message string // пустое - поле в порядке
+}
+
+type ArchivePageData struct {
+	CSRFToken string
+	Policies  []domain.ConflictPolicy
+}
+
+type ArchiveImportData struct {
+	Report *domain.ImportReport
+	Error  string // пусто, если архив загружен целиком
 }
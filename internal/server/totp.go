@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+type TOTP interface {
+	TOTPRequired() bool
+	TOTPEnabled(ctx context.Context, userID int) (bool, error)
+	UnusedRecoveryCodes(ctx context.Context, userID int) (int, error)
+	BeginTOTPEnrollment(ctx context.Context, userID int, account string) (authdomain.TOTPEnrollment, error)
+	ConfirmTOTP(
+		ctx context.Context,
+		user *authdomain.Principal,
+		code string,
+		meta authdomain.SessionMeta,
+	) ([]string, authdomain.Tokens, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error)
+	DisableTOTP(ctx context.Context, userID int) error
+	NewMFAChallenge(userID int) (string, error)
+	VerifySecondFactor(
+		ctx context.Context,
+		challenge, code string,
+		meta authdomain.SessionMeta,
+	) (*authdomain.User, error)
+	StepUp(
+		ctx context.Context,
+		user *authdomain.Principal,
+		code string,
+		meta authdomain.SessionMeta,
+	) (authdomain.Tokens, error)
+}
+
+func (s *Server) registerTOTPRoutes(mux *http.ServeMux) {
+	requireAuth := middleware.RequireAuth(s.Auth, s.log)
+	requireFreshMFA := func(h http.HandlerFunc) http.Handler {
+		return requireAuth(middleware.RequireFreshMFA()(h))
+	}
+
+	mux.Handle("GET /admin/2fa", requireAuth(http.HandlerFunc(s.totpPage)))
+	mux.Handle("POST /admin/2fa/setup", requireAuth(http.HandlerFunc(s.beginTOTPEnrollment)))
+	mux.Handle("POST /admin/2fa/confirm", requireAuth(http.HandlerFunc(s.confirmTOTP)))
+	mux.Handle("POST /admin/2fa/step-up", requireAuth(http.HandlerFunc(s.stepUpTOTP)))
+	mux.Handle("POST /admin/2fa/recovery-codes", requireFreshMFA(s.regenerateRecoveryCodes))
+	mux.Handle("DELETE /admin/2fa", requireFreshMFA(s.disableTOTP))
+}
+
+func (s *Server) totpPage(w http.ResponseWriter, r *http.Request) {
+	user := middleware.PrincipalFrom(r.Context())
+
+	enabled, err := s.Auth.TOTPEnabled(r.Context(), user.UserID)
+	if err != nil {
+		s.renderError(w, "can't check totp", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData := TOTPPageData{
+		CSRFToken:     middleware.CSRFToken(r.Context()),
+		Enabled:       enabled,
+		Required:      s.Auth.TOTPRequired(),
+		RecoveryCodes: 0,
+		FreshMFA:      false,
+	}
+
+	if enabled {
+		tmplData.RecoveryCodes, err = s.Auth.UnusedRecoveryCodes(r.Context(), user.UserID)
+		if err != nil {
+			s.renderError(w, "can't count recovery codes", err, http.StatusInternalServerError)
+
+			return
+		}
+
+		tmplData.FreshMFA = user.FreshMFA(time.Now())
+	}
+
+	s.renderTemplate(w, "totp.html", tmplData)
+}
+
+func (s *Server) beginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.PrincipalFrom(r.Context())
+
+	enrollment, err := s.Auth.BeginTOTPEnrollment(r.Context(), user.UserID, user.Username)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) {
+			s.renderTemplate(w, "totp-message", TOTPMessageData{Success: false, Message: "2FA уже включена."})
+
+			return
+		}
+
+		s.renderError(w, "can't begin totp enrollment", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.renderTemplate(w, "totp-enrollment", TOTPEnrollmentData{
+		Secret: enrollment.Secret,
+		// #nosec G203 - png generated on server
+		QRCode: template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(enrollment.QRCode)),
+	})
+}
+
+func (s *Server) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	meta := authdomain.SessionMeta{UserAgent: r.UserAgent(), IP: s.clientIP(r)}
+
+	codes, tokens, err := s.Auth.ConfirmTOTP(r.Context(), middleware.PrincipalFrom(r.Context()), r.FormValue("code"), meta)
+	if err != nil {
+		var lockedErr *authdomain.LoginLockedError
+
+		switch {
+		case errors.As(err, &lockedErr):
+			s.renderTemplate(w, "totp-message", TOTPMessageData{
+				Success: false,
+				Message: "Слишком много попыток. Попробуйте позже.",
+			})
+		case errors.Is(err, errs.ErrInvalid), errors.Is(err, errs.ErrNotFound):
+			s.renderTemplate(w, "totp-message", TOTPMessageData{
+				Success: false,
+				Message: "Код не подошёл. Проверьте время на телефоне и попробуйте ещё раз.",
+			})
+		case errors.Is(err, errs.ErrDuplicate):
+			s.renderTemplate(w, "totp-message", TOTPMessageData{Success: false, Message: "2FA уже включена."})
+		default:
+			s.renderError(w, "can't confirm totp", err, http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	middleware.SetAuthCookies(w, tokens)
+
+	s.renderTemplate(w, "totp-recovery-codes", RecoveryCodesData{Codes: codes})
+}
+
+// stepUpTOTP подтверждает второй фактор заново, чтобы открыть опасные действия.
+func (s *Server) stepUpTOTP(w http.ResponseWriter, r *http.Request) {
+	meta := authdomain.SessionMeta{UserAgent: r.UserAgent(), IP: s.clientIP(r)}
+
+	tokens, err := s.Auth.StepUp(r.Context(), middleware.PrincipalFrom(r.Context()), r.FormValue("code"), meta)
+	if err != nil {
+		var lockedErr *authdomain.LoginLockedError
+
+		switch {
+		case errors.As(err, &lockedErr):
+			s.renderTemplate(w, "totp-message", TOTPMessageData{
+				Success: false,
+				Message: "Слишком много попыток. Попробуйте позже.",
+			})
+		case errors.Is(err, errs.ErrInvalid):
+			s.renderTemplate(w, "totp-message", TOTPMessageData{Success: false, Message: "Неверный код."})
+		default:
+			s.renderError(w, "can't verify totp", err, http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	middleware.SetAuthCookies(w, tokens)
+
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := s.Auth.RegenerateRecoveryCodes(r.Context(), middleware.PrincipalFrom(r.Context()).UserID)
+	if err != nil {
+		s.renderError(w, "can't regenerate recovery codes", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.renderTemplate(w, "totp-recovery-codes", RecoveryCodesData{Codes: codes})
+}
+
+func (s *Server) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.Auth.DisableTOTP(r.Context(), middleware.PrincipalFrom(r.Context()).UserID); err != nil {
+		if errors.Is(err, errs.ErrInvalid) {
+			s.renderTemplate(w, "totp-message", TOTPMessageData{
+				Success: false,
+				Message: "2FA обязательна по настройкам сервера и не может быть отключена.",
+			})
+
+			return
+		}
+
+		s.renderError(w, "can't disable totp", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/arevbond/arevbond-blog/internal/middleware"
+	authdomain "github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+type Users interface {
+	Users(ctx context.Context) ([]*authdomain.User, error)
+	CreateUser(ctx context.Context, params authdomain.CreateUserParams) (*authdomain.User, error)
+	SetUserRole(ctx context.Context, id int, role authdomain.Role) error
+	SetUserPassword(ctx context.Context, id int, password string) error
+	DeleteUser(ctx context.Context, id int) error
+}
+
+func (s *Server) registerUsersRoutes(mux *http.ServeMux) {
+	requireAdmin := func(h http.HandlerFunc) http.Handler {
+		return middleware.RequireAuth(s.Auth, s.log)(middleware.RequireRole(authdomain.RoleAdmin)(h))
+	}
+
+	// изменения учётных записей требуют свежего второго фактора, если он подключён
+	freshMFA := middleware.RequireFreshMFAIfEnrolled(s.Auth, s.log)
+	requireFreshMFA := func(h http.HandlerFunc) http.Handler {
+		return middleware.RequireAuth(s.Auth, s.log)(middleware.RequireRole(authdomain.RoleAdmin)(freshMFA(h)))
+	}
+
+	mux.Handle("GET /admin/users", requireAdmin(s.usersPage))
+	mux.Handle("POST /admin/users", requireFreshMFA(s.createUser))
+	mux.Handle("PATCH /admin/users/{id}/role", requireFreshMFA(s.setUserRole))
+	mux.Handle("POST /admin/users/{id}/password", requireFreshMFA(s.setUserPassword))
+	mux.Handle("DELETE /admin/users/{id}", requireFreshMFA(s.deleteUser))
+}
+
+func (s *Server) usersPage(w http.ResponseWriter, r *http.Request) {
+	tmplData, err := s.usersData(r, true, "")
+	if err != nil {
+		s.renderError(w, "can't get users", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.renderTemplate(w, "users.html", tmplData)
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	_, err := s.Auth.CreateUser(r.Context(), authdomain.CreateUserParams{
+		Username:    r.FormValue("username"),
+		DisplayName: r.FormValue("display_name"),
+		Password:    r.FormValue("password"),
+		Role:        authdomain.Role(r.FormValue("role")),
+	})
+
+	s.renderUsersResult(w, r, err, "Пользователь создан.")
+}
+
+func (s *Server) setUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid user id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	err = s.Auth.SetUserRole(r.Context(), userID, authdomain.Role(r.FormValue("role")))
+
+	s.renderUsersResult(w, r, err, "Роль изменена.")
+}
+
+func (s *Server) setUserPassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid user id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	err = s.Auth.SetUserPassword(r.Context(), userID, r.FormValue("password"))
+
+	s.renderUsersResult(w, r, err, "Пароль изменён, сессии пользователя завершены.")
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		s.renderError(w, "invalid user id", err, http.StatusBadRequest)
+
+		return
+	}
+
+	err = s.Auth.DeleteUser(r.Context(), userID)
+
+	s.renderUsersResult(w, r, err, "Пользователь удалён.")
+}
+
+// renderUsersResult перерисовывает таблицу пользователей с сообщением о результате действия.
+func (s *Server) renderUsersResult(w http.ResponseWriter, r *http.Request, err error, successMsg string) {
+	success, message := true, successMsg
+
+	switch {
+	case err == nil:
+	case errors.Is(err, errs.ErrDuplicate):
+		success, message = false, "Пользователь с таким именем уже есть."
+	case errors.Is(err, errs.ErrNotFound):
+		success, message = false, "Пользователь не найден."
+	case errors.Is(err, errs.ErrInvalid):
+		success, message = false, userErrorMessage(err)
+	default:
+		s.renderError(w, "can't update users", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	tmplData, err := s.usersData(r, success, message)
+	if err != nil {
+		s.renderError(w, "can't get users", err, http.StatusInternalServerError)
+
+		return
+	}
+
+	s.renderTemplate(w, "users-table", tmplData)
+}
+
+func userErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, authdomain.ErrLastAdmin):
+		return "Нельзя убрать последнего администратора."
+	case errors.Is(err, authdomain.ErrWeakPassword):
+		return "Пароль должен быть не короче 12 символов."
+	default:
+		return "Имя: латиница, цифры, точка, дефис и подчёркивание, от 2 до 50 символов. Роль должна быть из списка."
+	}
+}
+
+func (s *Server) usersData(r *http.Request, success bool, message string) (UsersPageData, error) {
+	users, err := s.Auth.Users(r.Context())
+	if err != nil {
+		return UsersPageData{}, fmt.Errorf("can't get users: %w", err)
+	}
+
+	current := middleware.PrincipalFrom(r.Context())
+
+	tmplData := UsersPageData{
+		CSRFToken: middleware.CSRFToken(r.Context()),
+		Users:     make([]UserView, 0, len(users)),
+		Roles:     authdomain.Roles,
+		Success:   success,
+		Message:   message,
+	}
+
+	for _, user := range users {
+		tmplData.Users = append(tmplData.Users, UserView{
+			ID:          user.ID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt.Format("02.01.2006"),
+			Current:     user.ID == current.UserID,
+		})
+	}
+
+	return tmplData, nil
+}
@@ -0,0 +1,11 @@
+// Запрос с устаревшим или отсутствующим CSRF токеном получает страницу 403.
+// htmx не вставляет ответы с ошибкой, поэтому показываем её целиком.
+document.addEventListener("htmx:responseError", function (event) {
+    var xhr = event.detail.xhr;
+
+    if (xhr.status === 403 && xhr.getResponseHeader("X-CSRF-Rejected")) {
+        document.open();
+        document.write(xhr.responseText);
+        document.close();
+    }
+});
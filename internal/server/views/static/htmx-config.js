@@ -0,0 +1,5 @@
+// Настройки htmx, которые нужны Content Security Policy: htmx не вставляет
+// свой <style> с индикаторами (они в style.css) и не выполняет js из атрибутов.
+// Настройки из <meta name="htmx-config"> на страницах применяются поверх.
+htmx.config.includeIndicatorStyles = false;
+htmx.config.allowEval = false;
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"time"
+)
+
+// PageView - один просмотр страницы, как его видит http сервер.
+type PageView struct {
+	Path      string
+	PostID    int // 0 для страниц, не относящихся к посту
+	Referrer  string
+	IP        string
+	UserAgent string
+	At        time.Time
+}
+
+// ViewCounter - накопленное количество просмотров страницы за день.
+type ViewCounter struct {
+	Day      time.Time
+	Path     string
+	PostID   int
+	Referrer string
+	Views    int
+}
+
+// Visitor - уникальный посетитель страницы за день.
+// Hash вычисляется с солью, которая меняется каждый день.
+type Visitor struct {
+	Day    time.Time
+	Path   string
+	PostID int
+	Hash   string
+}
+
+type PostStats struct {
+	PostID   int    `db:"post_id"`
+	Title    string `db:"title"`
+	Slug     string `db:"slug"`
+	Views    int    `db:"views"`
+	Visitors int    `db:"visitors"`
+}
+
+type ReferrerStats struct {
+	Referrer string `db:"referrer"`
+	Views    int    `db:"views"`
+}
+
+type DayStats struct {
+	Day      time.Time `db:"day"`
+	Views    int       `db:"views"`
+	Visitors int       `db:"visitors"`
+}
+
+type Dashboard struct {
+	Since     time.Time
+	Posts     []*PostStats
+	Referrers []*ReferrerStats
+	Daily     []*DayStats
+}
@@ -0,0 +1,16 @@
+package analytics
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/service"
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+func NewAnalyticsModule(log *slog.Logger, db *sqlx.DB, flushInterval time.Duration, ownHost string) *service.Analytics {
+	viewsRepo := storage.NewViewsRepo(log, db)
+
+	return service.New(log, viewsRepo, flushInterval, ownHost)
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/domain"
+)
+
+const (
+	// при переполнении буфера просмотры сбрасываются в базу раньше интервала.
+	maxBufferedVisitors = 10_000
+	// после неудачного сброса в буфер возвращается не больше посетителей,
+	// чтобы следующий сброс не запускался на каждом просмотре
+	maxRetainedVisitors = maxBufferedVisitors / 2
+	// сверх этого числа счётчиков просмотры возвращаются без источника
+	// перехода, а сверх maxRetainedCounters - отбрасываются
+	maxDetailedCounters = 10_000
+	maxRetainedCounters = 2 * maxDetailedCounters
+	topReferrersLimit   = 20
+	saltSize            = 32
+)
+
+type ViewsRepository interface {
+	Save(ctx context.Context, counters []*domain.ViewCounter, visitors []*domain.Visitor) error
+	PostStats(ctx context.Context, since time.Time) ([]*domain.PostStats, error)
+	ReferrerStats(ctx context.Context, since time.Time, limit int) ([]*domain.ReferrerStats, error)
+	DailyStats(ctx context.Context, since time.Time) ([]*domain.DayStats, error)
+}
+
+type counterKey struct {
+	day      string
+	path     string
+	postID   int
+	referrer string
+}
+
+type visitorKey struct {
+	day  string
+	path string
+	hash string
+}
+
+// Analytics считает просмотры страниц без cookies и сторонних трекеров.
+// Посетитель определяется хэшем ip и user agent с солью, которая живёт
+// только в памяти и меняется раз в сутки, поэтому хэши разных дней не связать.
+// Просмотры копятся в памяти и периодически сбрасываются в базу.
+type Analytics struct {
+	log           *slog.Logger
+	ViewsRepo     ViewsRepository
+	flushInterval time.Duration
+	ownHost       string
+
+	mu       sync.Mutex
+	saltDay  string
+	salt     []byte
+	counters map[counterKey]int
+	visitors map[visitorKey]int
+	flushNow chan struct{}
+}
+
+func New(log *slog.Logger, views ViewsRepository, flushInterval time.Duration, ownHost string) *Analytics {
+	return &Analytics{
+		log:           log,
+		ViewsRepo:     views,
+		flushInterval: flushInterval,
+		ownHost:       strings.TrimPrefix(strings.ToLower(ownHost), "www."),
+		mu:            sync.Mutex{},
+		saltDay:       "",
+		salt:          nil,
+		counters:      make(map[counterKey]int),
+		visitors:      make(map[visitorKey]int),
+		flushNow:      make(chan struct{}, 1),
+	}
+}
+
+// TrackView учитывает просмотр. Боты игнорируются.
+func (a *Analytics) TrackView(view domain.PageView) {
+	if IsBot(view.UserAgent) {
+		return
+	}
+
+	day := view.At.UTC().Format(time.DateOnly)
+	referrer := a.referrerHost(view.Referrer)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	hash := a.visitorHash(day, view.IP, view.UserAgent)
+
+	a.counters[counterKey{day: day, path: view.Path, postID: view.PostID, referrer: referrer}]++
+	a.visitors[visitorKey{day: day, path: view.Path, hash: hash}] = view.PostID
+
+	if len(a.visitors) >= maxBufferedVisitors {
+		select {
+		case a.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run периодически сбрасывает накопленные просмотры в базу до отмены ctx.
+// Перед выходом выполняется финальный сброс.
+func (a *Analytics) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx уже отменён, поэтому для финального сброса нужен новый
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), a.flushInterval)
+			a.Flush(flushCtx)
+			cancel()
+
+			return
+		case <-ticker.C:
+			a.Flush(ctx)
+		case <-a.flushNow:
+			a.Flush(ctx)
+		}
+	}
+}
+
+// Flush сохраняет буфер в базу. При ошибке данные возвращаются в буфер
+// в пределах лимитов, лишнее отбрасывается.
+func (a *Analytics) Flush(ctx context.Context) {
+	a.mu.Lock()
+	counters, visitors := a.counters, a.visitors
+	a.counters = make(map[counterKey]int)
+	a.visitors = make(map[visitorKey]int)
+	a.mu.Unlock()
+
+	if len(counters) == 0 && len(visitors) == 0 {
+		return
+	}
+
+	err := a.ViewsRepo.Save(ctx, toViewCounters(counters), toVisitors(visitors))
+	if err == nil {
+		return
+	}
+
+	a.log.Error("can't flush page views", slog.Any("error", err), slog.Int("pages", len(counters)))
+
+	droppedViews, droppedVisitors := a.restore(counters, visitors)
+	if droppedViews > 0 || droppedVisitors > 0 {
+		a.log.Warn("page views dropped, buffer is full",
+			slog.Int("views", droppedViews),
+			slog.Int("visitors", droppedVisitors))
+	}
+}
+
+// restore возвращает в буфер данные неудачного сброса. Пока база недоступна,
+// буфер не растёт без предела: новые счётчики сверх лимита складываются
+// в счётчик страницы без источника перехода, а если места нет и для него,
+// отбрасываются вместе с лишними посетителями. Возвращает число потерянных
+// просмотров и посетителей.
+func (a *Analytics) restore(counters map[counterKey]int, visitors map[visitorKey]int) (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var droppedViews, droppedVisitors int
+
+	for key, views := range counters {
+		if _, ok := a.counters[key]; !ok && len(a.counters) >= maxDetailedCounters {
+			key.referrer = ""
+		}
+
+		if _, ok := a.counters[key]; !ok && len(a.counters) >= maxRetainedCounters {
+			droppedViews += views
+
+			continue
+		}
+
+		a.counters[key] += views
+	}
+
+	for key, postID := range visitors {
+		if _, ok := a.visitors[key]; !ok && len(a.visitors) >= maxRetainedVisitors {
+			droppedVisitors++
+
+			continue
+		}
+
+		a.visitors[key] = postID
+	}
+
+	return droppedViews, droppedVisitors
+}
+
+func (a *Analytics) Dashboard(ctx context.Context, days int) (*domain.Dashboard, error) {
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day()-days+1, 0, 0, 0, 0, time.UTC)
+
+	posts, err := a.ViewsRepo.PostStats(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("analytics: %w", err)
+	}
+
+	referrers, err := a.ViewsRepo.ReferrerStats(ctx, since, topReferrersLimit)
+	if err != nil {
+		return nil, fmt.Errorf("analytics: %w", err)
+	}
+
+	daily, err := a.ViewsRepo.DailyStats(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("analytics: %w", err)
+	}
+
+	return &domain.Dashboard{Since: since, Posts: posts, Referrers: referrers, Daily: daily}, nil
+}
+
+// visitorHash должен вызываться под a.mu.
+func (a *Analytics) visitorHash(day, ip, userAgent string) string {
+	if a.saltDay != day {
+		a.salt = make([]byte, saltSize)
+		_, _ = rand.Read(a.salt)
+		a.saltDay = day
+	}
+
+	hash := sha256.New()
+	hash.Write(a.salt)
+	hash.Write([]byte(day + "|" + ip + "|" + userAgent))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// referrerHost оставляет от referrer только хост, переходы внутри сайта не учитываются.
+func (a *Analytics) referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if host == a.ownHost {
+		return ""
+	}
+
+	return host
+}
+
+//nolint:gochecknoglobals // list of user agent markers
+var botMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "curl", "wget", "python-requests", "go-http-client",
+	"headless", "lighthouse", "preview", "facebookexternalhit", "scrapy", "httpclient", "monitor",
+}
+
+// IsBot определяет очевидных ботов по user agent.
+func IsBot(userAgent string) bool {
+	if userAgent == "" {
+		return true
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toViewCounters(counters map[counterKey]int) []*domain.ViewCounter {
+	result := make([]*domain.ViewCounter, 0, len(counters))
+
+	for key, views := range counters {
+		day, _ := time.Parse(time.DateOnly, key.day)
+		result = append(result, &domain.ViewCounter{
+			Day:      day,
+			Path:     key.path,
+			PostID:   key.postID,
+			Referrer: key.referrer,
+			Views:    views,
+		})
+	}
+
+	return result
+}
+
+func toVisitors(visitors map[visitorKey]int) []*domain.Visitor {
+	result := make([]*domain.Visitor, 0, len(visitors))
+
+	for key, postID := range visitors {
+		day, _ := time.Parse(time.DateOnly, key.day)
+		result = append(result, &domain.Visitor{Day: day, Path: key.path, PostID: postID, Hash: key.hash})
+	}
+
+	return result
+}
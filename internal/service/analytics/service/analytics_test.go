@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeViewsRepo struct {
+	counters []*domain.ViewCounter
+	visitors []*domain.Visitor
+	err      error
+}
+
+func (f *fakeViewsRepo) Save(_ context.Context, counters []*domain.ViewCounter, visitors []*domain.Visitor) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	f.counters = append(f.counters, counters...)
+	f.visitors = append(f.visitors, visitors...)
+
+	return nil
+}
+
+func (f *fakeViewsRepo) PostStats(context.Context, time.Time) ([]*domain.PostStats, error) {
+	return nil, nil
+}
+
+func (f *fakeViewsRepo) ReferrerStats(context.Context, time.Time, int) ([]*domain.ReferrerStats, error) {
+	return nil, nil
+}
+
+func (f *fakeViewsRepo) DailyStats(context.Context, time.Time) ([]*domain.DayStats, error) {
+	return nil, nil
+}
+
+func TestAnalytics_Flush(t *testing.T) {
+	t.Parallel()
+
+	repo := &fakeViewsRepo{}
+	analytics := service.New(slog.Default(), repo, time.Minute, "www.arevbond.ru")
+
+	const browser = "Mozilla/5.0 (X11; Linux x86_64) Firefox/140.0"
+
+	now := time.Now()
+	views := []domain.PageView{
+		{Path: "/blog/posts/slug", PostID: 1, Referrer: "https://news.ycombinator.com/item", IP: "1.1.1.1", UserAgent: browser, At: now},
+		{Path: "/blog/posts/slug", PostID: 1, Referrer: "https://news.ycombinator.com/", IP: "1.1.1.1", UserAgent: browser, At: now},
+		{Path: "/blog/posts/slug", PostID: 1, Referrer: "https://arevbond.ru/blog/posts", IP: "2.2.2.2", UserAgent: browser, At: now},
+		{Path: "/blog/posts/slug", PostID: 1, Referrer: "", IP: "3.3.3.3", UserAgent: "Googlebot/2.1", At: now},
+	}
+
+	for _, view := range views {
+		analytics.TrackView(view)
+	}
+
+	analytics.Flush(t.Context())
+
+	require.Len(t, repo.counters, 2)
+	require.Len(t, repo.visitors, 2, "the same ip and user agent is one visitor, bots are skipped")
+
+	byReferrer := make(map[string]int)
+	for _, counter := range repo.counters {
+		byReferrer[counter.Referrer] = counter.Views
+	}
+
+	assert.Equal(t, map[string]int{"news.ycombinator.com": 2, "": 1}, byReferrer)
+
+	analytics.Flush(t.Context())
+	assert.Len(t, repo.counters, 2, "buffer should be empty after flush")
+}
+
+func TestAnalytics_FlushFailureKeepsBufferBounded(t *testing.T) {
+	t.Parallel()
+
+	repo := &fakeViewsRepo{err: errors.New("db is down")}
+	analytics := service.New(slog.Default(), repo, time.Minute, "arevbond.ru")
+
+	const views = 12_000
+
+	now := time.Now()
+	for i := range views {
+		analytics.TrackView(domain.PageView{
+			Path: "/", PostID: 0, Referrer: fmt.Sprintf("https://site%d.example/", i),
+			IP: fmt.Sprintf("10.0.%d.%d", i/256, i%256), UserAgent: "Mozilla/5.0", At: now,
+		})
+	}
+
+	analytics.Flush(t.Context())
+
+	repo.err = nil
+	analytics.Flush(t.Context())
+
+	// посетители сверх лимита теряются, просмотры складываются без источника
+	assert.Less(t, len(repo.visitors), views)
+	assert.Less(t, len(repo.counters), views)
+
+	total := 0
+	for _, counter := range repo.counters {
+		total += counter.Views
+	}
+
+	assert.Equal(t, views, total)
+}
+
+func TestIsBot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		userAgent string
+		want      bool
+	}{
+		{userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", want: true},
+		{userAgent: "curl/8.5.0", want: true},
+		{userAgent: "", want: true},
+		{userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Safari/604.1", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, service.IsBot(tt.userAgent), tt.userAgent)
+	}
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/analytics/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type Views struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewViewsRepo(log *slog.Logger, db *sqlx.DB) *Views {
+	return &Views{log: log, DB: db}
+}
+
+// Save добавляет накопленные просмотры и посетителей одной транзакцией.
+func (v *Views) Save(ctx context.Context, counters []*domain.ViewCounter, visitors []*domain.Visitor) error {
+	tx, err := v.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	viewsQuery := `
+		INSERT INTO page_views (day, path, post_id, referrer, views)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5)
+		ON CONFLICT (day, path, referrer) DO UPDATE
+		SET views = page_views.views + EXCLUDED.views,
+		    post_id = COALESCE(EXCLUDED.post_id, page_views.post_id);`
+
+	for _, c := range counters {
+		_, err = tx.ExecContext(ctx, viewsQuery, c.Day, c.Path, c.PostID, c.Referrer, c.Views)
+		if err != nil {
+			return fmt.Errorf("can't save page views: %w", err)
+		}
+	}
+
+	visitorsQuery := `
+		INSERT INTO page_visitors (day, path, post_id, visitor_hash)
+		VALUES ($1, $2, NULLIF($3, 0), $4)
+		ON CONFLICT DO NOTHING;`
+
+	for _, visitor := range visitors {
+		_, err = tx.ExecContext(ctx, visitorsQuery, visitor.Day, visitor.Path, visitor.PostID, visitor.Hash)
+		if err != nil {
+			return fmt.Errorf("can't save page visitors: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit page views: %w", err)
+	}
+
+	return nil
+}
+
+func (v *Views) PostStats(ctx context.Context, since time.Time) ([]*domain.PostStats, error) {
+	query := `
+		SELECT p.id AS post_id, p.title, p.slug, v.views, COALESCE(u.visitors, 0) AS visitors
+		FROM (SELECT post_id, SUM(views) AS views
+		      FROM page_views
+		      WHERE day >= $1 AND post_id IS NOT NULL
+		      GROUP BY post_id) v
+		INNER JOIN posts p ON p.id = v.post_id
+		LEFT JOIN (SELECT post_id, COUNT(DISTINCT (day, visitor_hash)) AS visitors
+		           FROM page_visitors
+		           WHERE day >= $1 AND post_id IS NOT NULL
+		           GROUP BY post_id) u ON u.post_id = v.post_id
+		ORDER BY v.views DESC;`
+
+	stats := []*domain.PostStats{}
+
+	err := v.DB.SelectContext(ctx, &stats, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("can't get post stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+func (v *Views) ReferrerStats(ctx context.Context, since time.Time, limit int) ([]*domain.ReferrerStats, error) {
+	query := `
+		SELECT referrer, SUM(views) AS views
+		FROM page_views
+		WHERE day >= $1 AND referrer <> ''
+		GROUP BY referrer
+		ORDER BY views DESC
+		LIMIT $2;`
+
+	stats := []*domain.ReferrerStats{}
+
+	err := v.DB.SelectContext(ctx, &stats, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get referrer stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+func (v *Views) DailyStats(ctx context.Context, since time.Time) ([]*domain.DayStats, error) {
+	query := `
+		SELECT d.day::date AS day, COALESCE(v.views, 0) AS views, COALESCE(u.visitors, 0) AS visitors
+		FROM generate_series($1::date, CURRENT_DATE, INTERVAL '1 day') AS d (day)
+		LEFT JOIN (SELECT day, SUM(views) AS views
+		           FROM page_views
+		           WHERE day >= $1
+		           GROUP BY day) v ON v.day = d.day::date
+		LEFT JOIN (SELECT day, COUNT(DISTINCT visitor_hash) AS visitors
+		           FROM page_visitors
+		           WHERE day >= $1
+		           GROUP BY day) u ON u.day = d.day::date
+		ORDER BY d.day;`
+
+	stats := []*domain.DayStats{}
+
+	err := v.DB.SelectContext(ctx, &stats, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("can't get daily stats: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scope ограничивает, что можно делать API токеном. Права роли владельца
+// токена при этом остаются в силе: автор с posts:publish всё равно
+// не сможет опубликовать пост.
+type Scope string
+
+const (
+	ScopePostsRead    Scope = "posts:read"    // чтение постов, в том числе черновиков
+	ScopePostsWrite   Scope = "posts:write"   // создание, изменение и удаление постов
+	ScopePostsPublish Scope = "posts:publish" // публикация и снятие с публикации
+)
+
+// Scopes - все доступные scope, для форм.
+//
+//nolint:gochecknoglobals // list of allowed scopes
+var Scopes = []Scope{ScopePostsRead, ScopePostsWrite, ScopePostsPublish}
+
+func (s Scope) Valid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// APITokenPrefix отличает API токены от других секретов, например в логах CI.
+const APITokenPrefix = "abt_"
+
+type APIToken struct {
+	ID         int        `db:"id"`
+	UserID     int        `db:"user_id"`
+	Username   string     `db:"username"` // заполняется только в списках
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"` // начало токена, чтобы узнать его в списке
+	Hash       string     `db:"token_hash"`
+	Scopes     string     `db:"scopes"` // scope через пробел
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+func (t *APIToken) ScopeList() []Scope {
+	return SplitScopes(t.Scopes)
+}
+
+type CreateAPITokenParams struct {
+	UserID int
+	Name   string
+	Scopes []Scope
+	TTL    time.Duration
+}
+
+func JoinScopes(scopes []Scope) string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+
+	return strings.Join(names, " ")
+}
+
+func SplitScopes(scopes string) []Scope {
+	fields := strings.Fields(scopes)
+
+	result := make([]Scope, 0, len(fields))
+	for _, field := range fields {
+		result = append(result, Scope(field))
+	}
+
+	return result
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+)
+
+const (
+	// AccessTokenTTL - время жизни JWT, после него токен обновляется по refresh токену.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL - сессия живёт, пока админ заходит хотя бы раз за этот срок.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Session - семейство токенов одного входа пользователя, id совпадает с claim jti.
+// ExpiresAt сдвигается при каждом обновлении refresh токена.
+type Session struct {
+	ID        string     `db:"id"`
+	UserID    int        `db:"user_id"`
+	Username  string     `db:"username"` // заполняется только в списке сессий
+	UserAgent string     `db:"user_agent"`
+	IP        string     `db:"ip"`
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+	AMR       string     `db:"amr"`    // методы входа через пробел, см. JoinAMR
+	MFAAt     *time.Time `db:"mfa_at"` // когда последний раз вводился второй фактор
+}
+
+// SessionMeta - информация о клиенте, для которого создаётся сессия.
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// RefreshToken хранится только в виде хэша. После обмена на новую пару
+// токенов RotatedAt заполняется, повторное использование означает утечку.
+type RefreshToken struct {
+	Hash      string     `db:"token_hash"`
+	SessionID string     `db:"session_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+}
+
+// Tokens - пара токенов для cookie. Пустой Refresh означает, что
+// клиенту нужно оставить текущий refresh токен.
+type Tokens struct {
+	Access  string
+	Refresh string
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+// GlobalThrottleKey - ключ общего для всех ip счётчика неудачных входов.
+const GlobalThrottleKey = "global"
+
+// LoginThrottle - неудачные попытки входа с одного ip или глобально.
+// Хранится в базе, чтобы блокировка переживала перезапуск.
+type LoginThrottle struct {
+	Key          string    `db:"key"`
+	Failures     int       `db:"failures"`
+	LastFailedAt time.Time `db:"last_failed_at"`
+	LockedUntil  time.Time `db:"locked_until"`
+}
+
+// ThrottlePolicy описывает экспоненциальную задержку: первые FreeAttempts
+// ошибок проходят без неё, дальше задержка удваивается до MaxDelay.
+// Счётчик сбрасывается, если ошибок не было дольше ResetAfter.
+type ThrottlePolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	ResetAfter   time.Duration
+}
+
+// Delay возвращает блокировку после failures неудачных попыток подряд.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	exceeded := failures - p.FreeAttempts
+	if exceeded <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for range exceeded - 1 {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+
+	return min(delay, p.MaxDelay)
+}
+
+// LoginLockedError возвращается, пока вход заблокирован после неудачных попыток.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "login locked, retry after " + e.RetryAfter.String()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return errs.ErrRateLimited
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Методы аутентификации для claim amr (RFC 8176).
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+)
+
+const (
+	// MFAChallengeTTL - сколько ждать код второго фактора после ввода токена.
+	MFAChallengeTTL = 5 * time.Minute
+	// FreshMFAWindow - в течение этого времени после ввода кода второй фактор считается свежим.
+	FreshMFAWindow = 10 * time.Minute
+)
+
+// TOTP - секрет приложения-аутентификатора пользователя. Пока ConfirmedAt пуст,
+// секрет только выпущен и ещё не подтверждён кодом.
+type TOTP struct {
+	UserID      int        `db:"user_id"`
+	Secret      string     `db:"secret"`
+	LastStep    int64      `db:"last_step"` // последний принятый шаг, защищает от повтора кода
+	CreatedAt   time.Time  `db:"created_at"`
+	ConfirmedAt *time.Time `db:"confirmed_at"`
+}
+
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+// TOTPEnrollment - данные для добавления секрета в приложение.
+type TOTPEnrollment struct {
+	Secret string
+	URL    string // otpauth:// ссылка, она же закодирована в QR
+	QRCode []byte // PNG
+}
+
+// Claims - данные access токена, нужные для проверки второго фактора.
+type Claims struct {
+	SessionID string
+	AMR       []string
+	AuthTime  time.Time // время последнего ввода второго фактора
+}
+
+func (c Claims) HasMFA() bool {
+	return slices.Contains(c.AMR, AMROTP)
+}
+
+// FreshMFA сообщает, вводился ли второй фактор недавно.
+func (c Claims) FreshMFA(now time.Time) bool {
+	return c.HasMFA() && now.Sub(c.AuthTime) <= FreshMFAWindow
+}
+
+// JoinAMR и SplitAMR переводят список методов в формат колонки sessions.amr.
+func JoinAMR(amr []string) string {
+	return strings.Join(amr, " ")
+}
+
+func SplitAMR(amr string) []string {
+	return strings.Fields(amr)
+}
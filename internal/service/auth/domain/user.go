@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+var (
+	ErrWeakPassword = fmt.Errorf("password is too short: %w", errs.ErrInvalid)
+	ErrLastAdmin    = fmt.Errorf("can't remove the last admin: %w", errs.ErrInvalid)
+)
+
+type Role string
+
+const (
+	RoleAuthor Role = "author" // пишет и правит свои посты
+	RoleEditor Role = "editor" // правит и публикует любые посты, модерирует комментарии
+	RoleAdmin  Role = "admin"  // всё вышеперечисленное и управление пользователями
+)
+
+//nolint:gochecknoglobals // role order for AtLeast
+var roleRanks = map[Role]int{
+	RoleAuthor: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Roles - роли в порядке возрастания прав, для форм.
+//
+//nolint:gochecknoglobals // list of allowed roles
+var Roles = []Role{RoleAuthor, RoleEditor, RoleAdmin}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+
+	return ok
+}
+
+// AtLeast сообщает, что у роли не меньше прав, чем у other.
+func (r Role) AtLeast(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
+
+type User struct {
+	ID           int       `db:"id"`
+	Username     string    `db:"username"`
+	DisplayName  string    `db:"display_name"` // подпись под постами
+	PasswordHash string    `db:"password_hash"`
+	Role         Role      `db:"role"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+type CreateUserParams struct {
+	Username    string
+	DisplayName string
+	Password    string
+	Role        Role
+}
+
+// Principal - пользователь, от имени которого выполняется запрос.
+type Principal struct {
+	UserID      int
+	Username    string
+	DisplayName string
+	Role        Role
+	// Scopes заполнены только при входе по API токену, у сессий браузера - nil
+	Scopes []Scope
+	Claims
+}
+
+// Методы Principal безопасно вызывать на nil - так в шаблонах
+// проверяются права анонимного посетителя.
+
+func (p *Principal) CanEditPost(authorID int) bool {
+	return p != nil && (p.Role.AtLeast(RoleEditor) || p.UserID == authorID)
+}
+
+func (p *Principal) CanPublish() bool {
+	return p != nil && p.Role.AtLeast(RoleEditor)
+}
+
+func (p *Principal) CanModerate() bool {
+	return p != nil && p.Role.AtLeast(RoleEditor)
+}
+
+func (p *Principal) CanManageUsers() bool {
+	return p != nil && p.Role.AtLeast(RoleAdmin)
+}
+
+// Allows сообщает, разрешён ли scope. Сессиям браузера разрешено всё,
+// что позволяет роль.
+func (p *Principal) Allows(scope Scope) bool {
+	return p != nil && (p.Scopes == nil || slices.Contains(p.Scopes, scope))
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+var ErrInvalidAPIToken = fmt.Errorf("invalid api token: %w", errs.ErrInvalid)
+
+const (
+	apiTokenSize         = 32
+	apiTokenPrefixLength = len(domain.APITokenPrefix) + 6
+	maxAPITokenNameLen   = 100
+	maxAPITokenTTL       = 366 * 24 * time.Hour
+	// время последнего использования пишется не чаще раза в минуту,
+	// чтобы скрипт с сотней запросов не делал сотню UPDATE
+	apiTokenTouchInterval = time.Minute
+)
+
+type APITokensRepository interface {
+	Create(ctx context.Context, token *domain.APIToken) error
+	FindByHash(ctx context.Context, hash string) (*domain.APIToken, error)
+	All(ctx context.Context) ([]*domain.APIToken, error)
+	Revoke(ctx context.Context, id int, at time.Time) error
+	Touch(ctx context.Context, id int, at time.Time) error
+}
+
+// CreateAPIToken выпускает токен для пользователя. Сам токен возвращается
+// только здесь, в базе хранится его хэш.
+func (a *Auth) CreateAPIToken(
+	ctx context.Context,
+	params domain.CreateAPITokenParams,
+) (*domain.APIToken, string, error) {
+	params.Name = strings.TrimSpace(params.Name)
+
+	if params.Name == "" || utf8.RuneCountInString(params.Name) > maxAPITokenNameLen ||
+		len(params.Scopes) == 0 || params.TTL <= 0 || params.TTL > maxAPITokenTTL {
+		return nil, "", ErrInvalidAPIToken
+	}
+
+	for _, scope := range params.Scopes {
+		if !scope.Valid() {
+			return nil, "", ErrInvalidAPIToken
+		}
+	}
+
+	if _, err := a.UsersRepo.Find(ctx, params.UserID); err != nil {
+		return nil, "", fmt.Errorf("auth: %w", err)
+	}
+
+	secret, err := randomToken(apiTokenSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plain := domain.APITokenPrefix + secret
+	now := time.Now()
+
+	token := &domain.APIToken{
+		ID:         0,
+		UserID:     params.UserID,
+		Username:   "",
+		Name:       params.Name,
+		Prefix:     plain[:apiTokenPrefixLength],
+		Hash:       hashToken(plain),
+		Scopes:     domain.JoinScopes(params.Scopes),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(params.TTL),
+		LastUsedAt: nil,
+		RevokedAt:  nil,
+	}
+
+	if err = a.APITokensRepo.Create(ctx, token); err != nil {
+		return nil, "", fmt.Errorf("auth: %w", err)
+	}
+
+	a.log.Info("api token created",
+		slog.Int("token_id", token.ID),
+		slog.Int("user_id", token.UserID),
+		slog.String("scopes", token.Scopes))
+
+	return token, plain, nil
+}
+
+func (a *Auth) APITokens(ctx context.Context) ([]*domain.APIToken, error) {
+	tokens, err := a.APITokensRepo.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (a *Auth) RevokeAPIToken(ctx context.Context, id int) error {
+	if err := a.APITokensRepo.Revoke(ctx, id, time.Now()); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAPIToken возвращает владельца токена с его scope или nil, если токен
+// неизвестен, отозван или истёк.
+func (a *Auth) VerifyAPIToken(ctx context.Context, tokenStr string) (*domain.Principal, error) {
+	if !strings.HasPrefix(tokenStr, domain.APITokenPrefix) {
+		return nil, nil
+	}
+
+	token, err := a.APITokensRepo.FindByHash(ctx, hashToken(tokenStr))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("can't find api token: %w", err)
+	}
+
+	now := time.Now()
+
+	if !token.Active(now) {
+		return nil, nil
+	}
+
+	user, err := a.UsersRepo.Find(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("can't find user: %w", err)
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err = a.APITokensRepo.Touch(ctx, token.ID, now); err != nil {
+			a.log.Warn("can't update api token last use", slog.Any("error", err))
+		}
+	}
+
+	return &domain.Principal{
+		UserID:      user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Role:        user.Role,
+		Scopes:      token.ScopeList(),
+		Claims:      domain.Claims{SessionID: "", AMR: nil, AuthTime: token.CreatedAt},
+	}, nil
+}
@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPITokensRepo struct {
+	tokens map[int]*domain.APIToken
+	nextID int
+}
+
+func newFakeAPITokensRepo() *fakeAPITokensRepo {
+	return &fakeAPITokensRepo{tokens: make(map[int]*domain.APIToken), nextID: 1}
+}
+
+func (f *fakeAPITokensRepo) Create(_ context.Context, token *domain.APIToken) error {
+	token.ID = f.nextID
+	f.nextID++
+	f.tokens[token.ID] = token
+
+	return nil
+}
+
+func (f *fakeAPITokensRepo) FindByHash(_ context.Context, hash string) (*domain.APIToken, error) {
+	for _, token := range f.tokens {
+		if token.Hash == hash {
+			return token, nil
+		}
+	}
+
+	return nil, errs.ErrNotFound
+}
+
+func (f *fakeAPITokensRepo) All(context.Context) ([]*domain.APIToken, error) {
+	tokens := make([]*domain.APIToken, 0, len(f.tokens))
+	for _, token := range f.tokens {
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func (f *fakeAPITokensRepo) Revoke(_ context.Context, id int, at time.Time) error {
+	token, ok := f.tokens[id]
+	if !ok || token.RevokedAt != nil {
+		return errs.ErrNotFound
+	}
+
+	token.RevokedAt = &at
+
+	return nil
+}
+
+func (f *fakeAPITokensRepo) Touch(_ context.Context, id int, at time.Time) error {
+	f.tokens[id].LastUsedAt = &at
+
+	return nil
+}
+
+func TestAPITokens(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+
+	_, _, err := auth.CreateAPIToken(ctx, domain.CreateAPITokenParams{
+		UserID: 1, Name: "ci", Scopes: []domain.Scope{"posts:everything"}, TTL: time.Hour,
+	})
+	require.ErrorIs(t, err, errs.ErrInvalid)
+
+	_, _, err = auth.CreateAPIToken(ctx, domain.CreateAPITokenParams{
+		UserID: 1, Name: "ci", Scopes: nil, TTL: time.Hour,
+	})
+	require.ErrorIs(t, err, errs.ErrInvalid)
+
+	token, plain, err := auth.CreateAPIToken(ctx, domain.CreateAPITokenParams{
+		UserID: 1, Name: " ci ", Scopes: []domain.Scope{domain.ScopePostsWrite}, TTL: time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ci", token.Name)
+	assert.True(t, len(plain) > len(token.Prefix))
+	assert.Contains(t, plain, token.Prefix)
+	assert.NotContains(t, token.Hash, plain)
+
+	principal, err := auth.VerifyAPIToken(ctx, plain)
+	require.NoError(t, err)
+	require.NotNil(t, principal)
+	assert.Equal(t, "admin", principal.Username)
+	assert.True(t, principal.Allows(domain.ScopePostsWrite))
+	assert.False(t, principal.Allows(domain.ScopePostsPublish))
+	assert.NotNil(t, token.LastUsedAt)
+
+	principal, err = auth.VerifyAPIToken(ctx, plain+"x")
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+
+	require.NoError(t, auth.RevokeAPIToken(ctx, token.ID))
+	require.ErrorIs(t, auth.RevokeAPIToken(ctx, token.ID), errs.ErrNotFound)
+
+	principal, err = auth.VerifyAPIToken(ctx, plain)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+}
+
+func TestExpiredAPITokenIsRejected(t *testing.T) {
+	repo := newFakeAPITokensRepo()
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	auth.APITokensRepo = repo
+	ctx := context.Background()
+
+	token, plain, err := auth.CreateAPIToken(ctx, domain.CreateAPITokenParams{
+		UserID: 1, Name: "ci", Scopes: []domain.Scope{domain.ScopePostsRead}, TTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	repo.tokens[token.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	principal, err := auth.VerifyAPIToken(ctx, plain)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+}
@@ -1,57 +1,489 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+var (
+	ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+	ErrMissingSessionID        = errors.New("missing jti claim")
+	ErrInvalidRefreshToken     = fmt.Errorf("invalid refresh token: %w", errs.ErrInvalid)
+	ErrRefreshTokenReused      = fmt.Errorf("refresh token reused: %w", errs.ErrInvalid)
+)
+
+const (
+	sessionIDSize    = 16
+	refreshTokenSize = 32
+	// параллельные запросы с истёкшим access токеном приходят с одним и тем же
+	// refresh токеном. В течение этого окна повтор из того же браузера не
+	// считается кражей. Окно короткое: всё это время токен выдаёт access токены.
+	refreshGracePeriod = 5 * time.Second
+	// истёкшие сессии хранятся ещё какое-то время, чтобы было видно историю входов
+	expiredSessionsRetention = 30 * 24 * time.Hour
+)
+
+type SessionsRepository interface {
+	Create(ctx context.Context, session *domain.Session) error
+	Find(ctx context.Context, id string) (*domain.Session, error)
+	Active(ctx context.Context, now time.Time, userID int) ([]*domain.Session, error)
+	Revoke(ctx context.Context, id string, userID int, at time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+	Extend(ctx context.Context, id string, expiresAt time.Time) error
+	MarkMFA(ctx context.Context, id string, amr string, at time.Time) error
+	CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error
+	FindRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, hash string, at time.Time) (bool, error)
+}
 
 type Auth struct {
-	log          *slog.Logger
-	adminToken   string
-	secretKeyJWT string
+	log           *slog.Logger
+	adminToken    string
+	secretKeyJWT  string
+	issuer        string // название сайта в приложении-аутентификаторе
+	requireTOTP   bool
+	SessionsRepo  SessionsRepository
+	ThrottlesRepo ThrottlesRepository
+	// ChallengesRepo - использованные challenge второго фактора
+	ChallengesRepo MFAChallengesRepository
+	TOTPRepo       TOTPRepository
+	UsersRepo      UsersRepository
+	APITokensRepo  APITokensRepository
+	// loginLocks - попытки входа по одному ip или пользователю идут по одной
+	loginLocks *keyLocks
+	// dummyHash - хэш случайного пароля для сверки при входе под несуществующим именем
+	dummyHash func() (string, error)
+}
+
+func New(
+	log *slog.Logger,
+	adminToken string,
+	secretKey string,
+	issuer string,
+	requireTOTP bool,
+	sessions SessionsRepository,
+	throttles ThrottlesRepository,
+	challenges MFAChallengesRepository,
+	totp TOTPRepository,
+	users UsersRepository,
+	apiTokens APITokensRepository,
+) *Auth {
+	return &Auth{
+		log:            log,
+		adminToken:     adminToken,
+		secretKeyJWT:   secretKey,
+		issuer:         issuer,
+		requireTOTP:    requireTOTP,
+		SessionsRepo:   sessions,
+		ThrottlesRepo:  throttles,
+		ChallengesRepo: challenges,
+		TOTPRepo:       totp,
+		UsersRepo:      users,
+		APITokensRepo:  apiTokens,
+		loginLocks:     newKeyLocks(),
+		dummyHash: sync.OnceValues(func() (string, error) {
+			password, err := randomToken(refreshTokenSize)
+			if err != nil {
+				return "", err
+			}
+
+			return hashPassword(password)
+		}),
+	}
+}
+
+// NewJWT создаёт сессию пользователя и выдаёт для неё access и refresh токены.
+// amr - методы, которыми пользователь подтвердил вход, попадают в claim amr.
+func (a *Auth) NewJWT(
+	ctx context.Context,
+	userID int,
+	meta domain.SessionMeta,
+	amr []string,
+) (domain.Tokens, error) {
+	sessionID, err := randomToken(sessionIDSize)
+	if err != nil {
+		return domain.Tokens{}, err
+	}
+
+	now := time.Now()
+
+	session := &domain.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		Username:  "",
+		UserAgent: meta.UserAgent,
+		IP:        meta.IP,
+		CreatedAt: now,
+		ExpiresAt: now.Add(domain.RefreshTokenTTL),
+		RevokedAt: nil,
+		AMR:       domain.JoinAMR(amr),
+		MFAAt:     nil,
+	}
+
+	if slices.Contains(amr, domain.AMROTP) {
+		session.MFAAt = &now
+	}
+
+	if err = a.SessionsRepo.Create(ctx, session); err != nil {
+		return domain.Tokens{}, fmt.Errorf("can't create session: %w", err)
+	}
+
+	if err = a.SessionsRepo.DeleteExpired(ctx, now.Add(-expiredSessionsRetention)); err != nil {
+		a.log.Warn("can't delete expired sessions", slog.Any("error", err))
+	}
+
+	return a.issueTokens(ctx, session, now)
 }
 
-func New(log *slog.Logger, adminToken string, secretKey string) *Auth {
-	return &Auth{log: log, adminToken: adminToken, secretKeyJWT: secretKey}
+// Refresh обменивает refresh токен на новую пару токенов той же сессии.
+// Повторное использование уже обменянного токена отзывает всю сессию.
+// userAgent - браузер запроса: повтор из другого браузера - всегда кража.
+func (a *Auth) Refresh(ctx context.Context, refreshToken, userAgent string) (domain.Tokens, *domain.Principal, error) {
+	tokens, session, err := a.refresh(ctx, refreshToken, userAgent)
+	if err != nil {
+		return domain.Tokens{}, nil, err
+	}
+
+	// новый access токен выпущен по состоянию сессии, claims совпадают с ним
+	principal, err := a.principal(ctx, session, sessionClaims(session))
+	if err != nil {
+		return domain.Tokens{}, nil, err
+	}
+
+	if principal == nil {
+		return domain.Tokens{}, nil, ErrInvalidRefreshToken
+	}
+
+	return tokens, principal, nil
+}
+
+func (a *Auth) refresh(ctx context.Context, refreshToken, userAgent string) (domain.Tokens, *domain.Session, error) {
+	hash := hashToken(refreshToken)
+
+	token, err := a.SessionsRepo.FindRefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return domain.Tokens{}, nil, ErrInvalidRefreshToken
+		}
+
+		return domain.Tokens{}, nil, fmt.Errorf("can't find refresh token: %w", err)
+	}
+
+	session, err := a.SessionsRepo.Find(ctx, token.SessionID)
+	if err != nil {
+		return domain.Tokens{}, nil, fmt.Errorf("can't find session: %w", err)
+	}
+
+	now := time.Now()
+
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return domain.Tokens{}, nil, ErrInvalidRefreshToken
+	}
+
+	if token.RotatedAt != nil {
+		tokens, err := a.handleRotatedToken(ctx, session, token, userAgent, now)
+
+		return tokens, session, err
+	}
+
+	rotated, err := a.SessionsRepo.RotateRefreshToken(ctx, hash, now)
+	if err != nil {
+		return domain.Tokens{}, nil, fmt.Errorf("can't rotate refresh token: %w", err)
+	}
+
+	// токен успел обменять параллельный запрос
+	if !rotated {
+		tokens, err := a.handleRotatedToken(ctx, session, token, userAgent, now)
+
+		return tokens, session, err
+	}
+
+	if err = a.SessionsRepo.Extend(ctx, session.ID, now.Add(domain.RefreshTokenTTL)); err != nil {
+		return domain.Tokens{}, nil, fmt.Errorf("can't extend session: %w", err)
+	}
+
+	tokens, err := a.issueTokens(ctx, session, now)
+
+	return tokens, session, err
 }
 
-func (a *Auth) IsAdminToken(token string) bool {
-	return a.adminToken == token
+func (a *Auth) handleRotatedToken(
+	ctx context.Context,
+	session *domain.Session,
+	token *domain.RefreshToken,
+	userAgent string,
+	now time.Time,
+) (domain.Tokens, error) {
+	// токен обменял параллельный запрос только что, RotatedAt ещё не прочитан
+	rotatedAt := now
+	if token.RotatedAt != nil {
+		rotatedAt = *token.RotatedAt
+	}
+
+	if now.Sub(rotatedAt) <= refreshGracePeriod && userAgent == session.UserAgent {
+		return a.accessOnly(session, now)
+	}
+
+	a.log.Warn("refresh token reuse detected, revoking session",
+		slog.String("session_id", token.SessionID),
+		slog.Time("rotated_at", rotatedAt),
+		slog.Bool("same_user_agent", userAgent == session.UserAgent))
+
+	if err := a.SessionsRepo.Revoke(ctx, token.SessionID, session.UserID, now); err != nil {
+		return domain.Tokens{}, fmt.Errorf("can't revoke session: %w", err)
+	}
+
+	return domain.Tokens{}, ErrRefreshTokenReused
 }
 
-func (a *Auth) NewJWT() (string, error) {
+func (a *Auth) issueTokens(ctx context.Context, session *domain.Session, now time.Time) (domain.Tokens, error) {
+	tokens, err := a.accessOnly(session, now)
+	if err != nil {
+		return domain.Tokens{}, err
+	}
+
+	refreshToken, err := randomToken(refreshTokenSize)
+	if err != nil {
+		return domain.Tokens{}, err
+	}
+
+	err = a.SessionsRepo.CreateRefreshToken(ctx, &domain.RefreshToken{
+		Hash:      hashToken(refreshToken),
+		SessionID: session.ID,
+		CreatedAt: now,
+		RotatedAt: nil,
+	})
+	if err != nil {
+		return domain.Tokens{}, fmt.Errorf("can't save refresh token: %w", err)
+	}
+
+	tokens.Refresh = refreshToken
+
+	return tokens, nil
+}
+
+// accessOnly подписывает access токен. auth_time - время последнего ввода
+// второго фактора, по нему проверяется свежесть для опасных действий.
+func (a *Auth) accessOnly(session *domain.Session, now time.Time) (domain.Tokens, error) {
+	state := sessionClaims(session)
+
 	claims := jwt.MapClaims{
-		"exp": time.Now().Add(1 * time.Hour).Unix(),
-		"iat": time.Now().Unix(),
+		"jti":       session.ID,
+		"sub":       strconv.Itoa(session.UserID),
+		"exp":       now.Add(domain.AccessTokenTTL).Unix(),
+		"iat":       now.Unix(),
+		"amr":       state.AMR,
+		"auth_time": state.AuthTime.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	signedToken, err := token.SignedString([]byte(a.secretKeyJWT))
 	if err != nil {
-		return "", fmt.Errorf("can't sign string: %w", err)
+		return domain.Tokens{}, fmt.Errorf("can't sign string: %w", err)
+	}
+
+	return domain.Tokens{Access: signedToken, Refresh: ""}, nil
+}
+
+// VerifyJWT проверяет подпись токена и то, что его сессия не отозвана.
+// Возвращает пользователя сессии или nil, если токен недействителен.
+func (a *Auth) VerifyJWT(ctx context.Context, tokenStr string) (*domain.Principal, error) {
+	// истёкший или поддельный токен - не ошибка сервера, а повод обновить токены
+	claims, err := a.parseClaims(tokenStr)
+	if err != nil {
+		a.log.Debug("invalid access token", slog.Any("error", err))
+
+		return nil, nil
+	}
+
+	session, err := a.SessionsRepo.Find(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("can't find session: %w", err)
+	}
+
+	if session.RevokedAt != nil || !time.Now().Before(session.ExpiresAt) {
+		return nil, nil
+	}
+
+	return a.principal(ctx, session, claims)
+}
+
+// principal собирает пользователя сессии. Возвращает nil, если пользователь
+// удалён или токен не проходит политику второго фактора.
+func (a *Auth) principal(
+	ctx context.Context,
+	session *domain.Session,
+	claims domain.Claims,
+) (*domain.Principal, error) {
+	user, err := a.UsersRepo.Find(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("can't find user: %w", err)
+	}
+
+	ok, err := a.satisfiesTOTPPolicy(ctx, user.ID, claims)
+	if err != nil || !ok {
+		return nil, err
 	}
 
-	return signedToken, nil
+	return &domain.Principal{
+		UserID:      user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Role:        user.Role,
+		Claims:      claims,
+	}, nil
+}
+
+// sessionClaims - claims по текущему состоянию сессии в базе.
+func sessionClaims(session *domain.Session) domain.Claims {
+	authTime := session.CreatedAt
+	if session.MFAAt != nil {
+		authTime = *session.MFAAt
+	}
+
+	return domain.Claims{
+		SessionID: session.ID,
+		AMR:       domain.SplitAMR(session.AMR),
+		AuthTime:  authTime,
+	}
 }
 
-func (a *Auth) VerifyJWT(tokenStr string) (bool, error) {
+// satisfiesTOTPPolicy отклоняет сессии без второго фактора, если он
+// обязателен и уже настроен. До настройки такие сессии нужны, чтобы
+// пользователь мог добавить секрет.
+func (a *Auth) satisfiesTOTPPolicy(ctx context.Context, userID int, claims domain.Claims) (bool, error) {
+	if !a.requireTOTP || claims.HasMFA() {
+		return true, nil
+	}
+
+	enabled, err := a.TOTPEnabled(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return !enabled, nil
+}
+
+// TokenSessionID возвращает id сессии из токена без обращения к базе.
+// Подпись проверяется, срок действия - нет: так можно завершить сессию,
+// access токен которой уже истёк.
+func (a *Auth) TokenSessionID(tokenStr string) (string, error) {
+	claims, err := a.TokenClaims(tokenStr)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.SessionID, nil
+}
+
+// TokenClaims возвращает claims токена, срок действия так же не проверяется.
+// Вызывается после RequireAuth, который уже проверил саму сессию.
+func (a *Auth) TokenClaims(tokenStr string) (domain.Claims, error) {
+	return a.parseClaims(tokenStr, jwt.WithoutClaimsValidation())
+}
+
+func (a *Auth) parseClaims(tokenStr string, opts ...jwt.ParserOption) (domain.Claims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodHS256 {
 			return nil, fmt.Errorf("%w: %v", ErrUnexpectedSigningMethod, token.Header["alg"])
 		}
 
 		return []byte(a.secretKeyJWT), nil
-	})
+	}, opts...)
 	if err != nil {
-		return false, fmt.Errorf("can't parse jwt: %w", err)
+		return domain.Claims{}, fmt.Errorf("can't parse jwt: %w", err)
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return domain.Claims{}, ErrMissingSessionID
 	}
 
-	return token.Valid, nil
+	claims := domain.Claims{AMR: nil, AuthTime: time.Time{}}
+
+	claims.SessionID, ok = mapClaims["jti"].(string)
+	if !ok || claims.SessionID == "" {
+		return domain.Claims{}, ErrMissingSessionID
+	}
+
+	if amr, isList := mapClaims["amr"].([]any); isList {
+		for _, method := range amr {
+			if name, isString := method.(string); isString {
+				claims.AMR = append(claims.AMR, name)
+			}
+		}
+	}
+
+	if authTime, isNumber := mapClaims["auth_time"].(float64); isNumber {
+		claims.AuthTime = time.Unix(int64(authTime), 0)
+	}
+
+	return claims, nil
+}
+
+// Sessions возвращает активные сессии пользователя, userID = 0 - всех пользователей.
+func (a *Auth) Sessions(ctx context.Context, userID int) ([]*domain.Session, error) {
+	sessions, err := a.SessionsRepo.Active(ctx, time.Now(), userID)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает сессию пользователя, userID = 0 - любую сессию.
+func (a *Auth) RevokeSession(ctx context.Context, id string, userID int) error {
+	if err := a.SessionsRepo.Revoke(ctx, id, userID, time.Now()); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	return nil
+}
+
+func randomToken(size int) (string, error) {
+	buf, err := randomBytes(size)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func randomBytes(size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("can't generate random bytes: %w", err)
+	}
+
+	return buf, nil
+}
+
+// hashToken - refresh токены случайны и длинны, медленный хэш для них не нужен.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
 }
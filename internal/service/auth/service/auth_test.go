@@ -0,0 +1,249 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/auth/service"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessionsRepo struct {
+	sessions      map[string]*domain.Session
+	refreshTokens map[string]*domain.RefreshToken
+}
+
+func newFakeSessionsRepo() *fakeSessionsRepo {
+	return &fakeSessionsRepo{
+		sessions:      make(map[string]*domain.Session),
+		refreshTokens: make(map[string]*domain.RefreshToken),
+	}
+}
+
+func (f *fakeSessionsRepo) Create(_ context.Context, session *domain.Session) error {
+	f.sessions[session.ID] = session
+
+	return nil
+}
+
+func (f *fakeSessionsRepo) Find(_ context.Context, id string) (*domain.Session, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return session, nil
+}
+
+func (f *fakeSessionsRepo) Active(_ context.Context, now time.Time, userID int) ([]*domain.Session, error) {
+	var result []*domain.Session
+
+	for _, session := range f.sessions {
+		if session.RevokedAt == nil && session.ExpiresAt.After(now) && (userID == 0 || session.UserID == userID) {
+			result = append(result, session)
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fakeSessionsRepo) Revoke(_ context.Context, id string, userID int, at time.Time) error {
+	if session, ok := f.sessions[id]; ok && (userID == 0 || session.UserID == userID) {
+		session.RevokedAt = &at
+	}
+
+	return nil
+}
+
+func (f *fakeSessionsRepo) DeleteExpired(context.Context, time.Time) error {
+	return nil
+}
+
+func (f *fakeSessionsRepo) MarkMFA(_ context.Context, id string, amr string, at time.Time) error {
+	f.sessions[id].AMR = amr
+	f.sessions[id].MFAAt = &at
+
+	return nil
+}
+
+func (f *fakeSessionsRepo) Extend(_ context.Context, id string, expiresAt time.Time) error {
+	f.sessions[id].ExpiresAt = expiresAt
+
+	return nil
+}
+
+func (f *fakeSessionsRepo) CreateRefreshToken(_ context.Context, token *domain.RefreshToken) error {
+	f.refreshTokens[token.Hash] = token
+
+	return nil
+}
+
+func (f *fakeSessionsRepo) FindRefreshToken(_ context.Context, hash string) (*domain.RefreshToken, error) {
+	token, ok := f.refreshTokens[hash]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return token, nil
+}
+
+func (f *fakeSessionsRepo) RotateRefreshToken(_ context.Context, hash string, at time.Time) (bool, error) {
+	token := f.refreshTokens[hash]
+	if token.RotatedAt != nil {
+		return false, nil
+	}
+
+	token.RotatedAt = &at
+
+	return true, nil
+}
+
+const testSecret = "secret"
+
+// newTestAuth создаёт сервис с пользователем admin (id 1) и паролем "admin".
+func newTestAuth(
+	requireTOTP bool,
+	sessions service.SessionsRepository,
+	throttles service.ThrottlesRepository,
+	totp service.TOTPRepository,
+) *service.Auth {
+	return service.New(slog.Default(), "admin", testSecret, "blog", requireTOTP,
+		sessions, throttles, newFakeChallengesRepo(), totp, newFakeUsersRepo(testAdmin()), newFakeAPITokensRepo())
+}
+
+func testAdmin() *domain.User {
+	return &domain.User{ID: 1, Username: "admin", DisplayName: "Admin", Role: domain.RoleAdmin}
+}
+
+func TestRevokedSessionIsRejected(t *testing.T) {
+	repo := newFakeSessionsRepo()
+	auth := newTestAuth(false, repo, newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+
+	tokens, err := auth.NewJWT(ctx, 1, domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	principal, err := auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+	require.NotNil(t, principal)
+	assert.Equal(t, "admin", principal.Username)
+
+	sessions, err := auth.Sessions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "127.0.0.1", sessions[0].IP)
+
+	require.NoError(t, auth.RevokeSession(ctx, sessions[0].ID, 1))
+
+	principal, err = auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+
+	sessions, err = auth.Sessions(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	_, _, err = auth.Refresh(ctx, tokens.Refresh, "test")
+	require.ErrorIs(t, err, errs.ErrInvalid)
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+
+	tokens, err := auth.NewJWT(ctx, 1, domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	refreshed, _, err := auth.Refresh(ctx, tokens.Refresh, "test")
+	require.NoError(t, err)
+	assert.NotEmpty(t, refreshed.Refresh)
+	assert.NotEqual(t, tokens.Refresh, refreshed.Refresh)
+
+	principal, err := auth.VerifyJWT(ctx, refreshed.Access)
+	require.NoError(t, err)
+	assert.NotNil(t, principal)
+
+	// параллельный запрос со старым токеном получает только access токен
+	concurrent, _, err := auth.Refresh(ctx, tokens.Refresh, "test")
+	require.NoError(t, err)
+	assert.NotEmpty(t, concurrent.Access)
+	assert.Empty(t, concurrent.Refresh)
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	repo := newFakeSessionsRepo()
+	auth := newTestAuth(false, repo, newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+
+	tokens, err := auth.NewJWT(ctx, 1, domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	refreshed, _, err := auth.Refresh(ctx, tokens.Refresh, "test")
+	require.NoError(t, err)
+
+	// старый токен обменян давно - значит, его кто-то украл
+	for _, token := range repo.refreshTokens {
+		if token.RotatedAt != nil {
+			rotatedAt := time.Now().Add(-time.Hour)
+			token.RotatedAt = &rotatedAt
+		}
+	}
+
+	_, _, err = auth.Refresh(ctx, tokens.Refresh, "test")
+	require.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+	_, _, err = auth.Refresh(ctx, refreshed.Refresh, "test")
+	require.ErrorIs(t, err, errs.ErrInvalid)
+
+	principal, err := auth.VerifyJWT(ctx, refreshed.Access)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+}
+
+func TestRefreshGraceRequiresSameBrowser(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+
+	tokens, err := auth.NewJWT(ctx, 1, domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	refreshed, _, err := auth.Refresh(ctx, tokens.Refresh, "test")
+	require.NoError(t, err)
+
+	// украденный токен, предъявленный сразу после обмена, но из другого браузера
+	_, _, err = auth.Refresh(ctx, tokens.Refresh, "curl/8.0")
+	require.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+	_, _, err = auth.Refresh(ctx, refreshed.Refresh, "test")
+	require.ErrorIs(t, err, errs.ErrInvalid)
+}
+
+func TestTokenWithoutSessionIsRejected(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+
+	// токены, выданные до появления сессий, не содержат jti
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+
+	principal, err := auth.VerifyJWT(context.Background(), legacy)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+
+	unknown, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": "unknown",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+
+	principal, err = auth.VerifyJWT(context.Background(), unknown)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+}
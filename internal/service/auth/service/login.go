@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+var ErrInvalidCredentials = fmt.Errorf("invalid username or password: %w", errs.ErrInvalid)
+
+//nolint:gochecknoglobals // login throttling policies
+var (
+	ipThrottlePolicy = domain.ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Hour,
+		ResetAfter:   24 * time.Hour,
+	}
+	// глобальный лимит ловит перебор с множества ip. Он только замедляет
+	// каждую попытку и никогда не блокирует вход, чтобы атакующий не мог
+	// закрыть его самому админу.
+	globalThrottlePolicy = domain.ThrottlePolicy{
+		FreeAttempts: 30,
+		BaseDelay:    250 * time.Millisecond,
+		MaxDelay:     3 * time.Second,
+		ResetAfter:   time.Hour,
+	}
+)
+
+type ThrottlesRepository interface {
+	Find(ctx context.Context, key string) (*domain.LoginThrottle, error)
+	RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	DeleteStale(ctx context.Context, before time.Time) error
+}
+
+// Login проверяет имя и пароль с учётом блокировок. Возвращает
+// *domain.LoginLockedError, пока вход с ip заблокирован,
+// и ErrInvalidCredentials при неверном имени или пароле.
+func (a *Auth) Login(ctx context.Context, username, password string, meta domain.SessionMeta) (*domain.User, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+
+	var user *domain.User
+
+	err := a.attempt(ctx, meta, username, factorPassword, ErrInvalidCredentials, func(ctx context.Context) (bool, error) {
+		var err error
+
+		user, err = a.UsersRepo.FindByUsername(ctx, username)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return false, fmt.Errorf("auth: %w", err)
+		}
+
+		// хэш считается и для несуществующего пользователя, чтобы
+		// по времени ответа нельзя было узнать, есть ли такое имя
+		hash, err := a.dummyHash()
+		if err != nil {
+			return false, fmt.Errorf("auth: %w", err)
+		}
+
+		if user != nil && user.PasswordHash != "" {
+			hash = user.PasswordHash
+		}
+
+		ok, err := verifyPassword(password, hash)
+		if err != nil {
+			return false, fmt.Errorf("auth: %w", err)
+		}
+
+		return ok && user != nil && user.PasswordHash != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+const (
+	factorPassword = "password"
+	factorTOTP     = "totp"
+)
+
+// throttle - счётчик неудачных попыток, через который проходит вход.
+type throttle struct {
+	scope  string // для логов
+	key    string
+	policy domain.ThrottlePolicy
+	// slowOnly - счётчик задерживает попытки, но не блокирует их
+	slowOnly bool
+}
+
+// throttlesFor возвращает счётчики попытки. Коды второго фактора считаются
+// ещё и по пользователю: пароль уже известен, и смена ip не должна давать
+// новых попыток подобрать код.
+func throttlesFor(meta domain.SessionMeta, username, factor string) []throttle {
+	throttles := []throttle{
+		{scope: "ip", key: "ip:" + meta.IP, policy: ipThrottlePolicy, slowOnly: false},
+		{scope: "global", key: domain.GlobalThrottleKey, policy: globalThrottlePolicy, slowOnly: true},
+	}
+
+	if factor == factorTOTP {
+		throttles = append(throttles, throttle{
+			scope: "user", key: "user:" + username, policy: ipThrottlePolicy, slowOnly: false,
+		})
+	}
+
+	return throttles
+}
+
+// attempt выполняет проверку одного фактора входа. Ошибки всех факторов
+// считаются вместе, чтобы перебор кода не обходил лимит перебора токена.
+func (a *Auth) attempt(
+	ctx context.Context,
+	meta domain.SessionMeta,
+	username string,
+	factor string,
+	invalidErr error,
+	check func(context.Context) (bool, error),
+) error {
+	throttles := throttlesFor(meta, username, factor)
+
+	// задержка выдерживается до захвата счётчиков, чтобы не держать их зря
+	if err := a.slowDown(ctx, throttles); err != nil {
+		return err
+	}
+
+	// попытки с одного ip и для одного пользователя идут по одной, чтобы
+	// параллельные запросы не обходили счётчик; разные ip друг друга не ждут
+	unlock := a.loginLocks.lock(lockKeys(throttles))
+	defer unlock()
+
+	now := time.Now()
+
+	for _, throttle := range throttles {
+		if err := a.checkLock(ctx, throttle, now); err != nil {
+			var lockedErr *domain.LoginLockedError
+			if errors.As(err, &lockedErr) {
+				a.log.Warn("login rejected",
+					slog.String("event", "login_locked"),
+					slog.String("username", username),
+					slog.String("factor", factor),
+					slog.String("ip", meta.IP),
+					slog.String("user_agent", meta.UserAgent),
+					slog.String("scope", throttle.scope),
+					slog.Duration("retry_after", lockedErr.RetryAfter))
+			}
+
+			return err
+		}
+	}
+
+	ok, err := check(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		a.resetThrottles(ctx, throttles, now)
+
+		return nil
+	}
+
+	attrs := []any{
+		slog.String("event", "login_failed"),
+		slog.String("username", username),
+		slog.String("factor", factor),
+		slog.String("ip", meta.IP),
+		slog.String("user_agent", meta.UserAgent),
+	}
+
+	for _, throttle := range throttles {
+		failures, lockedUntil, err := a.recordFailure(ctx, throttle, now)
+		if err != nil {
+			return err
+		}
+
+		group := []any{slog.Int("failures", failures)}
+		if !throttle.slowOnly {
+			group = append(group, slog.Time("locked_until", lockedUntil))
+		}
+
+		attrs = append(attrs, slog.Group(throttle.scope, group...))
+	}
+
+	a.log.Warn("login failed", attrs...)
+
+	return invalidErr
+}
+
+// resetThrottles сбрасывает счётчики после успешной попытки, кроме
+// глобального, и удаляет давно не нужные записи.
+func (a *Auth) resetThrottles(ctx context.Context, throttles []throttle, now time.Time) {
+	for _, throttle := range throttles {
+		if throttle.key == domain.GlobalThrottleKey {
+			continue
+		}
+
+		if err := a.ThrottlesRepo.Reset(ctx, throttle.key); err != nil {
+			a.log.Warn("can't reset login throttle", slog.Any("error", err))
+		}
+	}
+
+	if err := a.ThrottlesRepo.DeleteStale(ctx, now.Add(-ipThrottlePolicy.ResetAfter)); err != nil {
+		a.log.Warn("can't delete stale login throttles", slog.Any("error", err))
+	}
+}
+
+// slowDown выдерживает задержку счётчиков, которые не блокируют вход.
+func (a *Auth) slowDown(ctx context.Context, throttles []throttle) error {
+	for _, throttle := range throttles {
+		if !throttle.slowOnly {
+			continue
+		}
+
+		record, err := a.ThrottlesRepo.Find(ctx, throttle.key)
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("can't check login throttle: %w", err)
+		}
+
+		if record.LastFailedAt.Before(time.Now().Add(-throttle.policy.ResetAfter)) {
+			continue
+		}
+
+		delay := throttle.policy.Delay(record.Failures)
+		if delay == 0 {
+			continue
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("auth: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	return nil
+}
+
+// checkLock возвращает *domain.LoginLockedError, пока счётчик заблокирован.
+func (a *Auth) checkLock(ctx context.Context, throttle throttle, now time.Time) error {
+	if throttle.slowOnly {
+		return nil
+	}
+
+	record, err := a.ThrottlesRepo.Find(ctx, throttle.key)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("can't check login throttle: %w", err)
+	}
+
+	if now.Before(record.LockedUntil) {
+		return &domain.LoginLockedError{RetryAfter: record.LockedUntil.Sub(now)}
+	}
+
+	return nil
+}
+
+// recordFailure увеличивает счётчик в базе одним запросом, поэтому
+// параллельные ошибки с разных ip не теряются.
+func (a *Auth) recordFailure(ctx context.Context, throttle throttle, now time.Time) (int, time.Time, error) {
+	failures, err := a.ThrottlesRepo.RecordFailure(ctx, throttle.key, now, now.Add(-throttle.policy.ResetAfter))
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("auth: %w", err)
+	}
+
+	if throttle.slowOnly {
+		return failures, time.Time{}, nil
+	}
+
+	lockedUntil := now.Add(throttle.policy.Delay(failures))
+
+	if err = a.ThrottlesRepo.Lock(ctx, throttle.key, lockedUntil); err != nil {
+		return 0, time.Time{}, fmt.Errorf("auth: %w", err)
+	}
+
+	return failures, lockedUntil, nil
+}
+
+// lockKeys возвращает ключи счётчиков, попытки по которым идут по одной.
+// Глобальный счётчик только задерживает попытки и в них не входит.
+func lockKeys(throttles []throttle) []string {
+	keys := make([]string, 0, len(throttles))
+
+	for _, throttle := range throttles {
+		if !throttle.slowOnly {
+			keys = append(keys, throttle.key)
+		}
+	}
+
+	return keys
+}
+
+// keyLocks - мьютексы по ключам счётчиков. Мьютекс живёт, пока его кто-то
+// держит или ждёт, поэтому карта не растёт от перебора ip.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{mu: sync.Mutex{}, locks: make(map[string]*keyLock)}
+}
+
+// lock захватывает ключи в порядке сортировки, чтобы попытки с общими
+// ключами не ждали друг друга по кругу. Возвращает функцию освобождения.
+func (k *keyLocks) lock(keys []string) func() {
+	keys = slices.Compact(slices.Sorted(slices.Values(keys)))
+	held := make([]*keyLock, 0, len(keys))
+
+	for _, key := range keys {
+		k.mu.Lock()
+
+		entry, ok := k.locks[key]
+		if !ok {
+			entry = &keyLock{mu: sync.Mutex{}, refs: 0}
+			k.locks[key] = entry
+		}
+
+		entry.refs++
+		k.mu.Unlock()
+
+		entry.mu.Lock()
+
+		held = append(held, entry)
+	}
+
+	return func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].mu.Unlock()
+
+			k.mu.Lock()
+
+			held[i].refs--
+			if held[i].refs == 0 {
+				delete(k.locks, keys[i])
+			}
+
+			k.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/auth/service"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeThrottlesRepo struct {
+	throttles map[string]*domain.LoginThrottle
+}
+
+func newFakeThrottlesRepo() *fakeThrottlesRepo {
+	return &fakeThrottlesRepo{throttles: make(map[string]*domain.LoginThrottle)}
+}
+
+func (f *fakeThrottlesRepo) Find(_ context.Context, key string) (*domain.LoginThrottle, error) {
+	throttle, ok := f.throttles[key]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return throttle, nil
+}
+
+func (f *fakeThrottlesRepo) RecordFailure(_ context.Context, key string, at, resetBefore time.Time) (int, error) {
+	throttle, ok := f.throttles[key]
+	if !ok || throttle.LastFailedAt.Before(resetBefore) {
+		throttle = &domain.LoginThrottle{Key: key}
+		f.throttles[key] = throttle
+	}
+
+	throttle.Failures++
+	throttle.LastFailedAt = at
+
+	return throttle.Failures, nil
+}
+
+func (f *fakeThrottlesRepo) Lock(_ context.Context, key string, until time.Time) error {
+	f.throttles[key].LockedUntil = until
+
+	return nil
+}
+
+func (f *fakeThrottlesRepo) Reset(_ context.Context, key string) error {
+	delete(f.throttles, key)
+
+	return nil
+}
+
+func (f *fakeThrottlesRepo) DeleteStale(_ context.Context, before time.Time) error {
+	for key, throttle := range f.throttles {
+		if throttle.LastFailedAt.Before(before) && throttle.LockedUntil.Before(before) {
+			delete(f.throttles, key)
+		}
+	}
+
+	return nil
+}
+
+func TestLoginLocksAfterFailures(t *testing.T) {
+	throttles := newFakeThrottlesRepo()
+	auth := newTestAuth(false, newFakeSessionsRepo(), throttles, newFakeTOTPRepo())
+	ctx := context.Background()
+	meta := domain.SessionMeta{UserAgent: "test", IP: "10.0.0.1"}
+
+	require.NoError(t, auth.EnsureAdmin(ctx))
+
+	login := func(username, password string, meta domain.SessionMeta) error {
+		_, err := auth.Login(ctx, username, password, meta)
+
+		return err
+	}
+
+	for range 3 {
+		require.ErrorIs(t, login("admin", "wrong", meta), service.ErrInvalidCredentials)
+	}
+
+	// неизвестное имя считается такой же ошибкой и включает блокировку
+	require.ErrorIs(t, login("nobody", "admin", meta), service.ErrInvalidCredentials)
+
+	err := login("admin", "admin", meta)
+	require.ErrorIs(t, err, errs.ErrRateLimited)
+
+	var lockedErr *domain.LoginLockedError
+	require.ErrorAs(t, err, &lockedErr)
+	assert.Positive(t, lockedErr.RetryAfter)
+
+	// блокировка одного ip не мешает входу с другого
+	require.NoError(t, login("admin", "admin", domain.SessionMeta{UserAgent: "test", IP: "10.0.0.2"}))
+
+	// после окончания блокировки верный пароль сбрасывает счётчик
+	throttles.throttles["ip:10.0.0.1"].LockedUntil = time.Now().Add(-time.Second)
+
+	user, err := auth.Login(ctx, " Admin ", "admin", meta)
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.NotContains(t, throttles.throttles, "ip:10.0.0.1")
+}
+
+func TestGlobalThrottleOnlySlowsDown(t *testing.T) {
+	throttles := newFakeThrottlesRepo()
+	auth := newTestAuth(false, newFakeSessionsRepo(), throttles, newFakeTOTPRepo())
+	ctx := context.Background()
+
+	require.NoError(t, auth.EnsureAdmin(ctx))
+
+	// перебор с множества ip исчерпал глобальный лимит
+	throttles.throttles[domain.GlobalThrottleKey] = &domain.LoginThrottle{
+		Key: domain.GlobalThrottleKey, Failures: 31, LastFailedAt: time.Now(), LockedUntil: time.Now().Add(time.Hour),
+	}
+
+	start := time.Now()
+
+	_, err := auth.Login(ctx, "admin", "admin", domain.SessionMeta{UserAgent: "test", IP: "10.0.0.1"})
+	require.NoError(t, err, "the global throttle never locks the login")
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = auth.Login(canceled, "admin", "admin", domain.SessionMeta{UserAgent: "test", IP: "10.0.0.1"})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	policy := domain.ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		ResetAfter:   time.Hour,
+	}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 8, want: 16 * time.Second},
+		{failures: 10, want: time.Minute},
+		{failures: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, policy.Delay(tt.failures), "failures: %d", tt.failures)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Параметры argon2id - второй рекомендованный вариант из RFC 9106.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// hashPassword возвращает хэш в формате PHC, вместе с параметрами и солью:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>.
+func hashPassword(password string) (string, error) {
+	salt, err := randomBytes(argonSaltLen)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyPassword сравнивает пароль с хэшем за постоянное время. Параметры
+// берутся из самого хэша, поэтому старые хэши продолжают работать после их смены.
+func verifyPassword(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrMalformedHash
+	}
+
+	var (
+		memory  uint32
+		time    uint32
+		threads uint8
+	)
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+
+	//nolint:gosec // key length fits into uint32
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
@@ -0,0 +1,456 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+var (
+	ErrInvalidTOTPCode     = fmt.Errorf("invalid totp code: %w", errs.ErrInvalid)
+	ErrInvalidMFAChallenge = fmt.Errorf("invalid mfa challenge: %w", errs.ErrInvalid)
+	ErrTOTPNotEnabled      = fmt.Errorf("totp is not enabled: %w", errs.ErrNotFound)
+	ErrTOTPRequired        = fmt.Errorf("totp is required by config: %w", errs.ErrInvalid)
+)
+
+const (
+	totpPeriod = 30
+	totpQRSize = 256
+
+	recoveryCodesCount = 10
+	recoveryCodeSize   = 5 // байт, 8 символов base32
+
+	mfaChallengePurpose = "mfa_challenge"
+)
+
+type TOTPRepository interface {
+	Get(ctx context.Context, userID int) (*domain.TOTP, error)
+	SavePending(ctx context.Context, userID int, secret string, at time.Time) error
+	Confirm(ctx context.Context, userID int, step int64, at time.Time, recoveryHashes []string) error
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	Delete(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string, at time.Time) error
+	UseRecoveryCode(ctx context.Context, userID int, hash string, at time.Time) (bool, error)
+	UnusedRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
+
+// MFAChallengesRepository помнит использованные challenge до их истечения.
+type MFAChallengesRepository interface {
+	Use(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// TOTPRequired сообщает, что второй фактор обязателен по конфигурации.
+func (a *Auth) TOTPRequired() bool {
+	return a.requireTOTP
+}
+
+func (a *Auth) TOTPEnabled(ctx context.Context, userID int) (bool, error) {
+	secret, err := a.getTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return secret.Enabled(), nil
+}
+
+// UnusedRecoveryCodes возвращает число ещё не использованных кодов восстановления.
+func (a *Auth) UnusedRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	count, err := a.TOTPRepo.UnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("auth: %w", err)
+	}
+
+	return count, nil
+}
+
+// BeginTOTPEnrollment выпускает новый секрет. Он начнёт действовать
+// только после подтверждения кодом из приложения. account - имя
+// пользователя, под которым секрет покажется в приложении.
+func (a *Auth) BeginTOTPEnrollment(ctx context.Context, userID int, account string) (domain.TOTPEnrollment, error) {
+	//nolint:exhaustruct // defaults for period, digits and algorithm
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      a.issuer,
+		AccountName: account,
+	})
+	if err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("can't generate totp secret: %w", err)
+	}
+
+	if err = a.TOTPRepo.SavePending(ctx, userID, key.Secret(), time.Now()); err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("auth: %w", err)
+	}
+
+	img, err := key.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("can't render qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("can't encode qr code: %w", err)
+	}
+
+	return domain.TOTPEnrollment{Secret: key.Secret(), URL: key.URL(), QRCode: buf.Bytes()}, nil
+}
+
+// ConfirmTOTP включает второй фактор, если код подходит к выпущенному секрету.
+// Ошибки учитываются тем же ограничителем, что и вход. Текущая сессия
+// отмечается как прошедшая второй фактор. Возвращает коды восстановления,
+// они показываются пользователю один раз.
+func (a *Auth) ConfirmTOTP(
+	ctx context.Context,
+	user *domain.Principal,
+	code string,
+	meta domain.SessionMeta,
+) ([]string, domain.Tokens, error) {
+	secret, err := a.getTOTP(ctx, user.UserID)
+	if err != nil {
+		return nil, domain.Tokens{}, err
+	}
+
+	if secret == nil {
+		return nil, domain.Tokens{}, ErrTOTPNotEnabled
+	}
+
+	if secret.Enabled() {
+		return nil, domain.Tokens{}, fmt.Errorf("totp already enabled: %w", errs.ErrDuplicate)
+	}
+
+	now := time.Now()
+
+	var step int64
+
+	err = a.attempt(ctx, meta, user.Username, factorTOTP, ErrInvalidTOTPCode, func(context.Context) (bool, error) {
+		var ok bool
+
+		step, ok = matchTOTP(secret.Secret, code, now)
+
+		return ok, nil
+	})
+	if err != nil {
+		return nil, domain.Tokens{}, err
+	}
+
+	codes, hashes, err := a.newRecoveryCodes()
+	if err != nil {
+		return nil, domain.Tokens{}, err
+	}
+
+	if err = a.TOTPRepo.Confirm(ctx, user.UserID, step, now, hashes); err != nil {
+		return nil, domain.Tokens{}, fmt.Errorf("auth: %w", err)
+	}
+
+	tokens, err := a.markSessionMFA(ctx, user.SessionID, now)
+	if err != nil {
+		return nil, domain.Tokens{}, err
+	}
+
+	return codes, tokens, nil
+}
+
+// RegenerateRecoveryCodes заменяет все коды восстановления новыми.
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	enabled, err := a.TOTPEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	codes, hashes, err := a.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.TOTPRepo.ReplaceRecoveryCodes(ctx, userID, hashes, time.Now()); err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (a *Auth) DisableTOTP(ctx context.Context, userID int) error {
+	if a.requireTOTP {
+		return ErrTOTPRequired
+	}
+
+	if err := a.TOTPRepo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	return nil
+}
+
+// NewMFAChallenge подписывает короткоживущий токен, подтверждающий,
+// что пользователь уже ввёл первый фактор. Сессия создаётся только после второго.
+func (a *Auth) NewMFAChallenge(userID int) (string, error) {
+	now := time.Now()
+
+	id, err := randomToken(sessionIDSize)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"purpose": mfaChallengePurpose,
+		"jti":     id,
+		"sub":     strconv.Itoa(userID),
+		"exp":     now.Add(domain.MFAChallengeTTL).Unix(),
+		"iat":     now.Unix(),
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(a.secretKeyJWT))
+	if err != nil {
+		return "", fmt.Errorf("can't sign string: %w", err)
+	}
+
+	return signed, nil
+}
+
+// VerifySecondFactor проверяет challenge и код из приложения или код
+// восстановления. Ошибки учитываются тем же ограничителем, что и пароль.
+// Challenge одноразовый: после неверного кода вход начинается заново.
+// Возвращает пользователя, для которого выпущен challenge.
+func (a *Auth) VerifySecondFactor(
+	ctx context.Context,
+	challenge, code string,
+	meta domain.SessionMeta,
+) (*domain.User, error) {
+	parsed, ok := a.parseChallenge(challenge)
+	if !ok {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	fresh, err := a.ChallengesRepo.Use(ctx, parsed.id, parsed.expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	if !fresh {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	// истёкшие challenge не пройдут проверку подписи, помнить их незачем
+	if err = a.ChallengesRepo.DeleteExpired(ctx, time.Now()); err != nil {
+		a.log.Warn("can't delete expired mfa challenges", slog.Any("error", err))
+	}
+
+	user, err := a.UsersRepo.Find(ctx, parsed.userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, ErrInvalidMFAChallenge
+		}
+
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	err = a.attempt(ctx, meta, user.Username, factorTOTP, ErrInvalidTOTPCode, func(ctx context.Context) (bool, error) {
+		return a.checkCode(ctx, user.ID, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// StepUp повторно проверяет второй фактор в уже открытой сессии,
+// чтобы разрешить действия, требующие свежего подтверждения.
+func (a *Auth) StepUp(
+	ctx context.Context,
+	user *domain.Principal,
+	code string,
+	meta domain.SessionMeta,
+) (domain.Tokens, error) {
+	err := a.attempt(ctx, meta, user.Username, factorTOTP, ErrInvalidTOTPCode, func(ctx context.Context) (bool, error) {
+		return a.checkCode(ctx, user.UserID, code)
+	})
+	if err != nil {
+		return domain.Tokens{}, err
+	}
+
+	return a.markSessionMFA(ctx, user.SessionID, time.Now())
+}
+
+func (a *Auth) checkCode(ctx context.Context, userID int, code string) (bool, error) {
+	secret, err := a.getTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if !secret.Enabled() {
+		return false, ErrTOTPNotEnabled
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+
+	if step, ok := matchTOTP(secret.Secret, code, time.Now()); ok {
+		// один и тот же код нельзя использовать дважды
+		used, err := a.TOTPRepo.UseStep(ctx, userID, step)
+		if err != nil {
+			return false, fmt.Errorf("auth: %w", err)
+		}
+
+		return used, nil
+	}
+
+	used, err := a.TOTPRepo.UseRecoveryCode(ctx, userID, a.hashRecoveryCode(code), time.Now())
+	if err != nil {
+		return false, fmt.Errorf("auth: %w", err)
+	}
+
+	if used {
+		a.log.Warn("recovery code used", slog.Int("user_id", userID))
+	}
+
+	return used, nil
+}
+
+func (a *Auth) markSessionMFA(ctx context.Context, sessionID string, now time.Time) (domain.Tokens, error) {
+	session, err := a.SessionsRepo.Find(ctx, sessionID)
+	if err != nil {
+		return domain.Tokens{}, fmt.Errorf("can't find session: %w", err)
+	}
+
+	amr := domain.SplitAMR(session.AMR)
+	if !slices.Contains(amr, domain.AMROTP) {
+		amr = append(amr, domain.AMROTP)
+	}
+
+	session.AMR = domain.JoinAMR(amr)
+	session.MFAAt = &now
+
+	if err = a.SessionsRepo.MarkMFA(ctx, session.ID, session.AMR, now); err != nil {
+		return domain.Tokens{}, fmt.Errorf("auth: %w", err)
+	}
+
+	return a.accessOnly(session, now)
+}
+
+// mfaChallenge - проверенные поля challenge.
+type mfaChallenge struct {
+	id        string
+	userID    int
+	expiresAt time.Time
+}
+
+// parseChallenge проверяет подпись и срок challenge.
+func (a *Auth) parseChallenge(challenge string) (mfaChallenge, bool) {
+	token, err := jwt.Parse(challenge, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("%w: %v", ErrUnexpectedSigningMethod, token.Header["alg"])
+		}
+
+		return []byte(a.secretKeyJWT), nil
+	})
+	if err != nil {
+		return mfaChallenge{}, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != mfaChallengePurpose {
+		return mfaChallenge{}, false
+	}
+
+	id, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+
+	userID, err := strconv.Atoi(sub)
+	if err != nil || id == "" {
+		return mfaChallenge{}, false
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return mfaChallenge{}, false
+	}
+
+	return mfaChallenge{id: id, userID: userID, expiresAt: expiresAt.Time}, true
+}
+
+func (a *Auth) getTOTP(ctx context.Context, userID int) (*domain.TOTP, error) {
+	secret, err := a.TOTPRepo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	return secret, nil
+}
+
+// newRecoveryCodes возвращает коды для показа пользователю и их хэши для хранения.
+func (a *Auth) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for range recoveryCodesCount {
+		raw, err := randomBytes(recoveryCodeSize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))
+
+		codes = append(codes, code)
+		hashes = append(hashes, a.hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode - HMAC с секретом сервера: утечка одной базы
+// не позволяет перебрать короткие коды офлайн.
+func (a *Auth) hashRecoveryCode(code string) string {
+	mac := hmac.New(sha256.New, []byte(a.secretKeyJWT))
+	mac.Write([]byte(strings.ToLower(code)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// matchTOTP проверяет код для текущего шага и соседних (расхождение часов)
+// и возвращает номер подошедшего шага.
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	step := now.Unix() / totpPeriod
+
+	for _, candidate := range []int64{step, step - 1, step + 1} {
+		//nolint:exhaustruct // defaults for skew
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(candidate*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return candidate, true
+		}
+	}
+
+	return 0, false
+}
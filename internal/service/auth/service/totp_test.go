@@ -0,0 +1,263 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/auth/service"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTOTPRepo struct {
+	secret        *domain.TOTP
+	recoveryCodes map[string]bool // hash -> used
+}
+
+func newFakeTOTPRepo() *fakeTOTPRepo {
+	return &fakeTOTPRepo{recoveryCodes: make(map[string]bool)}
+}
+
+func (f *fakeTOTPRepo) Get(context.Context, int) (*domain.TOTP, error) {
+	if f.secret == nil {
+		return nil, errs.ErrNotFound
+	}
+
+	return f.secret, nil
+}
+
+func (f *fakeTOTPRepo) SavePending(_ context.Context, userID int, secret string, at time.Time) error {
+	if f.secret.Enabled() {
+		return errs.ErrDuplicate
+	}
+
+	f.secret = &domain.TOTP{UserID: userID, Secret: secret, CreatedAt: at}
+
+	return nil
+}
+
+func (f *fakeTOTPRepo) Confirm(ctx context.Context, userID int, step int64, at time.Time, hashes []string) error {
+	f.secret.ConfirmedAt = &at
+	f.secret.LastStep = step
+
+	return f.ReplaceRecoveryCodes(ctx, userID, hashes, at)
+}
+
+func (f *fakeTOTPRepo) UseStep(_ context.Context, _ int, step int64) (bool, error) {
+	if step <= f.secret.LastStep {
+		return false, nil
+	}
+
+	f.secret.LastStep = step
+
+	return true, nil
+}
+
+func (f *fakeTOTPRepo) Delete(context.Context, int) error {
+	f.secret = nil
+	f.recoveryCodes = make(map[string]bool)
+
+	return nil
+}
+
+func (f *fakeTOTPRepo) ReplaceRecoveryCodes(_ context.Context, _ int, hashes []string, _ time.Time) error {
+	f.recoveryCodes = make(map[string]bool)
+	for _, hash := range hashes {
+		f.recoveryCodes[hash] = false
+	}
+
+	return nil
+}
+
+func (f *fakeTOTPRepo) UseRecoveryCode(_ context.Context, _ int, hash string, _ time.Time) (bool, error) {
+	used, ok := f.recoveryCodes[hash]
+	if !ok || used {
+		return false, nil
+	}
+
+	f.recoveryCodes[hash] = true
+
+	return true, nil
+}
+
+func (f *fakeTOTPRepo) UnusedRecoveryCodes(context.Context, int) (int, error) {
+	count := 0
+
+	for _, used := range f.recoveryCodes {
+		if !used {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+type fakeChallengesRepo struct {
+	used map[string]time.Time
+}
+
+func newFakeChallengesRepo() *fakeChallengesRepo {
+	return &fakeChallengesRepo{used: make(map[string]time.Time)}
+}
+
+func (f *fakeChallengesRepo) Use(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	if _, ok := f.used[id]; ok {
+		return false, nil
+	}
+
+	f.used[id] = expiresAt
+
+	return true, nil
+}
+
+func (f *fakeChallengesRepo) DeleteExpired(_ context.Context, before time.Time) error {
+	for id, expiresAt := range f.used {
+		if expiresAt.Before(before) {
+			delete(f.used, id)
+		}
+	}
+
+	return nil
+}
+
+func TestTOTPEnrollmentAndLogin(t *testing.T) {
+	totpRepo := newFakeTOTPRepo()
+	auth := newTestAuth(true, newFakeSessionsRepo(), newFakeThrottlesRepo(), totpRepo)
+	ctx := context.Background()
+	meta := domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}
+
+	tokens, err := auth.NewJWT(ctx, 1, meta, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	// пока 2FA не настроена, сессии без второго фактора работают даже при обязательной 2FA
+	principal, err := auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+	require.NotNil(t, principal)
+
+	enrollment, err := auth.BeginTOTPEnrollment(ctx, 1, "admin")
+	require.NoError(t, err)
+	assert.NotEmpty(t, enrollment.QRCode)
+
+	_, _, err = auth.ConfirmTOTP(ctx, principal, "000000", meta)
+	require.ErrorIs(t, err, errs.ErrInvalid)
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+
+	recoveryCodes, upgraded, err := auth.ConfirmTOTP(ctx, principal, code, meta)
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	claims, err := auth.TokenClaims(upgraded.Access)
+	require.NoError(t, err)
+	assert.True(t, claims.FreshMFA(time.Now()))
+
+	// после настройки старый токен без второго фактора больше не принимается,
+	// а токен текущей сессии обновлён с amr otp
+	principal, err = auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+
+	principal, err = auth.VerifyJWT(ctx, upgraded.Access)
+	require.NoError(t, err)
+	require.NotNil(t, principal)
+	assert.True(t, principal.HasMFA())
+
+	verify := func(code string) error {
+		challenge, err := auth.NewMFAChallenge(1)
+		require.NoError(t, err)
+
+		_, err = auth.VerifySecondFactor(ctx, challenge, code, meta)
+
+		return err
+	}
+
+	// тот же код повторно не принимается
+	require.ErrorIs(t, verify(code), service.ErrInvalidTOTPCode)
+
+	_, err = auth.VerifySecondFactor(ctx, "forged", recoveryCodes[0], meta)
+	require.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+
+	// код восстановления работает один раз, challenge тоже
+	challenge, err := auth.NewMFAChallenge(1)
+	require.NoError(t, err)
+
+	user, err := auth.VerifySecondFactor(ctx, challenge, recoveryCodes[0], meta)
+	require.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+
+	_, err = auth.VerifySecondFactor(ctx, challenge, recoveryCodes[1], meta)
+	require.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+	require.ErrorIs(t, verify(recoveryCodes[0]), service.ErrInvalidTOTPCode)
+
+	unused, err := auth.UnusedRecoveryCodes(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 9, unused)
+
+	require.ErrorIs(t, auth.DisableTOTP(ctx, 1), service.ErrTOTPRequired)
+}
+
+func TestSecondFactorThrottledPerUser(t *testing.T) {
+	totpRepo := newFakeTOTPRepo()
+	throttles := newFakeThrottlesRepo()
+	auth := newTestAuth(true, newFakeSessionsRepo(), throttles, totpRepo)
+	ctx := context.Background()
+
+	confirmedAt := time.Now()
+	totpRepo.secret = &domain.TOTP{UserID: 1, Secret: "JBSWY3DPEHPK3PXP", ConfirmedAt: &confirmedAt}
+
+	verify := func(code, ip string) error {
+		challenge, err := auth.NewMFAChallenge(1)
+		require.NoError(t, err)
+
+		_, err = auth.VerifySecondFactor(ctx, challenge, code, domain.SessionMeta{UserAgent: "test", IP: ip})
+
+		return err
+	}
+
+	// смена ip не даёт новых попыток подобрать код
+	for i := range 4 {
+		require.ErrorIs(t, verify("000000", fmt.Sprintf("10.0.0.%d", i+1)), service.ErrInvalidTOTPCode)
+	}
+
+	code, err := totp.GenerateCode(totpRepo.secret.Secret, time.Now())
+	require.NoError(t, err)
+	require.ErrorIs(t, verify(code, "10.0.0.10"), errs.ErrRateLimited)
+
+	throttles.throttles["user:admin"].LockedUntil = time.Now().Add(-time.Second)
+
+	require.NoError(t, verify(code, "10.0.0.10"))
+	assert.NotContains(t, throttles.throttles, "user:admin")
+}
+
+func TestConfirmTOTPThrottled(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+	meta := domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}
+
+	tokens, err := auth.NewJWT(ctx, 1, meta, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	principal, err := auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+
+	enrollment, err := auth.BeginTOTPEnrollment(ctx, 1, "admin")
+	require.NoError(t, err)
+
+	// подбор кода при подключении упирается в тот же счётчик, что и вход
+	for range 4 {
+		_, _, err = auth.ConfirmTOTP(ctx, principal, "000000", meta)
+		require.ErrorIs(t, err, service.ErrInvalidTOTPCode)
+	}
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+
+	_, _, err = auth.ConfirmTOTP(ctx, principal, code, meta)
+	require.ErrorIs(t, err, errs.ErrRateLimited)
+}
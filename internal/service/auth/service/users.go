@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+var (
+	ErrInvalidUser    = fmt.Errorf("invalid user: %w", errs.ErrInvalid)
+	ErrBootstrapAdmin = errors.New("can't bootstrap admin")
+)
+
+const (
+	bootstrapAdminUsername = "admin"
+	minPasswordLength      = 12
+	maxDisplayNameLength   = 100
+)
+
+//nolint:gochecknoglobals // compiled once
+var usernameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{1,49}$`)
+
+type UsersRepository interface {
+	All(ctx context.Context) ([]*domain.User, error)
+	Find(ctx context.Context, id int) (*domain.User, error)
+	FindByUsername(ctx context.Context, username string) (*domain.User, error)
+	Create(ctx context.Context, user *domain.User) error
+	SetRole(ctx context.Context, id int, role domain.Role) error
+	SetPasswordHash(ctx context.Context, id int, hash string) error
+	Delete(ctx context.Context, id int) error
+	CountByRole(ctx context.Context, role domain.Role) (int, error)
+}
+
+// EnsureAdmin выставляет пароль пользователю admin из ADMIN_TOKEN, пока
+// у него нет своего пароля, и создаёт admin, если админов не осталось.
+func (a *Auth) EnsureAdmin(ctx context.Context) error {
+	admin, err := a.UsersRepo.FindByUsername(ctx, bootstrapAdminUsername)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrBootstrapAdmin, err)
+	}
+
+	if admin != nil && admin.PasswordHash != "" {
+		return nil
+	}
+
+	if admin == nil {
+		admins, countErr := a.UsersRepo.CountByRole(ctx, domain.RoleAdmin)
+		if countErr != nil {
+			return fmt.Errorf("%w: %w", ErrBootstrapAdmin, countErr)
+		}
+
+		if admins > 0 {
+			return nil
+		}
+	}
+
+	hash, err := hashPassword(a.adminToken)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBootstrapAdmin, err)
+	}
+
+	if admin != nil {
+		if err = a.UsersRepo.SetPasswordHash(ctx, admin.ID, hash); err != nil {
+			return fmt.Errorf("%w: %w", ErrBootstrapAdmin, err)
+		}
+
+		a.log.Info("admin password set from ADMIN_TOKEN", slog.String("username", admin.Username))
+
+		return nil
+	}
+
+	err = a.UsersRepo.Create(ctx, &domain.User{
+		ID:           0,
+		Username:     bootstrapAdminUsername,
+		DisplayName:  bootstrapAdminUsername,
+		PasswordHash: hash,
+		Role:         domain.RoleAdmin,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBootstrapAdmin, err)
+	}
+
+	a.log.Info("admin user created with ADMIN_TOKEN as password")
+
+	return nil
+}
+
+func (a *Auth) Users(ctx context.Context) ([]*domain.User, error) {
+	users, err := a.UsersRepo.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	return users, nil
+}
+
+func (a *Auth) CreateUser(ctx context.Context, params domain.CreateUserParams) (*domain.User, error) {
+	params.Username = strings.ToLower(strings.TrimSpace(params.Username))
+	params.DisplayName = strings.TrimSpace(params.DisplayName)
+
+	if params.DisplayName == "" {
+		params.DisplayName = params.Username
+	}
+
+	if !usernameRe.MatchString(params.Username) || !params.Role.Valid() ||
+		utf8.RuneCountInString(params.DisplayName) > maxDisplayNameLength {
+		return nil, ErrInvalidUser
+	}
+
+	hash, err := a.newPasswordHash(params.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{
+		ID:           0,
+		Username:     params.Username,
+		DisplayName:  params.DisplayName,
+		PasswordHash: hash,
+		Role:         params.Role,
+		CreatedAt:    time.Now(),
+	}
+
+	if err = a.UsersRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	return user, nil
+}
+
+func (a *Auth) SetUserRole(ctx context.Context, id int, role domain.Role) error {
+	if !role.Valid() {
+		return ErrInvalidUser
+	}
+
+	if role != domain.RoleAdmin {
+		if err := a.ensureNotLastAdmin(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := a.UsersRepo.SetRole(ctx, id, role); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserPassword меняет пароль и завершает все сессии пользователя.
+func (a *Auth) SetUserPassword(ctx context.Context, id int, password string) error {
+	hash, err := a.newPasswordHash(password)
+	if err != nil {
+		return err
+	}
+
+	if err = a.UsersRepo.SetPasswordHash(ctx, id, hash); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	sessions, err := a.SessionsRepo.Active(ctx, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	for _, session := range sessions {
+		if err = a.SessionsRepo.Revoke(ctx, session.ID, id, time.Now()); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteUser удаляет пользователя вместе с сессиями. Посты остаются без автора.
+func (a *Auth) DeleteUser(ctx context.Context, id int) error {
+	if err := a.ensureNotLastAdmin(ctx, id); err != nil {
+		return err
+	}
+
+	if err := a.UsersRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Auth) ensureNotLastAdmin(ctx context.Context, id int) error {
+	user, err := a.UsersRepo.Find(ctx, id)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	if user.Role != domain.RoleAdmin {
+		return nil
+	}
+
+	admins, err := a.UsersRepo.CountByRole(ctx, domain.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+
+	if admins <= 1 {
+		return domain.ErrLastAdmin
+	}
+
+	return nil
+}
+
+func (a *Auth) newPasswordHash(password string) (string, error) {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return "", domain.ErrWeakPassword
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,187 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeUsersRepo struct {
+	users  map[int]*domain.User
+	nextID int
+}
+
+func newFakeUsersRepo(users ...*domain.User) *fakeUsersRepo {
+	repo := &fakeUsersRepo{users: make(map[int]*domain.User), nextID: 1}
+
+	for _, user := range users {
+		repo.users[user.ID] = user
+		repo.nextID = max(repo.nextID, user.ID+1)
+	}
+
+	return repo
+}
+
+func (f *fakeUsersRepo) All(context.Context) ([]*domain.User, error) {
+	users := make([]*domain.User, 0, len(f.users))
+	for _, user := range f.users {
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (f *fakeUsersRepo) Find(_ context.Context, id int) (*domain.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (f *fakeUsersRepo) FindByUsername(_ context.Context, username string) (*domain.User, error) {
+	for _, user := range f.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return nil, errs.ErrNotFound
+}
+
+func (f *fakeUsersRepo) Create(ctx context.Context, user *domain.User) error {
+	if existing, _ := f.FindByUsername(ctx, user.Username); existing != nil {
+		return errs.ErrDuplicate
+	}
+
+	user.ID = f.nextID
+	f.nextID++
+	f.users[user.ID] = user
+
+	return nil
+}
+
+func (f *fakeUsersRepo) SetRole(_ context.Context, id int, role domain.Role) error {
+	f.users[id].Role = role
+
+	return nil
+}
+
+func (f *fakeUsersRepo) SetPasswordHash(_ context.Context, id int, hash string) error {
+	f.users[id].PasswordHash = hash
+
+	return nil
+}
+
+func (f *fakeUsersRepo) Delete(_ context.Context, id int) error {
+	delete(f.users, id)
+
+	return nil
+}
+
+func (f *fakeUsersRepo) CountByRole(_ context.Context, role domain.Role) (int, error) {
+	count := 0
+
+	for _, user := range f.users {
+		if user.Role == role {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func TestCreateUserAndLogin(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+	meta := domain.SessionMeta{UserAgent: "test", IP: "127.0.0.1"}
+
+	_, err := auth.CreateUser(ctx, domain.CreateUserParams{
+		Username: "Bad Name", DisplayName: "", Password: "long enough password", Role: domain.RoleAuthor,
+	})
+	require.ErrorIs(t, err, errs.ErrInvalid)
+
+	_, err = auth.CreateUser(ctx, domain.CreateUserParams{
+		Username: "anna", DisplayName: "", Password: "short", Role: domain.RoleAuthor,
+	})
+	require.ErrorIs(t, err, domain.ErrWeakPassword)
+
+	user, err := auth.CreateUser(ctx, domain.CreateUserParams{
+		Username: " Anna ", DisplayName: "", Password: "long enough password", Role: domain.RoleAuthor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "anna", user.Username)
+	assert.Equal(t, "anna", user.DisplayName)
+	assert.NotContains(t, user.PasswordHash, "long enough password")
+
+	_, err = auth.CreateUser(ctx, domain.CreateUserParams{
+		Username: "anna", DisplayName: "", Password: "long enough password", Role: domain.RoleAuthor,
+	})
+	require.ErrorIs(t, err, errs.ErrDuplicate)
+
+	loggedIn, err := auth.Login(ctx, "anna", "long enough password", meta)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedIn.ID)
+
+	tokens, err := auth.NewJWT(ctx, user.ID, meta, []string{domain.AMRPassword})
+	require.NoError(t, err)
+
+	principal, err := auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+	require.NotNil(t, principal)
+	assert.Equal(t, domain.RoleAuthor, principal.Role)
+
+	// смена пароля завершает сессии пользователя
+	require.NoError(t, auth.SetUserPassword(ctx, user.ID, "another long password"))
+
+	principal, err = auth.VerifyJWT(ctx, tokens.Access)
+	require.NoError(t, err)
+	assert.Nil(t, principal)
+
+	_, err = auth.Login(ctx, "anna", "long enough password", meta)
+	require.ErrorIs(t, err, errs.ErrInvalid)
+}
+
+func TestLastAdminIsProtected(t *testing.T) {
+	auth := newTestAuth(false, newFakeSessionsRepo(), newFakeThrottlesRepo(), newFakeTOTPRepo())
+	ctx := context.Background()
+
+	require.ErrorIs(t, auth.SetUserRole(ctx, 1, domain.RoleEditor), domain.ErrLastAdmin)
+	require.ErrorIs(t, auth.DeleteUser(ctx, 1), domain.ErrLastAdmin)
+
+	second, err := auth.CreateUser(ctx, domain.CreateUserParams{
+		Username: "root", DisplayName: "", Password: "long enough password", Role: domain.RoleAdmin,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, auth.SetUserRole(ctx, 1, domain.RoleEditor))
+	require.ErrorIs(t, auth.DeleteUser(ctx, second.ID), domain.ErrLastAdmin)
+}
+
+func TestPrincipalPermissions(t *testing.T) {
+	var anonymous *domain.Principal
+
+	author := &domain.Principal{UserID: 2, Role: domain.RoleAuthor}
+	editor := &domain.Principal{UserID: 3, Role: domain.RoleEditor}
+	admin := &domain.Principal{UserID: 1, Role: domain.RoleAdmin}
+
+	assert.False(t, anonymous.CanEditPost(2))
+	assert.True(t, author.CanEditPost(2))
+	assert.False(t, author.CanEditPost(3))
+	assert.False(t, author.CanEditPost(0))
+	assert.True(t, editor.CanEditPost(2))
+
+	assert.False(t, author.CanPublish())
+	assert.True(t, editor.CanPublish())
+	assert.False(t, editor.CanManageUsers())
+	assert.True(t, admin.CanManageUsers())
+
+	// у сессий браузера нет ограничений по scope
+	assert.True(t, admin.Allows(domain.ScopePostsPublish))
+	assert.False(t, anonymous.Allows(domain.ScopePostsRead))
+}
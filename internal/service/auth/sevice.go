@@ -4,8 +4,27 @@ import (
 	"log/slog"
 
 	"github.com/arevbond/arevbond-blog/internal/service/auth/service"
+	"github.com/arevbond/arevbond-blog/internal/service/auth/storage"
+	"github.com/jmoiron/sqlx"
 )
 
-func NewAuthModule(log *slog.Logger, adminToken string, secretKeyJWT string) *service.Auth {
-	return service.New(log, adminToken, secretKeyJWT)
+func NewAuthModule(
+	log *slog.Logger,
+	db *sqlx.DB,
+	adminToken string,
+	secretKeyJWT string,
+	issuer string,
+	requireTOTP bool,
+) *service.Auth {
+	sessionsRepo := storage.NewSessionsRepo(log, db)
+	throttlesRepo := storage.NewThrottlesRepo(log, db)
+	challengesRepo := storage.NewMFAChallengesRepo(log, db)
+	totpRepo := storage.NewTOTPRepo(log, db)
+	usersRepo := storage.NewUsersRepo(log, db)
+	apiTokensRepo := storage.NewAPITokensRepo(log, db)
+
+	return service.New(
+		log, adminToken, secretKeyJWT, issuer, requireTOTP,
+		sessionsRepo, throttlesRepo, challengesRepo, totpRepo, usersRepo, apiTokensRepo,
+	)
 }
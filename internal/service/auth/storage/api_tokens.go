@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type APITokens struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewAPITokensRepo(log *slog.Logger, db *sqlx.DB) *APITokens {
+	return &APITokens{log: log, DB: db}
+}
+
+func (a *APITokens) Create(ctx context.Context, token *domain.APIToken) error {
+	query := `
+		INSERT INTO api_tokens (user_id, name, prefix, token_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id;`
+
+	args := []any{token.UserID, token.Name, token.Prefix, token.Hash, token.Scopes, token.CreatedAt, token.ExpiresAt}
+
+	if err := a.DB.QueryRowContext(ctx, query, args...).Scan(&token.ID); err != nil {
+		return fmt.Errorf("can't insert api token: %w", err)
+	}
+
+	return nil
+}
+
+func (a *APITokens) FindByHash(ctx context.Context, hash string) (*domain.APIToken, error) {
+	query := `
+		SELECT t.id, t.user_id, u.username, t.name, t.prefix, t.token_hash, t.scopes,
+			t.created_at, t.expires_at, t.last_used_at, t.revoked_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1;`
+
+	var token domain.APIToken
+
+	err := a.DB.GetContext(ctx, &token, query, hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api token: %w", errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get api token from db: %w", err)
+	}
+
+	return &token, nil
+}
+
+// All возвращает токены, начиная с новых. Отозванные тоже попадают в список,
+// чтобы было видно, каким токеном пользовались.
+func (a *APITokens) All(ctx context.Context) ([]*domain.APIToken, error) {
+	query := `
+		SELECT t.id, t.user_id, u.username, t.name, t.prefix, t.token_hash, t.scopes,
+			t.created_at, t.expires_at, t.last_used_at, t.revoked_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		ORDER BY t.created_at DESC;`
+
+	tokens := []*domain.APIToken{}
+
+	if err := a.DB.SelectContext(ctx, &tokens, query); err != nil {
+		return nil, fmt.Errorf("can't get api tokens from db: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (a *APITokens) Revoke(ctx context.Context, id int, at time.Time) error {
+	query := `UPDATE api_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL;`
+
+	res, err := a.DB.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return fmt.Errorf("can't revoke api token: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't get rows affected: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("api token %d: %w", id, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (a *APITokens) Touch(ctx context.Context, id int, at time.Time) error {
+	_, err := a.DB.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = $1 WHERE id = $2;`, at, id)
+	if err != nil {
+		return fmt.Errorf("can't update api token last use: %w", err)
+	}
+
+	return nil
+}
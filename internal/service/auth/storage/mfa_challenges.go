@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MFAChallenges хранит использованные challenge второго фактора, пока они не истекут.
+type MFAChallenges struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewMFAChallengesRepo(log *slog.Logger, db *sqlx.DB) *MFAChallenges {
+	return &MFAChallenges{log: log, DB: db}
+}
+
+// Use отмечает challenge использованным. Возвращает false, если он уже был
+// использован: вставка атомарна, поэтому из параллельных запросов challenge
+// достанется только одному.
+func (c *MFAChallenges) Use(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO used_mfa_challenges (id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING;`
+
+	result, err := c.DB.ExecContext(ctx, query, id, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("can't use mfa challenge: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+
+	return rowsAffected == 1, nil
+}
+
+// DeleteExpired удаляет challenge, истёкшие до before: их уже не примет проверка подписи.
+func (c *MFAChallenges) DeleteExpired(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM used_mfa_challenges WHERE expires_at < $1;`
+
+	_, err := c.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return fmt.Errorf("can't delete expired mfa challenges: %w", err)
+	}
+
+	return nil
+}
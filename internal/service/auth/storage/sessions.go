@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type Sessions struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewSessionsRepo(log *slog.Logger, db *sqlx.DB) *Sessions {
+	return &Sessions{log: log, DB: db}
+}
+
+func (s *Sessions) Create(ctx context.Context, session *domain.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, ip, created_at, expires_at, amr, mfa_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+
+	args := []any{
+		session.ID, session.UserID, session.UserAgent, session.IP, session.CreatedAt, session.ExpiresAt,
+		session.AMR, session.MFAAt,
+	}
+
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("can't insert session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Sessions) Find(ctx context.Context, id string) (*domain.Session, error) {
+	query := `
+		SELECT id, user_id, user_agent, ip, created_at, expires_at, revoked_at, amr, mfa_at
+		FROM sessions
+		WHERE id = $1;`
+
+	var session domain.Session
+
+	err := s.DB.GetContext(ctx, &session, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("session %s: %w", id, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get session from db: %w", err)
+	}
+
+	return &session, nil
+}
+
+// Active возвращает неотозванные и неистёкшие сессии пользователя,
+// новые первыми. userID = 0 возвращает сессии всех пользователей.
+func (s *Sessions) Active(ctx context.Context, now time.Time, userID int) ([]*domain.Session, error) {
+	query := `
+		SELECT s.id, s.user_id, u.username, s.user_agent, s.ip, s.created_at, s.expires_at,
+		       s.revoked_at, s.amr, s.mfa_at
+		FROM sessions s
+		INNER JOIN users u ON s.user_id = u.id
+		WHERE s.revoked_at IS NULL AND s.expires_at > $1 AND ($2 = 0 OR s.user_id = $2)
+		ORDER BY s.created_at DESC;`
+
+	sessions := []*domain.Session{}
+
+	err := s.DB.SelectContext(ctx, &sessions, query, now, userID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get sessions from db: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke отзывает сессию пользователя. userID = 0 отзывает сессию любого пользователя.
+func (s *Sessions) Revoke(ctx context.Context, id string, userID int, at time.Time) error {
+	query := `
+		UPDATE sessions SET revoked_at = $1
+		WHERE id = $2 AND ($3 = 0 OR user_id = $3) AND revoked_at IS NULL;`
+
+	_, err := s.DB.ExecContext(ctx, query, at, id, userID)
+	if err != nil {
+		return fmt.Errorf("can't revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired удаляет сессии, истёкшие раньше before.
+func (s *Sessions) DeleteExpired(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM sessions WHERE expires_at < $1;`
+
+	_, err := s.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return fmt.Errorf("can't delete expired sessions: %w", err)
+	}
+
+	return nil
+}
+
+// MarkMFA отмечает, что в сессии только что был введён второй фактор.
+func (s *Sessions) MarkMFA(ctx context.Context, id string, amr string, at time.Time) error {
+	query := `UPDATE sessions SET amr = $1, mfa_at = $2 WHERE id = $3;`
+
+	_, err := s.DB.ExecContext(ctx, query, amr, at, id)
+	if err != nil {
+		return fmt.Errorf("can't mark session mfa: %w", err)
+	}
+
+	return nil
+}
+
+// Extend сдвигает срок действия сессии при обновлении токенов.
+func (s *Sessions) Extend(ctx context.Context, id string, expiresAt time.Time) error {
+	query := `UPDATE sessions SET expires_at = $1 WHERE id = $2;`
+
+	_, err := s.DB.ExecContext(ctx, query, expiresAt, id)
+	if err != nil {
+		return fmt.Errorf("can't extend session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Sessions) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token_hash, session_id, created_at)
+		VALUES ($1, $2, $3);`
+
+	_, err := s.DB.ExecContext(ctx, query, token.Hash, token.SessionID, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("can't insert refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Sessions) FindRefreshToken(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT token_hash, session_id, created_at, rotated_at
+		FROM refresh_tokens
+		WHERE token_hash = $1;`
+
+	var token domain.RefreshToken
+
+	err := s.DB.GetContext(ctx, &token, query, hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("refresh token: %w", errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get refresh token from db: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken помечает токен использованным. Возвращает false,
+// если токен уже был использован параллельным запросом.
+func (s *Sessions) RotateRefreshToken(ctx context.Context, hash string, at time.Time) (bool, error) {
+	query := `UPDATE refresh_tokens SET rotated_at = $1 WHERE token_hash = $2 AND rotated_at IS NULL;`
+
+	res, err := s.DB.ExecContext(ctx, query, at, hash)
+	if err != nil {
+		return false, fmt.Errorf("can't rotate refresh token: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("can't get rows affected: %w", err)
+	}
+
+	return affected == 1, nil
+}
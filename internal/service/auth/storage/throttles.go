@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type Throttles struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewThrottlesRepo(log *slog.Logger, db *sqlx.DB) *Throttles {
+	return &Throttles{log: log, DB: db}
+}
+
+func (t *Throttles) Find(ctx context.Context, key string) (*domain.LoginThrottle, error) {
+	query := `
+		SELECT key, failures, last_failed_at, locked_until
+		FROM login_throttles
+		WHERE key = $1;`
+
+	var throttle domain.LoginThrottle
+
+	err := t.DB.GetContext(ctx, &throttle, query, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("throttle %s: %w", key, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get throttle from db: %w", err)
+	}
+
+	return &throttle, nil
+}
+
+// RecordFailure атомарно увеличивает счётчик неудачных попыток и возвращает
+// новое значение. Если последняя ошибка была раньше resetBefore, счёт начинается заново.
+func (t *Throttles) RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (int, error) {
+	query := `
+		INSERT INTO login_throttles (key, failures, last_failed_at, locked_until)
+		VALUES ($1, 1, $2, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failed_at < $3 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING failures;`
+
+	var failures int
+
+	err := t.DB.QueryRowxContext(ctx, query, key, at, resetBefore).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("can't record login failure: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (t *Throttles) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $1 WHERE key = $2;`
+
+	_, err := t.DB.ExecContext(ctx, query, until, key)
+	if err != nil {
+		return fmt.Errorf("can't lock login: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStale удаляет счётчики без ошибок и блокировок после before.
+func (t *Throttles) DeleteStale(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM login_throttles WHERE last_failed_at < $1 AND locked_until < $1;`
+
+	_, err := t.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return fmt.Errorf("can't delete stale throttles: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Throttles) Reset(ctx context.Context, key string) error {
+	query := `DELETE FROM login_throttles WHERE key = $1;`
+
+	_, err := t.DB.ExecContext(ctx, query, key)
+	if err != nil {
+		return fmt.Errorf("can't reset throttle: %w", err)
+	}
+
+	return nil
+}
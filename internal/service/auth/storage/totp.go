@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type TOTP struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewTOTPRepo(log *slog.Logger, db *sqlx.DB) *TOTP {
+	return &TOTP{log: log, DB: db}
+}
+
+func (t *TOTP) Get(ctx context.Context, userID int) (*domain.TOTP, error) {
+	query := `
+		SELECT user_id, secret, last_step, created_at, confirmed_at
+		FROM totp_secrets
+		WHERE user_id = $1;`
+
+	var totp domain.TOTP
+
+	err := t.DB.GetContext(ctx, &totp, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("totp: %w", errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get totp from db: %w", err)
+	}
+
+	return &totp, nil
+}
+
+// SavePending сохраняет новый неподтверждённый секрет. Подтверждённый
+// секрет не перезаписывается, его нужно сначала отключить.
+func (t *TOTP) SavePending(ctx context.Context, userID int, secret string, at time.Time) error {
+	query := `
+		INSERT INTO totp_secrets (user_id, secret, last_step, created_at, confirmed_at)
+		VALUES ($3, $1, 0, $2, NULL)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_step = 0,
+			created_at = EXCLUDED.created_at
+		WHERE totp_secrets.confirmed_at IS NULL;`
+
+	res, err := t.DB.ExecContext(ctx, query, secret, at, userID)
+	if err != nil {
+		return fmt.Errorf("can't save totp secret: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't get rows affected: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("totp already enabled: %w", errs.ErrDuplicate)
+	}
+
+	return nil
+}
+
+// Confirm включает секрет и одновременно сохраняет коды восстановления.
+func (t *TOTP) Confirm(ctx context.Context, userID int, step int64, at time.Time, recoveryHashes []string) error {
+	tx, err := t.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE totp_secrets SET confirmed_at = $1, last_step = $2 WHERE user_id = $3;`, at, step, userID)
+	if err != nil {
+		return fmt.Errorf("can't confirm totp: %w", err)
+	}
+
+	if err = replaceRecoveryCodes(ctx, tx, userID, recoveryHashes, at); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// UseStep запоминает принятый шаг. Возвращает false, если код этого
+// или более позднего шага уже использовался.
+func (t *TOTP) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `UPDATE totp_secrets SET last_step = $1 WHERE user_id = $2 AND last_step < $1;`
+
+	res, err := t.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("can't use totp step: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("can't get rows affected: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (t *TOTP) Delete(ctx context.Context, userID int) error {
+	tx, err := t.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM totp_secrets WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("can't delete totp: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("can't delete recovery codes: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit tx: %w", err)
+	}
+
+	return nil
+}
+
+func (t *TOTP) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string, at time.Time) error {
+	tx, err := t.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("can't begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = replaceRecoveryCodes(ctx, tx, userID, hashes, at); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("can't commit tx: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode погашает код. Возвращает false, если кода нет или он уже использован.
+func (t *TOTP) UseRecoveryCode(ctx context.Context, userID int, hash string, at time.Time) (bool, error) {
+	query := `
+		UPDATE recovery_codes SET used_at = $1
+		WHERE code_hash = $2 AND user_id = $3 AND used_at IS NULL;`
+
+	res, err := t.DB.ExecContext(ctx, query, at, hash, userID)
+	if err != nil {
+		return false, fmt.Errorf("can't use recovery code: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("can't get rows affected: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (t *TOTP) UnusedRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+
+	err := t.DB.GetContext(ctx, &count,
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL;`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("can't count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID int, hashes []string, at time.Time) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("can't delete recovery codes: %w", err)
+	}
+
+	for _, hash := range hashes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO recovery_codes (code_hash, user_id, created_at) VALUES ($1, $2, $3);`, hash, userID, at)
+		if err != nil {
+			return fmt.Errorf("can't insert recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
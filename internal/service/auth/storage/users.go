@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/arevbond/arevbond-blog/internal/service/auth/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+)
+
+const uniqueViolationErr = "23505"
+
+type Users struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewUsersRepo(log *slog.Logger, db *sqlx.DB) *Users {
+	return &Users{log: log, DB: db}
+}
+
+func (u *Users) All(ctx context.Context) ([]*domain.User, error) {
+	query := `
+		SELECT id, username, display_name, password_hash, role, created_at
+		FROM users
+		ORDER BY id;`
+
+	users := []*domain.User{}
+
+	err := u.DB.SelectContext(ctx, &users, query)
+	if err != nil {
+		return nil, fmt.Errorf("can't get users from db: %w", err)
+	}
+
+	return users, nil
+}
+
+func (u *Users) Find(ctx context.Context, id int) (*domain.User, error) {
+	query := `
+		SELECT id, username, display_name, password_hash, role, created_at
+		FROM users
+		WHERE id = $1;`
+
+	return u.get(ctx, query, id)
+}
+
+func (u *Users) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	query := `
+		SELECT id, username, display_name, password_hash, role, created_at
+		FROM users
+		WHERE username = $1;`
+
+	return u.get(ctx, query, username)
+}
+
+func (u *Users) get(ctx context.Context, query string, arg any) (*domain.User, error) {
+	var user domain.User
+
+	err := u.DB.GetContext(ctx, &user, query, arg)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user %v: %w", arg, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get user from db: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (u *Users) Create(ctx context.Context, user *domain.User) error {
+	query := `
+		INSERT INTO users (username, display_name, password_hash, role, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;`
+
+	args := []any{user.Username, user.DisplayName, user.PasswordHash, user.Role, user.CreatedAt}
+
+	err := u.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationErr {
+			return fmt.Errorf("user %s: %w", user.Username, errs.ErrDuplicate)
+		}
+
+		return fmt.Errorf("can't insert user: %w", err)
+	}
+
+	return nil
+}
+
+func (u *Users) SetRole(ctx context.Context, id int, role domain.Role) error {
+	return u.exec(ctx, `UPDATE users SET role = $1 WHERE id = $2;`, role, id)
+}
+
+func (u *Users) SetPasswordHash(ctx context.Context, id int, hash string) error {
+	return u.exec(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2;`, hash, id)
+}
+
+func (u *Users) Delete(ctx context.Context, id int) error {
+	return u.exec(ctx, `DELETE FROM users WHERE id = $1;`, id)
+}
+
+func (u *Users) CountByRole(ctx context.Context, role domain.Role) (int, error) {
+	var count int
+
+	err := u.DB.GetContext(ctx, &count, `SELECT COUNT(*) FROM users WHERE role = $1;`, role)
+	if err != nil {
+		return 0, fmt.Errorf("can't count users: %w", err)
+	}
+
+	return count, nil
+}
+
+func (u *Users) exec(ctx context.Context, query string, args ...any) error {
+	res, err := u.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("can't update user: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("can't get rows affected: %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("user: %w", errs.ErrNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// ConflictPolicy - что делать при импорте с постом, slug которого уже занят.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"      // оставить пост в базе как есть
+	ConflictOverwrite ConflictPolicy = "overwrite" // заменить пост в базе постом из архива
+	ConflictRename    ConflictPolicy = "rename"    // добавить пост из архива под свободным slug
+)
+
+func (p ConflictPolicy) Valid() bool {
+	return p == ConflictSkip || p == ConflictOverwrite || p == ConflictRename
+}
+
+type ImportOptions struct {
+	Policy ConflictPolicy
+	// AuthorID - автор новых постов: в архиве автор записан только именем.
+	AuthorID int
+}
+
+// ImportReport перечисляет slug постов по результату импорта. Пост,
+// совпадающий с постом в базе, считается неизменённым при любой политике,
+// поэтому повторный импорт того же архива ничего не меняет.
+type ImportReport struct {
+	Created   []string
+	Updated   []string
+	Renamed   map[string]string // slug в архиве -> slug нового поста
+	Skipped   []string
+	Unchanged []string
+
+	Categories []string // созданные категории
+	// Images - записанные картинки. Картинки с тем же именем, но другим
+	// содержимым заменяются только при ConflictOverwrite.
+	Images        []string
+	SkippedImages []string
+}
+
+type ExportReport struct {
+	Posts      int
+	Categories int
+	Images     int
+	// MissingImages - картинки, на которые ссылаются посты, но которых нет на диске.
+	MissingImages []string
+}
+
+// ArchiveFilename - имя файла архива для скачивания.
+func ArchiveFilename(now time.Time) string {
+	return "arevbond-blog-" + now.Format(time.DateOnly) + ".zip"
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "context"
+
+type skipCacheKey struct{}
+
+// SkipCache помечает запрос, которому нужны данные прямо из базы:
+// вошедшие пользователи должны сразу видеть свои правки.
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+// CacheSkipped сообщает, что запрос помечен SkipCache.
+func CacheSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+
+	return skip
+}
+
+// CacheStats - счётчики кэша постов с запуска процесса.
+type CacheStats struct {
+	Hits       int64
+	Misses     int64
+	Evictions  int64 // вытеснены из-за размера или срока
+	Entries    int
+	MaxEntries int
+}
+
+// HitRatio - доля попаданий в процентах, 0 без обращений.
+func (s CacheStats) HitRatio() int {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return int(s.Hits * 100 / total) //nolint:mnd // percent
+}
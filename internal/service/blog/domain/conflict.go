@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+// FieldChange - поле, в котором правка расходится с сохранённым постом.
+type FieldChange struct {
+	Field   string
+	Yours   string
+	Current string
+}
+
+// VersionConflictError возвращается, когда пост изменили после того,
+// как его открыли для правки.
+type VersionConflictError struct {
+	Current *Post
+	Changes []FieldChange
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("post %d was changed, current version is %d", e.Current.ID, e.Current.Version)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return errs.ErrConflict
+}
+
+// Diff сравнивает правку с текущим постом и возвращает различающиеся поля.
+func (p *UpdatePostParams) Diff(current *Post) []FieldChange {
+	var changes []FieldChange
+
+	add := func(field, yours, current string) {
+		if yours != current {
+			changes = append(changes, FieldChange{Field: field, Yours: yours, Current: current})
+		}
+	}
+
+	add("title", p.Title, current.Title)
+	add("slug", p.Slug, current.Slug)
+	add("description", p.Description, current.Description)
+	add("category_id", strconv.Itoa(p.CategoryID), strconv.Itoa(current.CategoryID))
+	add("content", string(p.Content), string(current.Content))
+
+	return changes
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"strings"
+)
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = " "
+	DiffDelete DiffOp = "-"
+	DiffInsert DiffOp = "+"
+	DiffSkip   DiffOp = "…" // пропущенные неизменные строки
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// maxDiffCells ограничивает таблицу LCS: для больших правок середина
+// показывается как удалённая и добавленная целиком.
+const maxDiffCells = 4_000_000
+
+// DiffLines строит построчный diff from -> to и оставляет вокруг
+// изменений не больше context неизменных строк.
+func DiffLines(from, to string, context int) []DiffLine {
+	a, b := strings.Split(from, "\n"), strings.Split(to, "\n")
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	return collapseEqual(lines, context)
+}
+
+func diffMiddle(a, b []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: line})
+		}
+
+		for _, line := range b {
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: line})
+		}
+
+		return lines
+	}
+
+	// lcs[i][j] - длина общей подпоследовательности a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+
+	return lines
+}
+
+func collapseEqual(lines []DiffLine, context int) []DiffLine {
+	keep := make([]bool, len(lines))
+
+	for i, line := range lines {
+		if line.Op == DiffEqual {
+			continue
+		}
+
+		for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+			keep[k] = true
+		}
+	}
+
+	result := make([]DiffLine, 0, len(lines))
+
+	for i, line := range lines {
+		switch {
+		case keep[i]:
+			result = append(result, line)
+		case len(result) == 0 || result[len(result)-1].Op != DiffSkip:
+			result = append(result, DiffLine{Op: DiffSkip, Text: ""})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,43 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+
+	from := "a\nb\nc\nd\ne\nf"
+	to := "a\nb\nc\nD\ne\nf"
+
+	assert.Equal(t, []domain.DiffLine{
+		{Op: domain.DiffSkip, Text: ""},
+		{Op: domain.DiffEqual, Text: "c"},
+		{Op: domain.DiffDelete, Text: "d"},
+		{Op: domain.DiffInsert, Text: "D"},
+		{Op: domain.DiffEqual, Text: "e"},
+		{Op: domain.DiffSkip, Text: ""},
+	}, domain.DiffLines(from, to, 1))
+
+	assert.Equal(t, []domain.DiffLine{
+		{Op: domain.DiffEqual, Text: "x"},
+		{Op: domain.DiffInsert, Text: "y"},
+	}, domain.DiffLines("x", "x\ny", 3))
+
+	assert.Equal(t, []domain.DiffLine{{Op: domain.DiffSkip, Text: ""}}, domain.DiffLines("same", "same", 0))
+}
+
+func TestUpdatePostParamsDiff(t *testing.T) {
+	t.Parallel()
+
+	current := &domain.Post{Title: "Old", Slug: "post", CategoryID: 1, Content: []byte("text"), Version: 3}
+	params := &domain.UpdatePostParams{Title: "New", Slug: "post", CategoryID: 1, Content: []byte("text"), Version: 2}
+
+	changes := params.Diff(current)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, domain.FieldChange{Field: "title", Yours: "New", Current: "Old"}, changes[0])
+	}
+}
@@ -1,21 +1,36 @@
 package domain
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 )
 
+// RendererVersion растёт при каждом изменении MdToHTML, которое меняет
+// html постов: версия входит в ETag страниц, и браузеры не покажут
+// устаревшую вёрстку.
+const RendererVersion = 1
+
 type Post struct {
-	ID           int       `db:"id"`
-	Title        string    `db:"title"`
-	Description  string    `db:"description"`
-	Content      []byte    `db:"content"`
-	Extension    string    `db:"extension"`
-	IsPublished  bool      `db:"is_published"`
-	Slug         string    `db:"slug"`
-	CategoryID   int       `db:"category_id"`
-	CategoryName string    `db:"category_name"`
-	CreatedAt    time.Time `db:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"`
+	ID           int            `db:"id"`
+	Title        string         `db:"title"`
+	Description  string         `db:"description"`
+	Content      []byte         `db:"content"`
+	Extension    string         `db:"extension"`
+	IsPublished  bool           `db:"is_published"`
+	Slug         string         `db:"slug"`
+	CategoryID   int            `db:"category_id"`
+	CategoryName string         `db:"category_name"`
+	AuthorID     int            `db:"author_id"`   // 0 - автор удалён
+	AuthorName   string         `db:"author_name"` // отображаемое имя автора
+	Reactions    ReactionCounts `db:"reactions"`
+	CreatedAt    time.Time      `db:"created_at"`
+	UpdatedAt    time.Time      `db:"updated_at"`
+	Version      int            `db:"version"` // растёт при каждом изменении поста
 }
 
 type Category struct {
@@ -24,10 +39,13 @@ type Category struct {
 }
 
 type SelectPostsParams struct {
-	Limit      int
-	Offset     int
-	IsAdmin    bool
-	CategoryID int
+	Limit  int
+	Offset int
+	// IncludeUnpublished - показывать черновики, только для вошедших пользователей
+	IncludeUnpublished bool
+	// DraftsAuthorID - показывать черновики только этого автора, 0 - всех
+	DraftsAuthorID int
+	CategoryID     int
 }
 
 type CreatePostParams struct {
@@ -36,6 +54,7 @@ type CreatePostParams struct {
 	Description string
 	Filename    string
 	CategoryID  int
+	AuthorID    int
 	IsPublished bool
 	Content     []byte
 }
@@ -47,4 +66,75 @@ type UpdatePostParams struct {
 	Description string
 	CategoryID  int
 	Content     []byte
+	// Version - версия, с которой начиналась правка. Если пост с тех пор
+	// изменили, обновление вернёт *VersionConflictError. 0 - без проверки.
+	Version int
+}
+
+// PostStatus - фильтр по статусу публикации, пустой - все посты.
+type PostStatus string
+
+const (
+	PostStatusAny       PostStatus = ""
+	PostStatusPublished PostStatus = "published"
+	PostStatusDraft     PostStatus = "draft"
+)
+
+func (s PostStatus) Valid() bool {
+	return s == PostStatusAny || s == PostStatusPublished || s == PostStatusDraft
+}
+
+// PostsFilter - выборка постов для API с постраничным выводом по курсору.
+type PostsFilter struct {
+	Limit      int
+	CategoryID int // 0 - все категории
+	AuthorID   int // 0 - все авторы
+	Status     PostStatus
+	After      *PostsCursor // nil - с самого нового поста
+	// DraftsAuthorID - из черновиков показывать только посты этого автора, 0 - все
+	DraftsAuthorID int
+}
+
+// PostsCursor указывает на последний пост страницы. Посты идут от новых
+// к старым, поэтому следующая страница начинается с постов старше курсора.
+type PostsCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+var ErrInvalidCursor = fmt.Errorf("invalid cursor: %w", errs.ErrInvalid)
+
+func CursorOf(post *Post) *PostsCursor {
+	return &PostsCursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+// Encode упаковывает курсор в непрозрачную для клиента строку.
+func (c *PostsCursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + "." + strconv.Itoa(c.ID)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (*PostsCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	microsStr, idStr, found := strings.Cut(string(raw), ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	micros, err := strconv.ParseInt(microsStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &PostsCursor{CreatedAt: time.UnixMicro(micros), ID: id}, nil
 }
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrUnsupportedReactionsType = errors.New("unsupported reactions type")
+
+type Reaction struct {
+	Key   string
+	Emoji string
+}
+
+// Reactions - доступный набор реакций в порядке отображения.
+//
+//nolint:gochecknoglobals // fixed set of reactions
+var Reactions = []Reaction{
+	{Key: "like", Emoji: "👍"},
+	{Key: "love", Emoji: "❤️"},
+	{Key: "fire", Emoji: "🔥"},
+	{Key: "think", Emoji: "🤔"},
+	{Key: "clap", Emoji: "👏"},
+}
+
+func IsValidReaction(key string) bool {
+	for _, reaction := range Reactions {
+		if reaction.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReactionCounts - количество реакций каждого вида, хранится в jsonb колонке posts.reactions.
+type ReactionCounts map[string]int
+
+type ReactionCount struct {
+	Reaction
+	Count int
+}
+
+// List возвращает ненулевые счётчики в порядке Reactions.
+func (rc ReactionCounts) List() []ReactionCount {
+	list := make([]ReactionCount, 0, len(rc))
+
+	for _, reaction := range Reactions {
+		if count := rc[reaction.Key]; count > 0 {
+			list = append(list, ReactionCount{Reaction: reaction, Count: count})
+		}
+	}
+
+	return list
+}
+
+func (rc *ReactionCounts) Scan(src any) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case nil:
+		*rc = ReactionCounts{}
+
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedReactionsType, src)
+	}
+
+	counts := ReactionCounts{}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return fmt.Errorf("can't unmarshal reactions: %w", err)
+	}
+
+	*rc = counts
+
+	return nil
+}
+
+func (rc ReactionCounts) Value() (driver.Value, error) {
+	if rc == nil {
+		return []byte("{}"), nil
+	}
+
+	data, err := json.Marshal(map[string]int(rc))
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal reactions: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+const (
+	MaxTitleLen       = 200
+	MaxDescriptionLen = 500
+	MaxSlugLen        = 100 // posts.slug VARCHAR(100)
+)
+
+// Коды ошибок полей поста. Тексты для людей подбирают формы и клиенты API.
+const (
+	FieldRequired      = "required"
+	FieldTooLong       = "too_long"
+	FieldInvalidFormat = "invalid_format"
+	FieldInvalidUTF8   = "invalid_utf8"
+	FieldUnsupported   = "unsupported"
+	FieldNotFound      = "not_found"
+)
+
+//nolint:gochecknoglobals // post files the blog can render
+var AllowedExtensions = []string{".md", ".markdown"}
+
+// slugPattern - латинские буквы в нижнем регистре, цифры и одиночные дефисы:
+// такой slug вставляется в URL без экранирования.
+//
+//nolint:gochecknoglobals // compiled once
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidationError перечисляет поля поста, не прошедшие проверку:
+// имя поля - код ошибки.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field, code := range e.Fields {
+		fields = append(fields, field+": "+code)
+	}
+
+	sort.Strings(fields)
+
+	return fmt.Sprintf("invalid post (%s)", strings.Join(fields, ", "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return errs.ErrInvalid
+}
+
+// Validate проверяет поля, не требующие обращения к базе.
+// Slug должен быть уже построен из названия, если его не указали.
+func (p *CreatePostParams) Validate() map[string]string {
+	fields := make(map[string]string)
+
+	validateText(fields, "title", p.Title, MaxTitleLen, true)
+	validateText(fields, "description", p.Description, MaxDescriptionLen, false)
+	validateSlug(fields, p.Slug)
+	validateContent(fields, p.Content)
+
+	if !slices.Contains(AllowedExtensions, strings.ToLower(filepath.Ext(p.Filename))) {
+		fields["filename"] = FieldUnsupported
+	}
+
+	if p.CategoryID <= 0 {
+		fields["category_id"] = FieldRequired
+	}
+
+	return fields
+}
+
+// Validate проверяет поля, не требующие обращения к базе.
+func (p *UpdatePostParams) Validate() map[string]string {
+	fields := make(map[string]string)
+
+	validateText(fields, "title", p.Title, MaxTitleLen, true)
+	validateText(fields, "description", p.Description, MaxDescriptionLen, false)
+	validateSlug(fields, p.Slug)
+	validateContent(fields, p.Content)
+
+	if p.CategoryID <= 0 {
+		fields["category_id"] = FieldRequired
+	}
+
+	return fields
+}
+
+func validateText(fields map[string]string, field, value string, maxLen int, required bool) {
+	switch {
+	case !utf8.ValidString(value):
+		fields[field] = FieldInvalidUTF8
+	case required && strings.TrimSpace(value) == "":
+		fields[field] = FieldRequired
+	case utf8.RuneCountInString(value) > maxLen:
+		fields[field] = FieldTooLong
+	}
+}
+
+// SlugError возвращает код ошибки slug или пустую строку, если slug подходит.
+func SlugError(slug string) string {
+	fields := make(map[string]string, 1)
+	validateSlug(fields, slug)
+
+	return fields["slug"]
+}
+
+func validateSlug(fields map[string]string, slug string) {
+	switch {
+	case slug == "":
+		fields["slug"] = FieldRequired
+	case len(slug) > MaxSlugLen:
+		fields["slug"] = FieldTooLong
+	case !slugPattern.MatchString(slug):
+		fields["slug"] = FieldInvalidFormat
+	}
+}
+
+func validateContent(fields map[string]string, content []byte) {
+	switch {
+	case len(content) == 0:
+		fields["content"] = FieldRequired
+	case !utf8.Valid(content):
+		fields["content"] = FieldInvalidUTF8
+	}
+}
@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatePostParamsValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := domain.CreatePostParams{
+		Title:      "Пост",
+		Slug:       "post-2",
+		Filename:   "post.md",
+		CategoryID: 1,
+		Content:    []byte("# Пост"),
+	}
+
+	assert.Empty(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(p *domain.CreatePostParams)
+		field  string
+		code   string
+	}{
+		{"empty title", func(p *domain.CreatePostParams) { p.Title = "  " }, "title", domain.FieldRequired},
+		{"long title", func(p *domain.CreatePostParams) {
+			p.Title = strings.Repeat("я", domain.MaxTitleLen+1)
+		}, "title", domain.FieldTooLong},
+		{"long description", func(p *domain.CreatePostParams) {
+			p.Description = strings.Repeat("d", domain.MaxDescriptionLen+1)
+		}, "description", domain.FieldTooLong},
+		{"slug with slash", func(p *domain.CreatePostParams) { p.Slug = "a/b" }, "slug", domain.FieldInvalidFormat},
+		{"slug with space", func(p *domain.CreatePostParams) { p.Slug = "a b" }, "slug", domain.FieldInvalidFormat},
+		{"double hyphen", func(p *domain.CreatePostParams) { p.Slug = "a--b" }, "slug", domain.FieldInvalidFormat},
+		{"long slug", func(p *domain.CreatePostParams) {
+			p.Slug = strings.Repeat("s", domain.MaxSlugLen+1)
+		}, "slug", domain.FieldTooLong},
+		{"binary content", func(p *domain.CreatePostParams) { p.Content = []byte{0xff, 0xfe} }, "content", domain.FieldInvalidUTF8},
+		{"extension", func(p *domain.CreatePostParams) { p.Filename = "post.exe" }, "filename", domain.FieldUnsupported},
+		{"no category", func(p *domain.CreatePostParams) { p.CategoryID = 0 }, "category_id", domain.FieldRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			params := valid
+			tt.modify(&params)
+
+			assert.Equal(t, map[string]string{tt.field: tt.code}, params.Validate())
+		})
+	}
+}
+
+func TestUpdatePostParamsValidate(t *testing.T) {
+	t.Parallel()
+
+	params := domain.UpdatePostParams{Title: "Пост", Slug: "", CategoryID: 1, Content: []byte("text")}
+
+	fields := params.Validate()
+	assert.Equal(t, map[string]string{"slug": domain.FieldRequired}, fields)
+
+	err := &domain.ValidationError{Fields: fields}
+	assert.ErrorIs(t, err, errs.ErrInvalid)
+	assert.Equal(t, "invalid post (slug: required)", err.Error())
+
+	assert.Empty(t, domain.SlugError("post-2"))
+	assert.Equal(t, domain.FieldTooLong, domain.SlugError(strings.Repeat("s", domain.MaxSlugLen)+"-2"))
+	assert.Equal(t, domain.FieldInvalidFormat, domain.SlugError("-2"))
+}
@@ -1,18 +1,69 @@
 package blog
 
 import (
+	"fmt"
 	"log/slog"
 
+	"github.com/arevbond/arevbond-blog/internal/config"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
 	"github.com/arevbond/arevbond-blog/internal/service/blog/service"
 	"github.com/arevbond/arevbond-blog/internal/service/blog/service/processor"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/slug"
 	"github.com/arevbond/arevbond-blog/internal/service/blog/storage"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/vault"
 	"github.com/jmoiron/sqlx"
 )
 
-func NewBlogModule(log *slog.Logger, db *sqlx.DB) *service.Blog {
+// ImagesDir - директория картинок постов, её же раздаёт /images/.
+const ImagesDir = "images"
+
+func NewBlogModule(
+	log *slog.Logger, db *sqlx.DB, siteName string, avatar []byte, slugs config.Slugs, cache config.Cache,
+) (*service.Blog, error) {
 	postsRepo := storage.NewPostsRepo(log, db)
 	imageProcessor := processor.NewImageProcessor(log)
 	categoryRepo := storage.NewCategoriesRepo(log, db)
 
-	return service.New(log, postsRepo, imageProcessor, categoryRepo)
+	previewRenderer, err := processor.NewOGImageRenderer(log, siteName, avatar)
+	if err != nil {
+		return nil, fmt.Errorf("can't create preview renderer: %w", err)
+	}
+
+	slugGenerator, err := newSlugGenerator(slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	imagesDir := storage.NewImagesDir(ImagesDir)
+
+	blog := service.New(log, postsRepo, imageProcessor, categoryRepo, previewRenderer, slugGenerator, imagesDir)
+
+	return blog.WithCache(cache.MaxEntries, cache.TTL), nil
+}
+
+// NewVaultSyncer собирает синхронизацию заметок Obsidian с постами blog.
+func NewVaultSyncer(log *slog.Logger, db *sqlx.DB, blog *service.Blog, opts vault.Options) *vault.Syncer {
+	return vault.New(log, blog, storage.NewVaultNotesRepo(log, db), blog.Images, blog.ImageProcessor, opts)
+}
+
+func newSlugGenerator(cfg config.Slugs) (*slug.Generator, error) {
+	table, ok := slug.TableByName(cfg.Transliteration)
+	if !ok {
+		return nil, fmt.Errorf("unknown slug transliteration %q", cfg.Transliteration)
+	}
+
+	if cfg.MaxLength <= 0 || cfg.MaxLength > domain.MaxSlugLen {
+		return nil, fmt.Errorf("slug max length must be 1-%d, got %d", domain.MaxSlugLen, cfg.MaxLength)
+	}
+
+	stopWords := cfg.StopWords
+	if len(stopWords) == 0 {
+		stopWords = slug.DefaultStopWords
+	}
+
+	return slug.New(slug.Options{
+		Tables:    slug.WithFallbacks(table),
+		StopWords: stopWords,
+		MaxLen:    cfg.MaxLength,
+	}), nil
 }
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/archive"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+// Export пишет в w архив со всеми постами, категориями и картинками,
+// на которые ссылаются посты. Картинки, которых нет на диске, попадают
+// в отчёт и не прерывают выгрузку.
+func (b *Blog) Export(ctx context.Context, w io.Writer) (*domain.ExportReport, error) {
+	categories, err := b.CategoriesRepo.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get categories: %w", err)
+	}
+
+	names := make([]string, 0, len(categories))
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+
+	writer := archive.NewWriter(w, names)
+	report := &domain.ExportReport{Posts: 0, Categories: len(categories), Images: 0, MissingImages: nil}
+
+	var images []string
+
+	err = b.eachPost(ctx, func(post *domain.Post) error {
+		if err := writer.AddPost(archivePost(post)); err != nil {
+			return err //nolint:wrapcheck // archive errors name the file
+		}
+
+		report.Posts++
+
+		postImages, err := b.ImageProcessor.ImageNames(post.Content)
+		if err != nil {
+			return fmt.Errorf("can't find images of post %d: %w", post.ID, err)
+		}
+
+		for _, name := range postImages {
+			if !slices.Contains(images, name) {
+				images = append(images, name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = b.exportImages(writer, images, report); err != nil {
+		return nil, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err //nolint:wrapcheck // archive errors describe the step
+	}
+
+	return report, nil
+}
+
+func (b *Blog) exportImages(writer *archive.Writer, images []string, report *domain.ExportReport) error {
+	for _, name := range images {
+		data, err := b.Images.Read(name)
+
+		switch {
+		case errors.Is(err, errs.ErrNotFound), errors.Is(err, errs.ErrInvalid):
+			b.log.Warn("image referenced by post is missing", slog.String("image", name))
+			report.MissingImages = append(report.MissingImages, name)
+
+			continue
+		case err != nil:
+			return fmt.Errorf("can't export images: %w", err)
+		}
+
+		if err = writer.AddImage(name, data); err != nil {
+			return err //nolint:wrapcheck // archive errors name the file
+		}
+
+		report.Images++
+	}
+
+	return nil
+}
+
+// Import загружает архив, созданный Export. Посты сопоставляются по slug,
+// занятый slug разрешается по opts.Policy. Архив сначала проверяется
+// целиком: при ошибке в любом посте ничего не меняется.
+func (b *Blog) Import(
+	ctx context.Context, r io.ReaderAt, size int64, opts domain.ImportOptions,
+) (*domain.ImportReport, error) {
+	if !opts.Policy.Valid() {
+		return nil, fmt.Errorf("%w: unknown conflict policy %q", errs.ErrInvalid, opts.Policy)
+	}
+
+	contents, err := archive.Read(r, size)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // archive errors wrap errs.ErrInvalid
+	}
+
+	if err = validateArchive(contents); err != nil {
+		return nil, err
+	}
+
+	// посты пишутся напрямую в репозиторий, поэтому кэш сбрасывается целиком,
+	// в том числе после частичного импорта
+	if b.cache != nil {
+		defer b.cache.purge()
+	}
+
+	report := &domain.ImportReport{
+		Created:       nil,
+		Updated:       nil,
+		Renamed:       make(map[string]string),
+		Skipped:       nil,
+		Unchanged:     nil,
+		Categories:    nil,
+		Images:        nil,
+		SkippedImages: nil,
+	}
+
+	// картинки раньше постов, чтобы новые посты сразу показывались целиком
+	if err = b.importImages(contents.Images, opts.Policy, report); err != nil {
+		return report, err
+	}
+
+	categoryIDs, err := b.importCategories(ctx, contents, report)
+	if err != nil {
+		return report, err
+	}
+
+	for _, imported := range contents.Posts {
+		if err = b.importPost(ctx, imported, categoryIDs[imported.Category], opts, report); err != nil {
+			return report, fmt.Errorf("can't import post %s: %w", imported.Slug, err)
+		}
+	}
+
+	return report, nil
+}
+
+// validateArchive проверяет посты теми же правилами, что и при создании.
+func validateArchive(contents *archive.Contents) error {
+	slugs := make(map[string]struct{}, len(contents.Posts))
+
+	for _, post := range contents.Posts {
+		if _, duplicate := slugs[post.Slug]; duplicate {
+			return fmt.Errorf("%w: post %s is in archive twice", archive.ErrInvalid, post.Slug)
+		}
+
+		slugs[post.Slug] = struct{}{}
+
+		params := domain.CreatePostParams{
+			Title:       post.Title,
+			Slug:        post.Slug,
+			Description: post.Description,
+			Filename:    post.Slug + post.Extension,
+			CategoryID:  0,
+			AuthorID:    0,
+			IsPublished: post.Published,
+			Content:     post.Content,
+		}
+
+		fields := params.Validate()
+		// категория в архиве записана именем и проверяется ниже
+		delete(fields, "category_id")
+
+		if len(fields) > 0 {
+			return fmt.Errorf("post %s: %w", post.Slug, &domain.ValidationError{Fields: fields})
+		}
+
+		if _, err := categoryName(post.Category); err != nil {
+			return fmt.Errorf("post %s: %w", post.Slug, err)
+		}
+	}
+
+	for _, name := range contents.Manifest.Categories {
+		if _, err := categoryName(name); err != nil {
+			return fmt.Errorf("category %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Blog) importImages(images map[string][]byte, policy domain.ConflictPolicy, report *domain.ImportReport) error {
+	for _, name := range slices.Sorted(maps.Keys(images)) {
+		data := images[name]
+
+		existing, err := b.Images.Read(name)
+
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+		case err != nil:
+			return fmt.Errorf("can't check image %s: %w", name, err)
+		case bytes.Equal(existing, data):
+			continue
+		case policy != domain.ConflictOverwrite:
+			report.SkippedImages = append(report.SkippedImages, name)
+
+			continue
+		}
+
+		if err = b.Images.Write(name, data); err != nil {
+			return fmt.Errorf("can't import image %s: %w", name, err)
+		}
+
+		report.Images = append(report.Images, name)
+	}
+
+	return nil
+}
+
+// importCategories создаёт недостающие категории и возвращает id всех
+// категорий архива по имени.
+func (b *Blog) importCategories(
+	ctx context.Context, contents *archive.Contents, report *domain.ImportReport,
+) (map[string]int, error) {
+	existing, err := b.CategoriesRepo.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get categories: %w", err)
+	}
+
+	ids := make(map[string]int, len(existing))
+	for _, category := range existing {
+		ids[category.Name] = category.ID
+	}
+
+	names := slices.Clone(contents.Manifest.Categories)
+	for _, post := range contents.Posts {
+		names = append(names, post.Category)
+	}
+
+	for _, name := range names {
+		if _, ok := ids[name]; ok {
+			continue
+		}
+
+		category, createErr := b.CreateCategory(ctx, name)
+		if createErr != nil {
+			return nil, fmt.Errorf("can't import category %s: %w", name, createErr)
+		}
+
+		ids[name] = category.ID
+		report.Categories = append(report.Categories, name)
+	}
+
+	return ids, nil
+}
+
+func (b *Blog) importPost(
+	ctx context.Context, imported *archive.Post, categoryID int, opts domain.ImportOptions, report *domain.ImportReport,
+) error {
+	content, err := b.ImageProcessor.AddPrefix(imported.Content, "/images/")
+	if err != nil {
+		return fmt.Errorf("can't add prefix to image: %w", err)
+	}
+
+	imported.Content = content
+
+	existing, err := b.PostsRepo.FindBySlug(ctx, imported.Slug)
+
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		if err = b.createImported(ctx, imported, imported.Slug, categoryID, opts.AuthorID); err != nil {
+			return err
+		}
+
+		report.Created = append(report.Created, imported.Slug)
+
+		return nil
+	case err != nil:
+		return fmt.Errorf("can't find post: %w", err)
+	case samePost(existing, imported):
+		report.Unchanged = append(report.Unchanged, imported.Slug)
+
+		return nil
+	}
+
+	switch opts.Policy {
+	case domain.ConflictSkip:
+		report.Skipped = append(report.Skipped, imported.Slug)
+
+		return nil
+	case domain.ConflictOverwrite:
+		if err = b.overwritePost(ctx, existing, imported, categoryID); err != nil {
+			return err
+		}
+
+		report.Updated = append(report.Updated, imported.Slug)
+
+		return nil
+	case domain.ConflictRename:
+		return b.renamePost(ctx, imported, categoryID, opts.AuthorID, report)
+	}
+
+	return nil
+}
+
+// renamePost добавляет пост под свободным slug. Если пост уже был добавлен
+// прошлым импортом под одним из номерных slug, второй раз он не создаётся.
+func (b *Blog) renamePost(
+	ctx context.Context, imported *archive.Post, categoryID, authorID int, report *domain.ImportReport,
+) error {
+	candidates, err := b.slugCandidates(imported.Slug, 2)
+	if err != nil {
+		return err
+	}
+
+	taken, err := b.PostsRepo.TakenSlugs(ctx, candidates)
+	if err != nil {
+		return fmt.Errorf("can't check slugs: %w", err)
+	}
+
+	for _, candidate := range candidates {
+		if !slices.Contains(taken, candidate) {
+			if err = b.createImported(ctx, imported, candidate, categoryID, authorID); err != nil {
+				return err
+			}
+
+			report.Renamed[imported.Slug] = candidate
+
+			return nil
+		}
+
+		post, findErr := b.PostsRepo.FindBySlug(ctx, candidate)
+		if findErr != nil {
+			return fmt.Errorf("can't find post: %w", findErr)
+		}
+
+		if samePost(post, imported) {
+			report.Unchanged = append(report.Unchanged, imported.Slug)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("all %d variants of slug %s are taken: %w", maxSlugCandidates, imported.Slug, errs.ErrDuplicate)
+}
+
+func (b *Blog) createImported(
+	ctx context.Context, imported *archive.Post, slug string, categoryID, authorID int,
+) error {
+	post := &domain.Post{
+		ID:           0,
+		Title:        imported.Title,
+		Description:  imported.Description,
+		Content:      imported.Content,
+		Extension:    imported.Extension,
+		IsPublished:  imported.Published,
+		Slug:         slug,
+		CategoryID:   categoryID,
+		CategoryName: "",
+		AuthorID:     authorID,
+		AuthorName:   "",
+		Reactions:    nil,
+		CreatedAt:    imported.CreatedAt,
+		UpdatedAt:    imported.UpdatedAt,
+		Version:      0,
+	}
+
+	if err := b.PostsRepo.Create(ctx, post); err != nil {
+		return fmt.Errorf("can't create post: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Blog) overwritePost(ctx context.Context, existing *domain.Post, imported *archive.Post, categoryID int) error {
+	err := b.PostsRepo.Update(ctx, domain.UpdatePostParams{
+		ID:          existing.ID,
+		Title:       imported.Title,
+		Slug:        existing.Slug,
+		Description: imported.Description,
+		CategoryID:  categoryID,
+		Content:     imported.Content,
+		Version:     existing.Version,
+	})
+	if err != nil {
+		return fmt.Errorf("can't update post: %w", err)
+	}
+
+	if existing.IsPublished != imported.Published {
+		if err = b.PostsRepo.SetPublicationStatus(ctx, existing.ID, imported.Published); err != nil {
+			return fmt.Errorf("can't update post: %w", err)
+		}
+	}
+
+	b.previews.invalidateIfTitleChanged(existing.ID, imported.Title)
+
+	return nil
+}
+
+// samePost сравнивает то, что переносит архив. Даты не сравниваются:
+// правка поста в базе их всё равно меняет.
+func samePost(post *domain.Post, imported *archive.Post) bool {
+	return post.Title == imported.Title &&
+		post.Description == imported.Description &&
+		post.CategoryName == imported.Category &&
+		post.IsPublished == imported.Published &&
+		bytes.Equal(post.Content, imported.Content)
+}
+
+func archivePost(post *domain.Post) *archive.Post {
+	return &archive.Post{
+		Slug:        post.Slug,
+		Title:       post.Title,
+		Description: post.Description,
+		Category:    post.CategoryName,
+		Author:      post.AuthorName,
+		Published:   post.IsPublished,
+		Extension:   post.Extension,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		Content:     post.Content,
+	}
+}
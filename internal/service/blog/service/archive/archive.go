@@ -0,0 +1,356 @@
+// Package archive описывает переносимый архив блога: zip с markdown
+// файлами постов, картинками и manifest.json с контрольными суммами.
+//
+//	manifest.json
+//	posts/<slug>.md      front matter с метаданными и текст поста
+//	images/<name>        картинки, на которые ссылаются посты
+package archive
+
+import (
+	"archive/zip"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/frontmatter"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+const (
+	// FormatVersion растёт при несовместимых изменениях формата.
+	FormatVersion = 1
+
+	ManifestName = "manifest.json"
+	PostsDir     = "posts/"
+	ImagesDir    = "images/"
+
+	// MaxUnpackedSize ограничивает суммарный размер распакованных файлов.
+	MaxUnpackedSize = 256 << 20
+)
+
+var ErrInvalid = fmt.Errorf("invalid archive: %w", errs.ErrInvalid)
+
+type Manifest struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	Categories []string  `json:"categories"`
+	Files      []File    `json:"files"`
+}
+
+type File struct {
+	Path   string `json:"path"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Post - пост в архиве. Категория и автор записаны именами: id в другой
+// базе будут другими.
+type Post struct {
+	Slug        string
+	Title       string
+	Description string
+	Category    string
+	Author      string
+	Published   bool
+	Extension   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Content     []byte
+}
+
+// Contents - проверенное содержимое архива.
+type Contents struct {
+	Manifest Manifest
+	Posts    []*Post
+	Images   map[string][]byte
+}
+
+// Writer пишет архив. Файлы добавляются через AddPost и AddImage,
+// манифест записывается в Close.
+type Writer struct {
+	zip      *zip.Writer
+	manifest Manifest
+}
+
+func NewWriter(w io.Writer, categories []string) *Writer {
+	return &Writer{
+		zip: zip.NewWriter(w),
+		manifest: Manifest{
+			Version:    FormatVersion,
+			CreatedAt:  time.Now().UTC(),
+			Categories: categories,
+			Files:      nil,
+		},
+	}
+}
+
+func (w *Writer) AddPost(post *Post) error {
+	return w.add(PostsDir+post.Slug+".md", EncodePost(post))
+}
+
+func (w *Writer) AddImage(name string, data []byte) error {
+	return w.add(ImagesDir+name, data)
+}
+
+func (w *Writer) add(name string, data []byte) error {
+	file, err := w.zip.Create(name)
+	if err != nil {
+		return fmt.Errorf("can't add %s to archive: %w", name, err)
+	}
+
+	if _, err = file.Write(data); err != nil {
+		return fmt.Errorf("can't write %s to archive: %w", name, err)
+	}
+
+	w.manifest.Files = append(w.manifest.Files, File{Path: name, Size: len(data), SHA256: checksum(data)})
+
+	return nil
+}
+
+func (w *Writer) Close() error {
+	manifest, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode manifest: %w", err)
+	}
+
+	file, err := w.zip.Create(ManifestName)
+	if err != nil {
+		return fmt.Errorf("can't add manifest to archive: %w", err)
+	}
+
+	if _, err = file.Write(manifest); err != nil {
+		return fmt.Errorf("can't write manifest to archive: %w", err)
+	}
+
+	if err = w.zip.Close(); err != nil {
+		return fmt.Errorf("can't finish archive: %w", err)
+	}
+
+	return nil
+}
+
+// Read распаковывает архив и сверяет файлы с манифестом. Архив, в котором
+// не хватает файлов, есть лишние или не сходятся суммы, не принимается
+// целиком.
+func Read(r io.ReaderAt, size int64) (*Contents, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	files, err := unpack(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	rawManifest, ok := files[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s", ErrInvalid, ManifestName)
+	}
+
+	delete(files, ManifestName)
+
+	var manifest Manifest
+	if err = json.Unmarshal(rawManifest, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: can't decode manifest: %w", ErrInvalid, err)
+	}
+
+	if manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalid, manifest.Version)
+	}
+
+	if err = verify(manifest, files); err != nil {
+		return nil, err
+	}
+
+	contents := &Contents{Manifest: manifest, Posts: nil, Images: make(map[string][]byte)}
+
+	for _, file := range manifest.Files {
+		data := files[file.Path]
+
+		switch {
+		case strings.HasPrefix(file.Path, PostsDir):
+			post, decodeErr := DecodePost(data)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalid, file.Path, decodeErr)
+			}
+
+			contents.Posts = append(contents.Posts, post)
+		case strings.HasPrefix(file.Path, ImagesDir):
+			contents.Images[strings.TrimPrefix(file.Path, ImagesDir)] = data
+		}
+	}
+
+	return contents, nil
+}
+
+func unpack(reader *zip.Reader) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(reader.File))
+	total := 0
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		if !validPath(file.Name) {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalid, file.Name)
+		}
+
+		data, err := readFile(file, MaxUnpackedSize-total)
+		if err != nil {
+			return nil, err
+		}
+
+		total += len(data)
+		files[file.Name] = data
+	}
+
+	return files, nil
+}
+
+func readFile(file *zip.File, limit int) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't open %s: %w", ErrInvalid, file.Name, err)
+	}
+	defer rc.Close()
+
+	// размер в заголовке zip может врать, поэтому читаем с ограничением
+	data, err := io.ReadAll(io.LimitReader(rc, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: can't read %s: %w", ErrInvalid, file.Name, err)
+	}
+
+	if len(data) > limit {
+		return nil, fmt.Errorf("%w: unpacked size exceeds %d bytes", ErrInvalid, MaxUnpackedSize)
+	}
+
+	return data, nil
+}
+
+func verify(manifest Manifest, files map[string][]byte) error {
+	listed := make(map[string]struct{}, len(manifest.Files))
+
+	for _, file := range manifest.Files {
+		data, ok := files[file.Path]
+		if !ok {
+			return fmt.Errorf("%w: %s is missing", ErrInvalid, file.Path)
+		}
+
+		if len(data) != file.Size || checksum(data) != file.SHA256 {
+			return fmt.Errorf("%w: checksum mismatch for %s", ErrInvalid, file.Path)
+		}
+
+		listed[file.Path] = struct{}{}
+	}
+
+	for name := range files {
+		if _, ok := listed[name]; !ok {
+			return fmt.Errorf("%w: %s is not in manifest", ErrInvalid, name)
+		}
+	}
+
+	return nil
+}
+
+// validPath пропускает только манифест и файлы первого уровня в posts/
+// и images/, чтобы имена из архива нельзя было использовать как пути.
+func validPath(name string) bool {
+	if name == ManifestName {
+		return true
+	}
+
+	dir, base := path.Split(name)
+
+	return (dir == PostsDir || dir == ImagesDir) && ValidName(base)
+}
+
+// ValidName проверяет имя файла картинки или поста.
+func ValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`) &&
+		!strings.HasPrefix(name, ".")
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// EncodePost записывает метаданные поста в front matter перед текстом.
+func EncodePost(post *Post) []byte {
+	fields := []frontmatter.Field{
+		{Key: "title", Value: post.Title},
+		{Key: "slug", Value: post.Slug},
+		{Key: "description", Value: post.Description},
+		{Key: "category", Value: post.Category},
+		{Key: "author", Value: post.Author},
+		{Key: "published", Value: strconv.FormatBool(post.Published)},
+		{Key: "extension", Value: post.Extension},
+		{Key: "created_at", Value: post.CreatedAt.UTC().Format(time.RFC3339Nano)},
+		{Key: "updated_at", Value: post.UpdatedAt.UTC().Format(time.RFC3339Nano)},
+	}
+
+	return frontmatter.Join(fields, post.Content)
+}
+
+func DecodePost(data []byte) (*Post, error) {
+	fields, content, err := frontmatter.Split(data)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // frontmatter errors describe the line
+	}
+
+	for _, required := range []string{"title", "slug", "category", "created_at"} {
+		if fields[required] == "" {
+			return nil, fmt.Errorf("front matter has no %s", required)
+		}
+	}
+
+	post := &Post{
+		Slug:        fields["slug"],
+		Title:       fields["title"],
+		Description: fields["description"],
+		Category:    fields["category"],
+		Author:      fields["author"],
+		Published:   false,
+		Extension:   fields["extension"],
+		CreatedAt:   time.Time{},
+		UpdatedAt:   time.Time{},
+		Content:     content,
+	}
+
+	if post.Published, err = strconv.ParseBool(cmp.Or(fields["published"], "false")); err != nil {
+		return nil, fmt.Errorf("invalid published: %w", err)
+	}
+
+	if post.CreatedAt, err = time.Parse(time.RFC3339Nano, fields["created_at"]); err != nil {
+		return nil, fmt.Errorf("invalid created_at: %w", err)
+	}
+
+	post.UpdatedAt = post.CreatedAt
+	if updated := fields["updated_at"]; updated != "" {
+		if post.UpdatedAt, err = time.Parse(time.RFC3339Nano, updated); err != nil {
+			return nil, fmt.Errorf("invalid updated_at: %w", err)
+		}
+	}
+
+	if post.Extension == "" {
+		post.Extension = ".md"
+	}
+
+	if !slices.Contains(domain.AllowedExtensions, post.Extension) {
+		return nil, fmt.Errorf("unsupported extension %s", post.Extension)
+	}
+
+	return post, nil
+}
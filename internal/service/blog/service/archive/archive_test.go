@@ -0,0 +1,145 @@
+package archive_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/archive"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPost() *archive.Post {
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	return &archive.Post{
+		Slug:        "slajsy",
+		Title:       "Слайсы: как устроены",
+		Description: "Про \"len\" и cap",
+		Category:    "Go",
+		Author:      "Иван",
+		Published:   true,
+		Extension:   ".md",
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Hour),
+		Content:     []byte("# Слайсы\n\n![](/images/slice.png)\n"),
+	}
+}
+
+func writeArchive(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w := archive.NewWriter(&buf, []string{"Go", "Пустая"})
+	require.NoError(t, w.AddPost(testPost()))
+	require.NoError(t, w.AddImage("slice.png", []byte("png")))
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	data := writeArchive(t)
+
+	contents, err := archive.Read(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Go", "Пустая"}, contents.Manifest.Categories)
+	assert.Equal(t, []*archive.Post{testPost()}, contents.Posts)
+	assert.Equal(t, map[string][]byte{"slice.png": []byte("png")}, contents.Images)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	t.Parallel()
+
+	valid := writeArchive(t)
+
+	// переписывает архив, заменяя файлы из replace; nil удаляет файл
+	rewrite := func(t *testing.T, replace map[string][]byte) []byte {
+		t.Helper()
+
+		reader, err := zip.NewReader(bytes.NewReader(valid), int64(len(valid)))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		w := zip.NewWriter(&buf)
+
+		for _, file := range reader.File {
+			data, replaced := replace[file.Name]
+			if !replaced {
+				rc, openErr := file.Open()
+				require.NoError(t, openErr)
+
+				data, openErr = io.ReadAll(rc)
+				require.NoError(t, openErr)
+				rc.Close()
+			}
+
+			delete(replace, file.Name)
+
+			if data == nil {
+				continue
+			}
+
+			fw, createErr := w.Create(file.Name)
+			require.NoError(t, createErr)
+			_, _ = fw.Write(data)
+		}
+
+		for name, data := range replace {
+			fw, createErr := w.Create(name)
+			require.NoError(t, createErr)
+			_, _ = fw.Write(data)
+		}
+
+		require.NoError(t, w.Close())
+
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name    string
+		replace map[string][]byte
+	}{
+		{"changed image", map[string][]byte{"images/slice.png": []byte("gif")}},
+		{"missing image", map[string][]byte{"images/slice.png": nil}},
+		{"extra file", map[string][]byte{"images/extra.png": []byte("png")}},
+		{"path traversal", map[string][]byte{"images/../../etc/passwd": []byte("root")}},
+		{"no manifest", map[string][]byte{archive.ManifestName: nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := rewrite(t, tt.replace)
+
+			_, err := archive.Read(bytes.NewReader(data), int64(len(data)))
+			require.ErrorIs(t, err, archive.ErrInvalid)
+			assert.ErrorIs(t, err, errs.ErrInvalid)
+		})
+	}
+}
+
+func TestDecodePost(t *testing.T) {
+	t.Parallel()
+
+	post, err := archive.DecodePost([]byte("---\ntitle: Заметка\nslug: zametka\ncategory: Go\n" +
+		"created_at: 2025-03-01T10:00:00Z\n---\nтекст"))
+	require.NoError(t, err)
+	assert.False(t, post.Published)
+	assert.Equal(t, ".md", post.Extension)
+	assert.Equal(t, post.CreatedAt, post.UpdatedAt)
+	assert.Equal(t, "текст", string(post.Content))
+
+	_, err = archive.DecodePost([]byte("---\ntitle: Заметка\n---\nтекст"))
+	assert.Error(t, err)
+}
@@ -1,11 +1,16 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,50 +22,121 @@ import (
 )
 
 type PostRepository interface {
-	All(ctx context.Context, limit int, offset int, publishedOnly bool) ([]*domain.Post, error)
-	AllWithCategory(ctx context.Context, limit int, offset int, publishedOnly bool, categoryID int) ([]*domain.Post, error)
+	All(ctx context.Context, limit int, offset int, publishedOnly bool, draftsAuthorID int) ([]*domain.Post, error)
+	AllPublished(ctx context.Context) ([]*domain.Post, error)
+	AllWithCategory(
+		ctx context.Context, limit, offset int, publishedOnly bool, draftsAuthorID, categoryID int,
+	) ([]*domain.Post, error)
+	List(ctx context.Context, filter domain.PostsFilter) ([]*domain.Post, error)
 	Find(ctx context.Context, id int) (*domain.Post, error)
 	FindBySlug(ctx context.Context, slug string) (*domain.Post, error)
+	TakenSlugs(ctx context.Context, slugs []string) ([]string, error)
 	Create(ctx context.Context, post *domain.Post) error
 	Update(ctx context.Context, params domain.UpdatePostParams) error
 	Delete(ctx context.Context, id int) error
 
 	SetPublicationStatus(ctx context.Context, id int, isPublished bool) error
+	AddReaction(ctx context.Context, postID int, reaction string, delta int) (domain.ReactionCounts, error)
 }
 
 type CategoriesRepository interface {
 	All(ctx context.Context) ([]*domain.Category, error)
+	Find(ctx context.Context, id int) (*domain.Category, error)
+	Create(ctx context.Context, category *domain.Category) error
+	Rename(ctx context.Context, id int, name string) error
+	Delete(ctx context.Context, id int) error
+}
+
+// SlugGenerator строит адреса постов, см. пакет slug.
+type SlugGenerator interface {
+	Make(title string) string
+	Clean(slug string) string
+	WithSuffix(slug string, n int) string
 }
 
 type ImageProcessor interface {
 	AddPrefix(content []byte, prefix string) ([]byte, error)
+	ImageNames(content []byte) ([]string, error)
+}
+
+// ImageStore - файлы картинок, которые раздаются по /images/.
+type ImageStore interface {
+	Read(name string) ([]byte, error)
+	Write(name string, data []byte) error
 }
 
 type Blog struct {
-	log            *slog.Logger
-	PostsRepo      PostRepository
-	CategoriesRepo CategoriesRepository
-	ImageProcessor ImageProcessor
+	log             *slog.Logger
+	PostsRepo       PostRepository
+	CategoriesRepo  CategoriesRepository
+	ImageProcessor  ImageProcessor
+	PreviewRenderer PreviewRenderer
+	Slugs           SlugGenerator
+	Images          ImageStore
+
+	previews *previewCache
+	cache    *postsCache // nil - кэш выключен, см. WithCache
 }
 
-func New(log *slog.Logger, posts PostRepository, imgReplacer ImageProcessor, categoryRepo CategoriesRepository) *Blog {
-	return &Blog{log: log, PostsRepo: posts, ImageProcessor: imgReplacer, CategoriesRepo: categoryRepo}
+func New(
+	log *slog.Logger,
+	posts PostRepository,
+	imgReplacer ImageProcessor,
+	categoryRepo CategoriesRepository,
+	previewRenderer PreviewRenderer,
+	slugs SlugGenerator,
+	images ImageStore,
+) *Blog {
+	return &Blog{
+		log:             log,
+		PostsRepo:       posts,
+		ImageProcessor:  imgReplacer,
+		CategoriesRepo:  categoryRepo,
+		PreviewRenderer: previewRenderer,
+		Slugs:           slugs,
+		Images:          images,
+		previews:        newPreviewCache(maxPreviews),
+		cache:           nil,
+	}
 }
 
+// Posts возвращает страницу постов. Первые страницы списков для
+// читателей берутся из кэша.
 func (b *Blog) Posts(ctx context.Context, params domain.SelectPostsParams) ([]*domain.Post, error) {
-	publishedOnly := !params.IsAdmin
+	cache := b.cacheFor(ctx)
+	if cache == nil || params.Offset != 0 || params.IncludeUnpublished {
+		return b.selectPosts(ctx, params)
+	}
+
+	key := "posts:" + strconv.Itoa(params.CategoryID) + ":" + strconv.Itoa(params.Limit)
+
+	return cached(cache, key, func() ([]*domain.Post, cacheTags, bool, error) {
+		// загрузку разделяют все ждущие запросы, отмена первого не должна их ломать
+		posts, err := b.selectPosts(context.WithoutCancel(ctx), params)
+		if err != nil {
+			return nil, cacheTags{}, false, err
+		}
+
+		return posts, listingTags(params.CategoryID, posts), true, nil
+	})
+}
+
+func (b *Blog) selectPosts(ctx context.Context, params domain.SelectPostsParams) ([]*domain.Post, error) {
+	publishedOnly := !params.IncludeUnpublished
 
 	var posts []*domain.Post
 
 	var err error
 
 	if params.CategoryID == 0 {
-		posts, err = b.PostsRepo.All(ctx, params.Limit, params.Offset, publishedOnly)
+		posts, err = b.PostsRepo.All(ctx, params.Limit, params.Offset, publishedOnly, params.DraftsAuthorID)
 		if err != nil {
 			return nil, fmt.Errorf("can't process all posts in service: %w", err)
 		}
 	} else {
-		posts, err = b.PostsRepo.AllWithCategory(ctx, params.Limit, params.Offset, publishedOnly, params.CategoryID)
+		posts, err = b.PostsRepo.AllWithCategory(
+			ctx, params.Limit, params.Offset, publishedOnly, params.DraftsAuthorID, params.CategoryID,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("can't process all posts in service: %w", err)
 		}
@@ -69,6 +145,52 @@ func (b *Blog) Posts(ctx context.Context, params domain.SelectPostsParams) ([]*d
 	return posts, nil
 }
 
+const (
+	DefaultPostsPageSize = 20
+	MaxPostsPageSize     = 100
+)
+
+// ListPosts возвращает страницу постов и курсор следующей страницы
+// (nil, если страница последняя).
+func (b *Blog) ListPosts(ctx context.Context, filter domain.PostsFilter) ([]*domain.Post, *domain.PostsCursor, error) {
+	if !filter.Status.Valid() {
+		return nil, nil, fmt.Errorf("%w: unknown status %q", errs.ErrInvalid, filter.Status)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPostsPageSize
+	}
+
+	filter.Limit = min(filter.Limit, MaxPostsPageSize)
+	pageSize := filter.Limit
+
+	// лишний пост показывает, есть ли следующая страница
+	filter.Limit++
+
+	posts, err := b.PostsRepo.List(ctx, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't list posts: %w", err)
+	}
+
+	if len(posts) <= pageSize {
+		return posts, nil, nil
+	}
+
+	posts = posts[:pageSize]
+
+	return posts, domain.CursorOf(posts[len(posts)-1]), nil
+}
+
+// PublishedPosts возвращает все опубликованные посты без содержимого.
+func (b *Blog) PublishedPosts(ctx context.Context) ([]*domain.Post, error) {
+	posts, err := b.PostsRepo.AllPublished(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't process published posts in service: %w", err)
+	}
+
+	return posts, nil
+}
+
 func (b *Blog) Post(ctx context.Context, id int) (*domain.Post, error) {
 	post, err := b.PostsRepo.Find(ctx, id)
 	if err != nil {
@@ -78,7 +200,26 @@ func (b *Blog) Post(ctx context.Context, id int) (*domain.Post, error) {
 	return post, nil
 }
 
+// PostBySlug возвращает пост по адресу. Опубликованные посты кэшируются,
+// возвращённый пост нельзя изменять.
 func (b *Blog) PostBySlug(ctx context.Context, slug string) (*domain.Post, error) {
+	cache := b.cacheFor(ctx)
+	if cache == nil {
+		return b.findBySlug(ctx, slug)
+	}
+
+	return cached(cache, "post:"+slug, func() (*domain.Post, cacheTags, bool, error) {
+		post, err := b.findBySlug(context.WithoutCancel(ctx), slug)
+		if err != nil {
+			return nil, cacheTags{}, false, err
+		}
+
+		// скрытые посты видят только вошедшие пользователи, а они идут мимо кэша
+		return post, postTags(post), post.IsPublished, nil
+	})
+}
+
+func (b *Blog) findBySlug(ctx context.Context, slug string) (*domain.Post, error) {
 	post, err := b.PostsRepo.FindBySlug(ctx, slug)
 	if err != nil {
 		return nil, fmt.Errorf("can't process post by slug in service: %w", err)
@@ -88,136 +229,126 @@ func (b *Blog) PostBySlug(ctx context.Context, slug string) (*domain.Post, error
 }
 
 func (b *Blog) CreatePost(ctx context.Context, params domain.CreatePostParams) (*domain.Post, error) {
+	params.Title = strings.TrimSpace(params.Title)
 	if params.Title == "" {
 		params.Title = strings.TrimSuffix(params.Filename, filepath.Ext(params.Filename))
 	}
 
+	if slug := strings.TrimSpace(params.Slug); slug != "" {
+		params.Slug = b.Slugs.Clean(slug)
+	} else {
+		params.Slug = b.Slugs.Make(params.Title)
+	}
+
+	if err := b.validatePost(ctx, params.Validate(), params.CategoryID); err != nil {
+		return nil, err
+	}
+
 	contentWithCorrectImages, err := b.ImageProcessor.AddPrefix(params.Content, "/images/")
 	if err != nil {
 		return nil, fmt.Errorf("can't add prefix to image: %w", err)
 	}
 
-	var lastError error
+	slug, err := b.uniqueSlug(ctx, params.Slug)
+	if err != nil {
+		return nil, err
+	}
 
-	baseSlug := params.Title
-	if params.Slug != "" {
-		baseSlug = params.Slug
-	}
-
-	for i := 1; i <= 100; i++ {
-		post := &domain.Post{
-			ID:           0,
-			Title:        params.Title,
-			Description:  params.Description,
-			Content:      contentWithCorrectImages,
-			Extension:    filepath.Ext(params.Filename),
-			IsPublished:  params.IsPublished,
-			CategoryID:   params.CategoryID,
-			CategoryName: "", // не используется при создании нового поста
-			Slug:         b.covertTitleToSlug(baseSlug, i),
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		}
+	post := &domain.Post{
+		ID:           0,
+		Title:        params.Title,
+		Description:  params.Description,
+		Content:      contentWithCorrectImages,
+		Extension:    filepath.Ext(params.Filename),
+		IsPublished:  params.IsPublished,
+		CategoryID:   params.CategoryID,
+		CategoryName: "", // не используется при создании нового поста
+		AuthorID:     params.AuthorID,
+		AuthorName:   "",
+		Reactions:    nil,
+		Slug:         slug,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Version:      0,
+	}
 
-		lastError = b.PostsRepo.Create(ctx, post)
-		if nil == lastError {
-			return post, nil
-		}
+	// между проверкой и вставкой slug мог занять параллельный запрос,
+	// тогда Create вернёт errs.ErrDuplicate
+	if err = b.PostsRepo.Create(ctx, post); err != nil {
+		return nil, fmt.Errorf("can't create post: %w", err)
+	}
 
-		if !errors.Is(lastError, errs.ErrDuplicate) {
-			return nil, fmt.Errorf("can't create post: %w", lastError)
-		}
+	if b.cache != nil && post.IsPublished {
+		b.cache.invalidatePost(post.ID, post.CategoryID)
 	}
 
-	return nil, fmt.Errorf("can't create post: %w", lastError)
+	return post, nil
 }
 
-func (b *Blog) covertTitleToSlug(title string, num int) string {
-	lowerTitle := strings.ToLower(title)
-	strs := strings.Fields(b.removeSpecialChars(lowerTitle))
+// maxSlugCandidates - сколько номеров перебирается для занятого slug.
+const maxSlugCandidates = 100
 
-	enStrs := make([]string, len(strs))
-
-	for i := 0; i < len(enStrs); i++ {
-		enStrs[i] = b.ruToEn(strs[i])
+// uniqueSlug возвращает base или base с первым свободным номером.
+// Занятые варианты проверяются одним запросом.
+func (b *Blog) uniqueSlug(ctx context.Context, base string) (string, error) {
+	candidates, err := b.slugCandidates(base, 1)
+	if err != nil {
+		return "", err
 	}
 
-	slug := strings.Join(enStrs, "-")
-
-	if num > 1 {
-		slug = fmt.Sprintf("%s-%d", slug, num)
+	taken, err := b.PostsRepo.TakenSlugs(ctx, candidates)
+	if err != nil {
+		return "", fmt.Errorf("can't check slugs: %w", err)
 	}
 
-	return slug
-}
-
-func (b *Blog) ruToEn(str string) string {
-	rutoEnMp := map[string]string{
-		"а": "a",
-		"б": "b",
-		"в": "v",
-		"г": "g",
-		"д": "d",
-		"е": "e",
-		"ж": "zh",
-		"з": "z",
-		"и": "i",
-		"й": "i",
-		"к": "k",
-		"л": "l",
-		"м": "m",
-		"н": "n",
-		"о": "o",
-		"п": "p",
-		"р": "r",
-		"с": "s",
-		"т": "t",
-		"у": "u",
-		"ф": "f",
-		"х": "kh",
-		"ц": "ts",
-		"ч": "ch",
-		"ш": "sh",
-		"щ": "shch",
-		"ъ": "",
-		"ы": "y",
-		"ь": "",
-		"э": "e",
-		"ю": "iu",
-		"я": "ia",
-		"ё": "e",
-	}
-
-	var sb strings.Builder
-
-	for _, ch := range str {
-		if res, ok := rutoEnMp[string(ch)]; ok {
-			sb.WriteString(res)
-		} else {
-			sb.WriteRune(ch)
+	for _, candidate := range candidates {
+		if !slices.Contains(taken, candidate) {
+			return candidate, nil
 		}
 	}
 
-	return sb.String()
+	return "", fmt.Errorf("all %d variants of slug %s are taken: %w", maxSlugCandidates, base, errs.ErrDuplicate)
 }
 
-func (b *Blog) removeSpecialChars(str string) string {
-	specialChars := map[rune]struct{}{'.': {}, ',': {}, '|': {}, '!': {}, '?': {}, ':': {}, ';': {}, '"': {}, '\'': {}}
+// slugCandidates возвращает base с номерами от first до maxSlugCandidates.
+// Варианты, которые не проходят проверку slug, например длиннее колонки,
+// пропускаются; если не подходит ни один, это ошибка поля slug.
+func (b *Blog) slugCandidates(base string, first int) ([]string, error) {
+	candidates := make([]string, 0, maxSlugCandidates-first+1)
+	invalid := ""
 
-	var sb strings.Builder
+	for i := first; i <= maxSlugCandidates; i++ {
+		candidate := b.Slugs.WithSuffix(base, i)
+		if code := domain.SlugError(candidate); code != "" {
+			invalid = code
 
-	for _, ch := range str {
-		if _, ok := specialChars[ch]; ok {
 			continue
 		}
 
-		sb.WriteRune(ch)
+		candidates = append(candidates, candidate)
+	}
+
+	if len(candidates) == 0 {
+		return nil, &domain.ValidationError{Fields: map[string]string{"slug": invalid}}
 	}
 
-	return sb.String()
+	return candidates, nil
 }
 
 func (b *Blog) UpdatePost(ctx context.Context, params domain.UpdatePostParams) error {
+	params.Title = strings.TrimSpace(params.Title)
+
+	params.Slug = strings.TrimSpace(params.Slug)
+
+	// slug из формы приводится к виду, который выдаёт генератор, как при создании
+	if params.Slug != "" {
+		params.Slug = b.Slugs.Clean(params.Slug)
+	}
+
+	if err := b.validatePost(ctx, params.Validate(), params.CategoryID); err != nil {
+		return err
+	}
+
 	contentWithCorrectImages, err := b.ImageProcessor.AddPrefix(params.Content, "/images/")
 	if err != nil {
 		return fmt.Errorf("can't add prefix to image: %w", err)
@@ -226,14 +357,73 @@ func (b *Blog) UpdatePost(ctx context.Context, params domain.UpdatePostParams) e
 	params.Content = contentWithCorrectImages
 
 	err = b.PostsRepo.Update(ctx, params)
+	if errors.Is(err, errs.ErrConflict) {
+		return b.versionConflict(ctx, params)
+	}
+
 	if err != nil {
 		return fmt.Errorf("service: %w", err)
 	}
 
+	b.previews.invalidateIfTitleChanged(params.ID, params.Title)
+
+	if b.cache != nil {
+		// старый slug и старая категория попадут под записи с этим постом
+		b.cache.invalidatePost(params.ID, params.CategoryID)
+	}
+
+	return nil
+}
+
+// validatePost дополняет ошибки полей проверкой категории и возвращает
+// *domain.ValidationError, если хоть одно поле не прошло проверку.
+func (b *Blog) validatePost(ctx context.Context, fields map[string]string, categoryID int) error {
+	if _, checked := fields["category_id"]; !checked {
+		_, err := b.CategoriesRepo.Find(ctx, categoryID)
+
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			fields["category_id"] = domain.FieldNotFound
+		case err != nil:
+			return fmt.Errorf("can't check category: %w", err)
+		}
+	}
+
+	if len(fields) > 0 {
+		return &domain.ValidationError{Fields: fields}
+	}
+
 	return nil
 }
 
+// versionConflict описывает, чем правка расходится с постом,
+// который успели изменить после начала правки.
+func (b *Blog) versionConflict(ctx context.Context, params domain.UpdatePostParams) error {
+	current, err := b.PostsRepo.Find(ctx, params.ID)
+	if err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+
+	return &domain.VersionConflictError{Current: current, Changes: params.Diff(current)}
+}
+
+// MdToHTML рендерит markdown. С включённым кэшем результат запоминается
+// по хэшу текста, поэтому сбрасывать его при изменении поста не нужно.
 func (b *Blog) MdToHTML(md []byte) []byte {
+	if b.cache == nil {
+		return renderMarkdown(md)
+	}
+
+	sum := sha256.Sum256(md)
+
+	html, _ := cached(b.cache, "html:"+hex.EncodeToString(sum[:]), func() ([]byte, cacheTags, bool, error) {
+		return renderMarkdown(md), cacheTags{}, true, nil
+	})
+
+	return html
+}
+
+func renderMarkdown(md []byte) []byte {
 	// create markdown parser with extensions
 	extensions := parser.CommonExtensions | parser.AutoHeadingIDs | parser.NoEmptyLineBeforeBlock
 	p := parser.NewWithExtensions(extensions)
@@ -254,6 +444,12 @@ func (b *Blog) DeletePost(ctx context.Context, id int) error {
 		return fmt.Errorf("delete post: %w", err)
 	}
 
+	b.previews.delete(id)
+
+	if b.cache != nil {
+		b.cache.invalidatePost(id)
+	}
+
 	return nil
 }
 
@@ -263,9 +459,131 @@ func (b *Blog) ChangePublishStatus(ctx context.Context, id int, curPublishStatus
 		return fmt.Errorf("repository error: %w", err)
 	}
 
+	if b.cache != nil {
+		b.invalidatePublication(ctx, id, !curPublishStatus)
+	}
+
 	return nil
 }
 
+// invalidatePublication сбрасывает кэш после смены статуса: скрытый пост
+// уходит из записей, где он есть, опубликованный появляется в списках
+// своей категории.
+func (b *Blog) invalidatePublication(ctx context.Context, id int, published bool) {
+	if !published {
+		b.cache.invalidatePost(id)
+
+		return
+	}
+
+	post, err := b.PostsRepo.Find(ctx, id)
+	if err != nil {
+		b.log.Warn("can't find published post, dropping cached listings", slog.Int("id", id), slog.Any("error", err))
+		b.cache.purge()
+
+		return
+	}
+
+	b.cache.invalidatePost(id, post.CategoryID)
+}
+
+// RerenderPosts заново прогоняет содержимое всех постов через обработку
+// при сохранении (ссылки на картинки) и возвращает число изменённых постов.
+// Нужна после изменения правил обработки: старые посты их не видели.
+func (b *Blog) RerenderPosts(ctx context.Context) (int, error) {
+	changed := 0
+
+	err := b.eachPost(ctx, func(post *domain.Post) error {
+		content, err := b.ImageProcessor.AddPrefix(post.Content, "/images/")
+		if err != nil {
+			return fmt.Errorf("can't process post %d: %w", post.ID, err)
+		}
+
+		if bytes.Equal(content, post.Content) {
+			return nil
+		}
+
+		err = b.PostsRepo.Update(ctx, domain.UpdatePostParams{
+			ID:          post.ID,
+			Title:       post.Title,
+			Slug:        post.Slug,
+			Description: post.Description,
+			CategoryID:  post.CategoryID,
+			Content:     content,
+			Version:     post.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("can't save post %d: %w", post.ID, err)
+		}
+
+		changed++
+
+		return nil
+	})
+
+	if b.cache != nil && changed > 0 {
+		b.cache.purge()
+	}
+
+	return changed, err
+}
+
+// eachPost вызывает fn для каждого поста вместе с содержимым, от новых
+// к старым, и останавливается на первой ошибке.
+func (b *Blog) eachPost(ctx context.Context, fn func(post *domain.Post) error) error {
+	filter := domain.PostsFilter{
+		Limit: MaxPostsPageSize, CategoryID: 0, AuthorID: 0, Status: domain.PostStatusAny, After: nil, DraftsAuthorID: 0,
+	}
+
+	for {
+		page, next, err := b.ListPosts(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, listed := range page {
+			// в списке нет содержимого
+			post, err := b.PostsRepo.Find(ctx, listed.ID)
+			if err != nil {
+				return fmt.Errorf("can't get post %d: %w", listed.ID, err)
+			}
+
+			if err = fn(post); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		filter.After = next
+	}
+}
+
+// React добавляет (add = true) или снимает реакцию с поста.
+func (b *Blog) React(ctx context.Context, postID int, reaction string, add bool) (domain.ReactionCounts, error) {
+	if !domain.IsValidReaction(reaction) {
+		return nil, fmt.Errorf("%w: unknown reaction %q", errs.ErrInvalid, reaction)
+	}
+
+	delta := 1
+	if !add {
+		delta = -1
+	}
+
+	counts, err := b.PostsRepo.AddReaction(ctx, postID, reaction, delta)
+	if err != nil {
+		return nil, fmt.Errorf("can't react to post: %w", err)
+	}
+
+	if b.cache != nil {
+		b.cache.updateReactions(postID, counts)
+	}
+
+	return counts, nil
+}
+
 func (b *Blog) Categories(ctx context.Context) ([]*domain.Category, error) {
 	categories, err := b.CategoriesRepo.All(ctx)
 	if err != nil {
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"golang.org/x/sync/singleflight"
+)
+
+// postsCache - ограниченный LRU кэш того, что читают анонимные посетители:
+// постов по slug, первых страниц списка по категориям и html постов.
+// Параллельные промахи по одному ключу превращаются в один запрос к базе.
+//
+// Записи сбрасываются методами Blog, которые меняют посты, поэтому в
+// пределах процесса кэш не отстаёт от базы. Срок жизни ограничивает
+// отставание от изменений из других процессов, например команд CLI.
+type postsCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+	entries    map[string]*list.Element
+	order      *list.List // в начале - последние использованные
+	// generation растёт при каждом сбросе: загрузка, начатая до сброса,
+	// не сохраняет свой, возможно устаревший, результат
+	generation uint64
+	group      singleflight.Group
+	stats      domain.CacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	value   any
+	tags    cacheTags
+	expires time.Time
+}
+
+// cacheTags описывают, какие изменения делают запись устаревшей.
+type cacheTags struct {
+	postIDs    []int // посты в записи
+	categories []int // категории этих постов: в записи их названия
+	listing    bool
+	categoryID int // категория списка, 0 - все посты
+}
+
+func postTags(post *domain.Post) cacheTags {
+	return cacheTags{postIDs: []int{post.ID}, categories: []int{post.CategoryID}, listing: false, categoryID: 0}
+}
+
+func listingTags(categoryID int, posts []*domain.Post) cacheTags {
+	tags := cacheTags{postIDs: make([]int, 0, len(posts)), categories: nil, listing: true, categoryID: categoryID}
+
+	for _, post := range posts {
+		tags.postIDs = append(tags.postIDs, post.ID)
+		if !slices.Contains(tags.categories, post.CategoryID) {
+			tags.categories = append(tags.categories, post.CategoryID)
+		}
+	}
+
+	return tags
+}
+
+func newPostsCache(maxEntries int, ttl time.Duration) *postsCache {
+	return &postsCache{
+		mu:         sync.Mutex{},
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		generation: 0,
+		group:      singleflight.Group{},
+		stats:      domain.CacheStats{Hits: 0, Misses: 0, Evictions: 0, Entries: 0, MaxEntries: maxEntries},
+	}
+}
+
+// cached возвращает значение key из кэша или загружает его через fetch.
+// fetch возвращает вместе со значением признак, можно ли его кэшировать.
+func cached[T any](c *postsCache, key string, fetch func() (T, cacheTags, bool, error)) (T, error) {
+	if value, ok := c.get(key); ok {
+		return value.(T), nil //nolint:forcetypeassert // key prefix defines the type
+	}
+
+	value, err, _ := c.group.Do(key, func() (any, error) {
+		generation := c.currentGeneration()
+
+		value, tags, cacheable, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+
+		if cacheable {
+			c.set(key, value, tags, generation)
+		}
+
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+
+		return zero, err //nolint:wrapcheck // fetch errors are already wrapped
+	}
+
+	return value.(T), nil //nolint:forcetypeassert // returned by fetch
+}
+
+func (c *postsCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok {
+		entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert // only entries are stored
+		if c.now().Before(entry.expires) {
+			c.order.MoveToFront(elem)
+			c.stats.Hits++
+
+			return entry.value, true
+		}
+
+		c.remove(elem)
+		c.stats.Evictions++
+	}
+
+	c.stats.Misses++
+
+	return nil, false
+}
+
+func (c *postsCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+func (c *postsCache) set(key string, value any, tags cacheTags, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	entry := &cacheEntry{key: key, value: value, tags: tags, expires: c.now().Add(c.ttl)}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *postsCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry) //nolint:forcetypeassert // only entries are stored
+	delete(c.entries, entry.key)
+}
+
+// invalidate удаляет записи, для которых stale возвращает true.
+func (c *postsCache) invalidate(stale func(tags cacheTags) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+
+		if entry := elem.Value.(*cacheEntry); stale(entry.tags) { //nolint:forcetypeassert // only entries
+			c.remove(elem)
+		}
+
+		elem = next
+	}
+}
+
+// invalidatePost сбрасывает записи с постом и списки категорий categoryIDs
+// и всех постов: туда пост мог добавиться.
+func (c *postsCache) invalidatePost(postID int, categoryIDs ...int) {
+	c.invalidate(func(tags cacheTags) bool {
+		if slices.Contains(tags.postIDs, postID) {
+			return true
+		}
+
+		return tags.listing && len(categoryIDs) > 0 && (tags.categoryID == 0 || slices.Contains(categoryIDs, tags.categoryID))
+	})
+}
+
+// invalidateCategory сбрасывает список категории и записи с её названием.
+func (c *postsCache) invalidateCategory(categoryID int) {
+	c.invalidate(func(tags cacheTags) bool {
+		return (tags.listing && tags.categoryID == categoryID) || slices.Contains(tags.categories, categoryID)
+	})
+}
+
+// purge сбрасывает посты и списки. Html постов остаётся: его ключ - содержимое.
+func (c *postsCache) purge() {
+	c.invalidate(func(tags cacheTags) bool { return tags.listing || len(tags.postIDs) > 0 })
+}
+
+// updateReactions записывает новые счётчики реакций в закэшированные
+// посты: реакции меняются часто, и сброс на каждую отправлял бы читателей в базу.
+// Значения заменяются копиями, потому что старые могут читаться сейчас.
+func (c *postsCache) updateReactions(postID int, counts domain.ReactionCounts) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// загрузка, начатая раньше, могла прочитать старые счётчики
+	c.generation++
+
+	withCounts := func(post *domain.Post) *domain.Post {
+		updated := *post
+		updated.Reactions = counts
+
+		return &updated
+	}
+
+	for _, elem := range c.entries {
+		entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert // only entries are stored
+		if !slices.Contains(entry.tags.postIDs, postID) {
+			continue
+		}
+
+		switch value := entry.value.(type) {
+		case *domain.Post:
+			entry.value = withCounts(value)
+		case []*domain.Post:
+			posts := slices.Clone(value)
+			for i, post := range posts {
+				if post.ID == postID {
+					posts[i] = withCounts(post)
+				}
+			}
+
+			entry.value = posts
+		}
+	}
+}
+
+func (c *postsCache) snapshot() domain.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+
+	return stats
+}
+
+// cacheFor возвращает кэш для запроса или nil, если кэш выключен или
+// запрос помечен domain.SkipCache.
+func (b *Blog) cacheFor(ctx context.Context) *postsCache {
+	if b.cache == nil || domain.CacheSkipped(ctx) {
+		return nil
+	}
+
+	return b.cache
+}
+
+// WithCache включает кэш на maxEntries записей, которые живут не дольше ttl.
+// Без вызова или с maxEntries <= 0 все чтения идут в базу.
+func (b *Blog) WithCache(maxEntries int, ttl time.Duration) *Blog {
+	b.cache = nil
+	if maxEntries > 0 && ttl > 0 {
+		b.cache = newPostsCache(maxEntries, ttl)
+	}
+
+	return b
+}
+
+// CacheStats возвращает счётчики кэша, нули - если кэш выключен.
+func (b *Blog) CacheStats() domain.CacheStats {
+	if b.cache == nil {
+		return domain.CacheStats{Hits: 0, Misses: 0, Evictions: 0, Entries: 0, MaxEntries: 0}
+	}
+
+	return b.cache.snapshot()
+}
@@ -0,0 +1,301 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/processor"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/slug"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePostsRepo считает обращения к "базе".
+type fakePostsRepo struct {
+	service.PostRepository
+
+	mu      sync.Mutex
+	posts   map[int]*domain.Post
+	queries int
+	// block задерживает FindBySlug, чтобы собрать параллельные промахи
+	block chan struct{}
+}
+
+func (f *fakePostsRepo) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.queries
+}
+
+func (f *fakePostsRepo) FindBySlug(_ context.Context, slug string) (*domain.Post, error) {
+	if f.block != nil {
+		<-f.block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.queries++
+
+	for _, post := range f.posts {
+		if post.Slug == slug {
+			copied := *post
+
+			return &copied, nil
+		}
+	}
+
+	return nil, errs.ErrNotFound
+}
+
+func (f *fakePostsRepo) Find(_ context.Context, id int) (*domain.Post, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	post, ok := f.posts[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	copied := *post
+
+	return &copied, nil
+}
+
+func (f *fakePostsRepo) AllWithCategory(
+	_ context.Context, limit, offset int, publishedOnly bool, draftsAuthorID, categoryID int,
+) ([]*domain.Post, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.queries++
+
+	var posts []*domain.Post
+
+	for id := 1; id <= len(f.posts); id++ {
+		post := f.posts[id]
+		if post == nil || post.CategoryID != categoryID {
+			continue
+		}
+
+		draftVisible := !publishedOnly && (draftsAuthorID == 0 || post.AuthorID == draftsAuthorID)
+		if post.IsPublished || draftVisible {
+			copied := *post
+			posts = append(posts, &copied)
+		}
+	}
+
+	posts = posts[min(offset, len(posts)):]
+
+	return posts[:min(limit, len(posts))], nil
+}
+
+func (f *fakePostsRepo) Update(_ context.Context, params domain.UpdatePostParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	post := f.posts[params.ID]
+	post.Title, post.Slug, post.CategoryID, post.Content = params.Title, params.Slug, params.CategoryID, params.Content
+	post.Version++
+
+	return nil
+}
+
+func (f *fakePostsRepo) SetPublicationStatus(_ context.Context, id int, isPublished bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.posts[id].IsPublished = isPublished
+
+	return nil
+}
+
+func (f *fakePostsRepo) AddReaction(
+	_ context.Context, postID int, reaction string, delta int,
+) (domain.ReactionCounts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	post := f.posts[postID]
+	counts := domain.ReactionCounts{}
+
+	for key, count := range post.Reactions {
+		counts[key] = count
+	}
+
+	counts[reaction] += delta
+	post.Reactions = counts
+
+	return counts, nil
+}
+
+type fakeCategoriesRepo struct {
+	service.CategoriesRepository
+}
+
+func (fakeCategoriesRepo) Find(_ context.Context, id int) (*domain.Category, error) {
+	return &domain.Category{ID: id, Name: "go"}, nil
+}
+
+func newCachedBlog(t *testing.T, maxEntries int) (*service.Blog, *fakePostsRepo) {
+	t.Helper()
+
+	repo := &fakePostsRepo{
+		PostRepository: nil,
+		mu:             sync.Mutex{},
+		posts: map[int]*domain.Post{
+			1: {ID: 1, Title: "Первый", Slug: "first", CategoryID: 1, IsPublished: true, Version: 1},
+			2: {ID: 2, Title: "Второй", Slug: "second", CategoryID: 1, IsPublished: false, Version: 1},
+			3: {ID: 3, Title: "Третий", Slug: "third", CategoryID: 2, IsPublished: true, Version: 1},
+		},
+		queries: 0,
+		block:   nil,
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	blog := service.New(log, repo, processor.NewImageProcessor(log), fakeCategoriesRepo{CategoriesRepository: nil},
+		nil, slug.New(slug.Options{Tables: nil, StopWords: nil, MaxLen: domain.MaxSlugLen}), nil)
+
+	return blog.WithCache(maxEntries, time.Minute), repo
+}
+
+func TestCachePostBySlug(t *testing.T) {
+	t.Parallel()
+
+	blog, repo := newCachedBlog(t, 10)
+	ctx := t.Context()
+
+	for range 3 {
+		post, err := blog.PostBySlug(ctx, "first")
+		require.NoError(t, err)
+		assert.Equal(t, "Первый", post.Title)
+	}
+
+	assert.Equal(t, 1, repo.count())
+
+	stats := blog.CacheStats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 66, stats.HitRatio())
+
+	// вошедшие пользователи читают мимо кэша, скрытые посты не кэшируются
+	_, err := blog.PostBySlug(domain.SkipCache(ctx), "first")
+	require.NoError(t, err)
+
+	_, err = blog.PostBySlug(ctx, "second")
+	require.NoError(t, err)
+	_, err = blog.PostBySlug(ctx, "second")
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, repo.count())
+
+	// реакции записываются в кэш без запроса
+	_, err = blog.React(ctx, 1, domain.Reactions[0].Key, true)
+	require.NoError(t, err)
+
+	post, err := blog.PostBySlug(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, 1, post.Reactions[domain.Reactions[0].Key])
+	assert.Equal(t, 4, repo.count())
+
+	// правка сбрасывает пост, в том числе под старым slug; slug из формы чистится, как при создании
+	require.NoError(t, blog.UpdatePost(ctx, domain.UpdatePostParams{
+		ID: 1, Title: "Новый", Slug: " Renamed ", Description: "", CategoryID: 1, Content: []byte("текст"), Version: 1,
+	}))
+
+	_, err = blog.PostBySlug(ctx, "first")
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	post, err = blog.PostBySlug(ctx, "renamed")
+	require.NoError(t, err)
+	assert.Equal(t, "Новый", post.Title)
+}
+
+func TestCacheListings(t *testing.T) {
+	t.Parallel()
+
+	blog, repo := newCachedBlog(t, 10)
+	ctx := t.Context()
+
+	params := domain.SelectPostsParams{Limit: 6, Offset: 0, IncludeUnpublished: false, CategoryID: 1}
+
+	posts, err := blog.Posts(ctx, params)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+
+	other, err := blog.Posts(ctx, domain.SelectPostsParams{Limit: 6, Offset: 0, IncludeUnpublished: false, CategoryID: 2})
+	require.NoError(t, err)
+	require.Len(t, other, 1)
+
+	_, err = blog.Posts(ctx, params)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.count())
+
+	// дальние страницы и списки со скрытыми постами не кэшируются
+	_, err = blog.Posts(ctx, domain.SelectPostsParams{Limit: 6, Offset: 5, IncludeUnpublished: false, CategoryID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, repo.count())
+
+	// опубликованный пост появляется в списке своей категории, чужой список остаётся в кэше
+	require.NoError(t, blog.ChangePublishStatus(ctx, 2, false))
+
+	posts, err = blog.Posts(ctx, params)
+	require.NoError(t, err)
+	assert.Len(t, posts, 2)
+	assert.Equal(t, 4, repo.count())
+
+	_, err = blog.Posts(ctx, domain.SelectPostsParams{Limit: 6, Offset: 0, IncludeUnpublished: false, CategoryID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 4, repo.count())
+}
+
+func TestCacheSingleflightAndBound(t *testing.T) {
+	t.Parallel()
+
+	blog, repo := newCachedBlog(t, 2)
+	repo.block = make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for range 20 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := blog.PostBySlug(t.Context(), "first")
+			assert.NoError(t, err)
+		}()
+	}
+
+	// все запросы успевают дойти до ожидания общей загрузки
+	time.Sleep(50 * time.Millisecond)
+	close(repo.block)
+	wg.Wait()
+
+	assert.Equal(t, 1, repo.count())
+
+	for _, slug := range []string{"third", "first", "second"} {
+		_, err := blog.PostBySlug(t.Context(), slug)
+		require.NoError(t, err)
+	}
+
+	stats := blog.CacheStats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, 2, stats.MaxEntries)
+	assert.Equal(t, int64(0), stats.Evictions, "unpublished post is not stored")
+
+	_, err := blog.PostBySlug(domain.SkipCache(t.Context()), "first")
+	require.NoError(t, err)
+
+	blog.MdToHTML([]byte("# title"))
+	assert.Equal(t, int64(1), blog.CacheStats().Evictions)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+const MaxCategoryNameLen = 50
+
+var ErrInvalidCategory = fmt.Errorf("%w: category name must be 1-%d characters", errs.ErrInvalid, MaxCategoryNameLen)
+
+func (b *Blog) Category(ctx context.Context, id int) (*domain.Category, error) {
+	category, err := b.CategoriesRepo.Find(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("blog: %w", err)
+	}
+
+	return category, nil
+}
+
+func (b *Blog) CreateCategory(ctx context.Context, name string) (*domain.Category, error) {
+	name, err := categoryName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &domain.Category{ID: 0, Name: name}
+
+	if err = b.CategoriesRepo.Create(ctx, category); err != nil {
+		return nil, fmt.Errorf("blog: %w", err)
+	}
+
+	return category, nil
+}
+
+func (b *Blog) RenameCategory(ctx context.Context, id int, name string) error {
+	name, err := categoryName(name)
+	if err != nil {
+		return err
+	}
+
+	if err = b.CategoriesRepo.Rename(ctx, id, name); err != nil {
+		return fmt.Errorf("blog: %w", err)
+	}
+
+	if b.cache != nil {
+		b.cache.invalidateCategory(id)
+	}
+
+	return nil
+}
+
+// DeleteCategory удаляет категорию без постов, для категории с постами
+// возвращает errs.ErrConflict.
+func (b *Blog) DeleteCategory(ctx context.Context, id int) error {
+	if err := b.CategoriesRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("blog: %w", err)
+	}
+
+	if b.cache != nil {
+		b.cache.invalidateCategory(id)
+	}
+
+	return nil
+}
+
+func categoryName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxCategoryNameLen {
+		return "", ErrInvalidCategory
+	}
+
+	return name, nil
+}
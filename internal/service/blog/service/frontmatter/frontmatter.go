@@ -0,0 +1,180 @@
+// Package frontmatter читает и пишет front matter markdown файлов:
+// блок "ключ: значение" между строками "---" в начале файла.
+//
+// Поддерживается подмножество YAML, которого хватает для метаданных
+// поста: скалярные значения в одну строку, строки в двойных или
+// одинарных кавычках. Списки и вложенные ключи пропускаются.
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const delimiter = "---"
+
+// Field - пара ключ-значение. Порядок полей при записи сохраняется.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Split отделяет front matter от текста. Если блока нет, возвращает
+// пустые поля и исходный текст.
+func Split(content []byte) (map[string]string, []byte, error) {
+	fields := make(map[string]string)
+
+	rest, found := cutLine(content, delimiter)
+	if !found {
+		return fields, content, nil
+	}
+
+	for len(rest) > 0 {
+		var line []byte
+
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		line = bytes.TrimRight(line, "\r")
+
+		if string(line) == delimiter {
+			return fields, rest, nil
+		}
+
+		if err := parseLine(fields, string(line)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return nil, nil, fmt.Errorf("front matter is not closed with %q", delimiter)
+}
+
+// Join записывает поля в начало текста.
+func Join(fields []Field, body []byte) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(delimiter + "\n")
+
+	for _, field := range fields {
+		buf.WriteString(field.Key + ": " + quote(field.Value) + "\n")
+	}
+
+	buf.WriteString(delimiter + "\n")
+	buf.Write(body)
+
+	return buf.Bytes()
+}
+
+// cutLine отрезает первую строку, если она равна line.
+func cutLine(content []byte, line string) ([]byte, bool) {
+	first, rest, _ := bytes.Cut(content, []byte("\n"))
+	if string(bytes.TrimRight(first, "\r")) != line {
+		return content, false
+	}
+
+	return rest, true
+}
+
+func parseLine(fields map[string]string, line string) error {
+	trimmed := strings.TrimSpace(line)
+
+	// пустые строки, комментарии, элементы списков и вложенные ключи
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '-' || line[0] == ' ' || line[0] == '\t' {
+		return nil
+	}
+
+	key, value, found := strings.Cut(line, ":")
+	if !found {
+		return fmt.Errorf("front matter line %q has no key", line)
+	}
+
+	value, err := unquote(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("front matter key %s: %w", key, err)
+	}
+
+	fields[strings.TrimSpace(key)] = value
+
+	return nil
+}
+
+func unquote(value string) (string, error) {
+	if value == "" || (value[0] != '"' && value[0] != '\'') {
+		// комментарий после значения
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+
+		return value, nil
+	}
+
+	end := closingQuote(value)
+	if end < 0 {
+		return "", fmt.Errorf("invalid quoted string %s", value)
+	}
+
+	if rest := strings.TrimSpace(value[end+1:]); rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected %q after quoted string", rest)
+	}
+
+	quoted := value[:end+1]
+
+	if quoted[0] == '\'' {
+		return strings.ReplaceAll(quoted[1:end], "''", "'"), nil
+	}
+
+	var unquoted string
+	if err := json.Unmarshal([]byte(quoted), &unquoted); err != nil {
+		return "", fmt.Errorf("invalid quoted string %s", quoted)
+	}
+
+	return unquoted, nil
+}
+
+// closingQuote возвращает индекс кавычки, закрывающей строку, или -1.
+// В двойных кавычках экранирует обратный слеш, в одинарных - удвоение.
+func closingQuote(value string) int {
+	quote := value[0]
+
+	for i := 1; i < len(value); i++ {
+		switch {
+		case quote == '"' && value[i] == '\\':
+			i++
+		case value[i] != quote:
+		case quote == '\'' && i+1 < len(value) && value[i+1] == '\'':
+			i++
+		default:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// quote оставляет простые значения как есть, остальные пишет в двойных
+// кавычках: экранирование JSON совместимо с YAML.
+func quote(value string) string {
+	if !needsQuotes(value) {
+		return value
+	}
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value) // строку закодировать можно всегда
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func needsQuotes(value string) bool {
+	if value == "" || strings.TrimSpace(value) != value {
+		return true
+	}
+
+	if strings.ContainsAny(value[:1], `-?:,[]{}#&*!|>'"%@`+"`") {
+		return true
+	}
+
+	return strings.ContainsAny(value, "\"'#\n\r\t\\") || strings.Contains(value, ": ") || strings.HasSuffix(value, ":")
+}
@@ -0,0 +1,86 @@
+package frontmatter_test
+
+import (
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/frontmatter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinSplit(t *testing.T) {
+	t.Parallel()
+
+	fields := []frontmatter.Field{
+		{Key: "title", Value: "Слайсы: как устроены"},
+		{Key: "slug", Value: "slajsy"},
+		{Key: "description", Value: `"кавычки" и # решётка`},
+		{Key: "empty", Value: ""},
+		{Key: "published", Value: "true"},
+		{Key: "created_at", Value: "2025-01-02T03:04:05Z"},
+	}
+	body := []byte("# Заголовок\n\n---\nтекст\n")
+
+	content := frontmatter.Join(fields, body)
+
+	got, gotBody, err := frontmatter.Split(content)
+	require.NoError(t, err)
+	assert.Equal(t, body, gotBody)
+
+	for _, field := range fields {
+		assert.Equal(t, field.Value, got[field.Key], field.Key)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		body    string
+		wantErr bool
+	}{
+		{
+			name:    "no front matter",
+			content: "# Заметка\n",
+			want:    map[string]string{},
+			body:    "# Заметка\n",
+		},
+		{
+			name: "obsidian note",
+			content: "---\r\nid: 42\r\ntitle: 'It''s here' # comment\r\ntags:\r\n  - go\r\n  - db\r\n" +
+				"aliases: [a, b]\r\n---\r\nbody",
+			want: map[string]string{"id": "42", "title": "It's here", "tags": "", "aliases": "[a, b]"},
+			body: "body",
+		},
+		{
+			name:    "not closed",
+			content: "---\ntitle: x\n",
+			wantErr: true,
+		},
+		{
+			name:    "broken quotes",
+			content: "---\ntitle: \"x\n---\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fields, body, err := frontmatter.Split([]byte(tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, fields)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+)
+
+type PreviewRenderer interface {
+	Render(title, categoryName string) ([]byte, error)
+}
+
+type previewEntry struct {
+	postID       int
+	title        string
+	categoryName string
+	image        []byte
+}
+
+// maxPreviews - сколько превью держится в памяти. Превью занимает десятки
+// килобайт, а нужно в основном для свежих постов, которыми делятся.
+const maxPreviews = 200
+
+// previewCache хранит сгенерированные og-изображения по id поста.
+// Когда превью больше maxEntries, вытесняются давно запрошенные.
+type previewCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[int]*list.Element
+	order      *list.List // в начале - последние использованные
+}
+
+func newPreviewCache(maxEntries int) *previewCache {
+	return &previewCache{
+		mu:         sync.Mutex{},
+		maxEntries: maxEntries,
+		entries:    make(map[int]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *previewCache) get(post *domain.Post) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[post.ID]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*previewEntry) //nolint:forcetypeassert // only entries are stored
+	if entry.title != post.Title || entry.categoryName != post.CategoryName {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return entry.image, true
+}
+
+func (c *previewCache) set(post *domain.Post, img []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(post.ID)
+
+	entry := &previewEntry{postID: post.ID, title: post.Title, categoryName: post.CategoryName, image: img}
+	c.entries[post.ID] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back().Value.(*previewEntry) //nolint:forcetypeassert // only entries are stored
+		c.remove(oldest.postID)
+	}
+}
+
+// invalidateIfTitleChanged удаляет превью, если заголовок поста изменился.
+func (c *previewCache) invalidateIfTitleChanged(postID int, title string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[postID]; ok && elem.Value.(*previewEntry).title != title { //nolint:forcetypeassert // entries
+		c.remove(postID)
+	}
+}
+
+func (c *previewCache) delete(postID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(postID)
+}
+
+func (c *previewCache) remove(postID int) {
+	if elem, ok := c.entries[postID]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, postID)
+	}
+}
+
+// PostPreviewImage возвращает PNG превью поста для og:image.
+func (b *Blog) PostPreviewImage(_ context.Context, post *domain.Post) ([]byte, error) {
+	if img, ok := b.previews.get(post); ok {
+		return img, nil
+	}
+
+	img, err := b.PreviewRenderer.Render(post.Title, post.CategoryName)
+	if err != nil {
+		return nil, fmt.Errorf("can't render preview image: %w", err)
+	}
+
+	b.previews.set(post, img)
+
+	return img, nil
+}
@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingRenderer struct {
+	renders int
+}
+
+func (r *countingRenderer) Render(title, _ string) ([]byte, error) {
+	r.renders++
+
+	return []byte(title), nil
+}
+
+func TestPostPreviewImageCacheIsBounded(t *testing.T) {
+	t.Parallel()
+
+	renderer := &countingRenderer{renders: 0}
+	blog := service.New(slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil, renderer, nil, nil)
+
+	preview := func(id int) {
+		t.Helper()
+
+		img, err := blog.PostPreviewImage(t.Context(), &domain.Post{ID: id, Title: "Пост"})
+		require.NoError(t, err)
+		assert.Equal(t, []byte("Пост"), img)
+	}
+
+	preview(1)
+	preview(1)
+	assert.Equal(t, 1, renderer.renders)
+
+	// тысяча постов не остаётся в памяти: первый вытеснен и рендерится заново
+	for id := 2; id <= 1000; id++ {
+		preview(id)
+	}
+
+	preview(1000)
+	assert.Equal(t, 1000, renderer.renders)
+
+	preview(1)
+	assert.Equal(t, 1001, renderer.renders)
+}
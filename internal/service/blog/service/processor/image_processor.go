@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"regexp"
+	"slices"
 )
 
 type ImageProcessor struct {
@@ -14,10 +15,12 @@ func NewImageProcessor(log *slog.Logger) *ImageProcessor {
 	return &ImageProcessor{log: log}
 }
 
-func (ir *ImageProcessor) AddPrefix(content []byte, prefix string) ([]byte, error) {
-	pattern := `\!\[(?:([^\]]*)\]\(.*?([^/\)]+)\)|\[([^\]]+)\]\])`
+// imagePattern находит картинки в markdown ![alt](path/file) и в формате
+// вики ![[file]].
+const imagePattern = `\!\[(?:([^\]]*)\]\(.*?([^/\)]+)\)|\[([^\]]+)\]\])`
 
-	mdImage, err := regexp.Compile(pattern)
+func (ir *ImageProcessor) AddPrefix(content []byte, prefix string) ([]byte, error) {
+	mdImage, err := regexp.Compile(imagePattern)
 	if err != nil {
 		return nil, fmt.Errorf("can't parse pattern: %w", err)
 	}
@@ -47,3 +50,27 @@ func (ir *ImageProcessor) AddPrefix(content []byte, prefix string) ([]byte, erro
 
 	return []byte(result), nil
 }
+
+// ImageNames возвращает имена файлов картинок, на которые ссылается
+// текст, без повторов и в порядке появления.
+func (ir *ImageProcessor) ImageNames(content []byte) ([]string, error) {
+	mdImage, err := regexp.Compile(imagePattern)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse pattern: %w", err)
+	}
+
+	var names []string
+
+	for _, submatches := range mdImage.FindAllStringSubmatch(string(content), -1) {
+		name := submatches[2]
+		if name == "" {
+			name = submatches[3]
+		}
+
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
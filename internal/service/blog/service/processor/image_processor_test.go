@@ -0,0 +1,21 @@
+package processor_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageProcessor_ImageNames(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("![схема](/images/scheme.png)\n![[photo 1.jpg]]\n" +
+		"[ссылка](/blog/posts/x)\n![](attachments/scheme.png)\n")
+
+	names, err := processor.NewImageProcessor(slog.Default()).ImageNames(content)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"scheme.png", "photo 1.jpg"}, names)
+}
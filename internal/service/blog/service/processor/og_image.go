@@ -0,0 +1,245 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"strings"
+	"sync"
+
+	// register jpeg decoder for avatar.
+	_ "image/jpeg"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	OGImageWidth  = 1200
+	OGImageHeight = 630
+
+	ogPadding       = 80
+	ogAvatarSize    = 160
+	ogAccentWidth   = 16
+	ogTitleSize     = 64
+	ogTitleLineGap  = 16
+	ogTitleMaxLines = 4
+	ogCaptionSize   = 32
+	ogFontDPI       = 72
+)
+
+//nolint:gochecknoglobals // palette of preview image
+var (
+	ogBackground = color.RGBA{R: 0xf8, G: 0xf9, B: 0xfa, A: 0xff}
+	ogAccent     = color.RGBA{R: 0x0d, G: 0x6e, B: 0xfd, A: 0xff}
+	ogText       = color.RGBA{R: 0x21, G: 0x25, B: 0x29, A: 0xff}
+	ogMuted      = color.RGBA{R: 0x6c, G: 0x75, B: 0x7d, A: 0xff}
+)
+
+// OGImageRenderer рисует превью поста для социальных сетей (og:image).
+// Шрифты Go встроены в бинарник и содержат кириллицу.
+type OGImageRenderer struct {
+	log      *slog.Logger
+	siteName string
+	avatar   image.Image
+
+	// font.Face не безопасен для конкурентного использования
+	mu          sync.Mutex
+	titleFace   font.Face
+	captionFace font.Face
+}
+
+func NewOGImageRenderer(log *slog.Logger, siteName string, avatar []byte) (*OGImageRenderer, error) {
+	titleFace, err := newFace(gobold.TTF, ogTitleSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't load title font: %w", err)
+	}
+
+	captionFace, err := newFace(goregular.TTF, ogCaptionSize)
+	if err != nil {
+		return nil, fmt.Errorf("can't load caption font: %w", err)
+	}
+
+	var avatarImg image.Image
+
+	if len(avatar) > 0 {
+		avatarImg, err = circleAvatar(avatar, ogAvatarSize)
+		if err != nil {
+			return nil, fmt.Errorf("can't prepare avatar: %w", err)
+		}
+	}
+
+	return &OGImageRenderer{
+		log:         log,
+		mu:          sync.Mutex{},
+		siteName:    siteName,
+		avatar:      avatarImg,
+		titleFace:   titleFace,
+		captionFace: captionFace,
+	}, nil
+}
+
+// Render возвращает PNG размером OGImageWidth x OGImageHeight.
+func (r *OGImageRenderer) Render(title, categoryName string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, OGImageWidth, OGImageHeight))
+
+	draw.Draw(img, img.Bounds(), image.NewUniform(ogBackground), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, ogAccentWidth, OGImageHeight), image.NewUniform(ogAccent), image.Point{}, draw.Src)
+
+	textWidth := OGImageWidth - 2*ogPadding
+
+	if r.avatar != nil {
+		avatarPos := image.Pt(OGImageWidth-ogPadding-ogAvatarSize, ogPadding)
+		draw.Draw(img, r.avatar.Bounds().Add(avatarPos), r.avatar, image.Point{}, draw.Over)
+
+		textWidth -= ogAvatarSize + ogPadding/2
+	}
+
+	captionHeight := r.captionFace.Metrics().Height.Ceil()
+
+	if categoryName != "" {
+		r.drawString(img, r.captionFace, ogMuted, categoryName, ogPadding, ogPadding+captionHeight)
+	}
+
+	titleHeight := r.titleFace.Metrics().Height.Ceil()
+	y := ogPadding + 2*captionHeight + titleHeight
+
+	for _, line := range wrapText(r.titleFace, title, textWidth, ogTitleMaxLines) {
+		r.drawString(img, r.titleFace, ogText, line, ogPadding, y)
+		y += titleHeight + ogTitleLineGap
+	}
+
+	r.drawString(img, r.captionFace, ogAccent, r.siteName, ogPadding, OGImageHeight-ogPadding)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("can't encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (r *OGImageRenderer) drawString(dst draw.Image, face font.Face, clr color.Color, text string, x, y int) {
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(clr),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+
+	drawer.DrawString(text)
+}
+
+// wrapText разбивает текст на строки, которые помещаются в maxWidth пикселей.
+// Если строк больше maxLines, последняя обрезается многоточием.
+func wrapText(face font.Face, text string, maxWidth int, maxLines int) []string {
+	limit := fixed.I(maxWidth)
+	words := strings.Fields(text)
+	lines := make([]string, 0, maxLines)
+
+	var cur string
+
+	for _, word := range words {
+		candidate := word
+		if cur != "" {
+			candidate = cur + " " + word
+		}
+
+		if cur != "" && font.MeasureString(face, candidate) > limit {
+			lines = append(lines, cur)
+			cur = word
+		} else {
+			cur = candidate
+		}
+	}
+
+	if cur != "" {
+		lines = append(lines, cur)
+	}
+
+	if len(lines) <= maxLines {
+		return lines
+	}
+
+	lines = lines[:maxLines]
+	last := []rune(lines[maxLines-1])
+
+	for len(last) > 0 && font.MeasureString(face, string(last)+"…") > limit {
+		last = last[:len(last)-1]
+	}
+
+	lines[maxLines-1] = strings.TrimSpace(string(last)) + "…"
+
+	return lines
+}
+
+func newFace(ttf []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse font: %w", err)
+	}
+
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     ogFontDPI,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't create font face: %w", err)
+	}
+
+	return face, nil
+}
+
+// circleAvatar масштабирует изображение до size x size и обрезает его по кругу.
+func circleAvatar(data []byte, size int) (image.Image, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode image: %w", err)
+	}
+
+	// обрезаем до квадрата по центру
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	square := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	scaled := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, square, draw.Src, nil)
+
+	result := image.NewRGBA(scaled.Bounds())
+	draw.DrawMask(result, result.Bounds(), scaled, image.Point{}, circleMask{size: size}, image.Point{}, draw.Over)
+
+	return result, nil
+}
+
+type circleMask struct {
+	size int
+}
+
+func (c circleMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (c circleMask) Bounds() image.Rectangle { return image.Rect(0, 0, c.size, c.size) }
+
+func (c circleMask) At(x, y int) color.Color {
+	r := float64(c.size) / 2 //nolint: mnd // radius
+	dx, dy := float64(x)+0.5-r, float64(y)+0.5-r
+
+	if dx*dx+dy*dy <= r*r {
+		return color.Alpha{A: 0xff}
+	}
+
+	return color.Alpha{A: 0}
+}
@@ -0,0 +1,49 @@
+package processor_test
+
+import (
+	"bytes"
+	"image/png"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOGImageRenderer_Render(t *testing.T) {
+	t.Parallel()
+
+	avatar, err := os.ReadFile("../../../../server/views/static/profile.jpg")
+	require.NoError(t, err)
+
+	renderer, err := processor.NewOGImageRenderer(slog.Default(), "arevbond", avatar)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		title        string
+		categoryName string
+	}{
+		{name: "cyrillic title", title: "Слайсы в Go", categoryName: "Технологии"},
+		{name: "long title", title: strings.Repeat("Очень длинный заголовок ", 20), categoryName: ""},
+		{name: "empty title", title: "", categoryName: "Разное"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := renderer.Render(tt.title, tt.categoryName)
+			require.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			assert.Equal(t, processor.OGImageWidth, img.Bounds().Dx())
+			assert.Equal(t, processor.OGImageHeight, img.Bounds().Dy())
+		})
+	}
+}
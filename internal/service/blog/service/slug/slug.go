@@ -0,0 +1,242 @@
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const DefaultMaxLen = 80
+
+// DefaultStopWords - служебные слова, которые не несут смысла в адресе.
+//
+//nolint:gochecknoglobals // default settings
+var DefaultStopWords = []string{
+	"а", "в", "во", "для", "за", "и", "из", "к", "ко", "на", "не", "но", "о", "об", "от", "по", "с", "со", "у",
+	"a", "an", "and", "in", "of", "on", "or", "the", "to",
+}
+
+type Options struct {
+	// Tables - таблицы транслитерации по убыванию приоритета. Буква
+	// берётся из первой таблицы, где она есть.
+	Tables []*Table
+	// StopWords выбрасываются из названия, если после них что-то остаётся.
+	StopWords []string
+	// MaxLen - максимальная длина slug, обрезается по границе слова.
+	MaxLen int
+}
+
+// Generator строит из названий slug: латиница в нижнем регистре,
+// цифры и одиночные дефисы.
+type Generator struct {
+	tables    []*Table
+	stopWords map[string]struct{}
+	maxLen    int
+}
+
+func New(opts Options) *Generator {
+	g := &Generator{
+		tables:    opts.Tables,
+		stopWords: make(map[string]struct{}, len(opts.StopWords)),
+		maxLen:    opts.MaxLen,
+	}
+
+	if len(g.tables) == 0 {
+		g.tables = Tables
+	}
+
+	for _, word := range opts.StopWords {
+		g.stopWords[normalize(word)] = struct{}{}
+	}
+
+	if g.maxLen <= 0 {
+		g.maxLen = DefaultMaxLen
+	}
+
+	return g
+}
+
+// WithFallbacks дополняет основную таблицу остальными встроенными,
+// чтобы буквы соседних алфавитов не терялись.
+func WithFallbacks(primary *Table) []*Table {
+	tables := []*Table{primary}
+
+	for _, table := range Tables {
+		if table != primary {
+			tables = append(tables, table)
+		}
+	}
+
+	return tables
+}
+
+// Make строит slug из названия поста без служебных слов.
+func (g *Generator) Make(title string) string {
+	return g.build(title, true)
+}
+
+// Clean приводит к формату slug введённый вручную адрес, сохраняя все слова.
+func (g *Generator) Clean(slug string) string {
+	return g.build(slug, false)
+}
+
+// WithSuffix добавляет к slug номер n > 1, укорачивая его при необходимости.
+// От slug остаётся хотя бы один символ, даже если вместе с номером он
+// получится длиннее maxLen: slug из одного номера недопустим.
+func (g *Generator) WithSuffix(slug string, n int) string {
+	if n <= 1 {
+		return slug
+	}
+
+	suffix := "-" + strconv.Itoa(n)
+
+	return truncate(slug, max(g.maxLen-len(suffix), 1)) + suffix
+}
+
+func (g *Generator) build(text string, dropStopWords bool) string {
+	words := splitWords(normalize(text))
+
+	if dropStopWords {
+		meaningful := make([]string, 0, len(words))
+
+		for _, word := range words {
+			if _, stop := g.stopWords[word]; !stop {
+				meaningful = append(meaningful, word)
+			}
+		}
+
+		if len(meaningful) > 0 {
+			words = meaningful
+		}
+	}
+
+	parts := make([]string, 0, len(words))
+
+	for _, word := range words {
+		// буквы без транслитерации разбивают слово на части
+		parts = append(parts, strings.FieldsFunc(g.transliterate(word), func(r rune) bool { return r == '-' })...)
+	}
+
+	return truncate(strings.Join(parts, "-"), g.maxLen)
+}
+
+func (g *Generator) transliterate(word string) string {
+	var sb strings.Builder
+
+	runes := []rune(word)
+
+	for i, r := range runes {
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLower(r) || unicode.IsDigit(r)):
+			sb.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// диакритика, не собравшаяся с буквой при нормализации
+		default:
+			sb.WriteString(g.letter(r, i == 0, next))
+		}
+	}
+
+	return sb.String()
+}
+
+// letter транслитерирует букву r, next - следующая буква слова или 0.
+func (g *Generator) letter(r rune, initial bool, next rune) string {
+	for _, table := range g.tables {
+		translit, ok := table.Letters[r]
+		if !ok {
+			continue
+		}
+
+		if variant, ok := table.Before[r][next]; ok {
+			return variant
+		}
+
+		if initial {
+			if first, ok := table.Initial[r]; ok {
+				return first
+			}
+		}
+
+		return translit
+	}
+
+	if replacement, ok := latin[r]; ok {
+		return replacement
+	}
+
+	// é -> e: раскладываем букву и оставляем латинскую основу
+	var sb strings.Builder
+
+	for _, part := range norm.NFKD.String(string(r)) {
+		if part < unicode.MaxASCII && (unicode.IsLower(part) || unicode.IsDigit(part)) {
+			sb.WriteRune(part)
+		}
+	}
+
+	if sb.Len() == 0 {
+		return "-"
+	}
+
+	return sb.String()
+}
+
+// normalize собирает составные символы (и + ◌̆ -> й) и переводит в нижний регистр.
+func normalize(text string) string {
+	return strings.ToLower(norm.NFKC.String(text))
+}
+
+// splitWords делит текст на слова по всему, кроме букв и цифр.
+// Апострофы внутри слов (п'ять, don't) выбрасываются.
+func splitWords(text string) []string {
+	var (
+		words []string
+		sb    strings.Builder
+	)
+
+	for _, r := range text {
+		switch {
+		case r == '\'' || r == '’' || r == 'ʼ':
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			sb.WriteRune(r)
+		default:
+			if sb.Len() > 0 {
+				words = append(words, sb.String())
+				sb.Reset()
+			}
+		}
+	}
+
+	if sb.Len() > 0 {
+		words = append(words, sb.String())
+	}
+
+	return words
+}
+
+// truncate укорачивает slug до maxLen по границе слова. Слово длиннее
+// maxLen обрезается посередине.
+func truncate(slug string, maxLen int) string {
+	if len(slug) <= maxLen {
+		return slug
+	}
+
+	if maxLen <= 0 {
+		return ""
+	}
+
+	cut := slug[:maxLen]
+	if slug[maxLen] != '-' {
+		if i := strings.LastIndexByte(cut, '-'); i > 0 {
+			cut = cut[:i]
+		}
+	}
+
+	return strings.TrimRight(cut, "-")
+}
@@ -0,0 +1,74 @@
+package slug_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/slug"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator_Make(t *testing.T) {
+	t.Parallel()
+
+	gost := slug.New(slug.Options{
+		Tables:    slug.WithFallbacks(slug.GOST),
+		StopWords: slug.DefaultStopWords,
+		MaxLen:    40,
+	})
+
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"russian", "Слайсы в Go", "slajsy-go"},
+		{"special chars", "CI/CD: 100% #автоматизация!", "ci-cd-100-avtomatizaciya"},
+		{"gost cz", "Концепция цапли", "koncepciya-czapli"},
+		{"emoji", "Привет 🙂 мир", "privet-mir"},
+		{"ukrainian letters", "Їжак і єнот", "yizhak-i-yenot"},
+		{"kazakh letters", "Қазақ тілі", "qazaq-tili"},
+		{"belarusian letters", "Ўладзімір", "wladzimir"},
+		{"apostrophe", "П'ять don't", "pyat-dont"},
+		{"diacritics", "Café Straße", "cafe-strasse"},
+		{"decomposed", "йод", "jod"},
+		{"only stop words", "и в на", "i-v-na"},
+		{"truncated by word", strings.Repeat("слово ", 10), "slovo-slovo-slovo-slovo-slovo-slovo"},
+		{"empty", "🙂 %%%", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, gost.Make(tt.title))
+		})
+	}
+}
+
+func TestGenerator_Tables(t *testing.T) {
+	t.Parallel()
+
+	ukrainian := slug.New(slug.Options{Tables: slug.WithFallbacks(slug.Ukrainian), StopWords: nil, MaxLen: 0})
+	assert.Equal(t, "hryhorii-yablunia", ukrainian.Make("Григорій Яблуня"))
+
+	belarusian := slug.New(slug.Options{Tables: slug.WithFallbacks(slug.Belarusian), StopWords: nil, MaxLen: 0})
+	assert.Equal(t, "yewropa-ziamlia", belarusian.Make("Еўропа зямля"))
+}
+
+func TestGenerator_CleanAndSuffix(t *testing.T) {
+	t.Parallel()
+
+	g := slug.New(slug.Options{Tables: nil, StopWords: slug.DefaultStopWords, MaxLen: 12})
+
+	assert.Equal(t, "go-i-rust", g.Clean("Go и Rust"), "explicit slug keeps stop words")
+	assert.Equal(t, "abc", g.WithSuffix("abc", 1))
+	assert.Equal(t, "abc-2", g.WithSuffix("abc", 2))
+	assert.Equal(t, "hello-12", g.WithSuffix("hello-world", 12))
+	assert.Equal(t, "abcdefghi-10", g.WithSuffix("abcdefghijkl", 10))
+
+	// номер не заменяет slug целиком, даже если не влезает в maxLen
+	tiny := slug.New(slug.Options{Tables: nil, StopWords: nil, MaxLen: 1})
+	assert.Equal(t, "a-2", tiny.WithSuffix("a", 2))
+	assert.Equal(t, "h-10", tiny.WithSuffix("hello", 10))
+}
@@ -0,0 +1,102 @@
+package slug
+
+// Table - таблица транслитерации алфавита в латиницу.
+type Table struct {
+	Name    string
+	Letters map[rune]string
+	// Initial - варианты букв в начале слова, например украинское є -> ye.
+	Initial map[rune]string
+	// Before - варианты букв перед определёнными буквами: буква -> следующая -> латиница.
+	Before map[rune]map[rune]string
+}
+
+// GOST - русский алфавит по ГОСТ 7.79-2000 (система Б, совместимая
+// с ISO 9). Апострофы и штрихи системы Б в slug не попадают,
+// ц перед i, e, y, j пишется как c.
+//
+//nolint:gochecknoglobals // transliteration table
+var GOST = &Table{
+	Name: "gost",
+	Letters: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+		'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+		'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "cz",
+		'ч': "ch", 'ш': "sh", 'щ': "shh", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+		'я': "ya",
+	},
+	Initial: nil,
+	Before: map[rune]map[rune]string{
+		'ц': {'е': "c", 'ё': "c", 'и': "c", 'й': "c", 'ы': "c", 'э': "c", 'ю': "c", 'я': "c"},
+	},
+}
+
+// Ukrainian - украинский алфавит по официальной транслитерации 2010 года.
+//
+//nolint:gochecknoglobals // transliteration table
+var Ukrainian = &Table{
+	Name: "uk",
+	Letters: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie",
+		'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l",
+		'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+		'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu",
+		'я': "ia",
+	},
+	Initial: map[rune]string{'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya"},
+	Before:  nil,
+}
+
+// Belarusian - белорусский алфавит латиницей без диакритики.
+//
+//nolint:gochecknoglobals // transliteration table
+var Belarusian = &Table{
+	Name: "be",
+	Letters: map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "ie", 'ё': "io",
+		'ж': "zh", 'з': "z", 'і': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m", 'н': "n",
+		'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ў': "w", 'ф': "f",
+		'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+		'я': "ia",
+	},
+	Initial: map[rune]string{'е': "ye", 'ё': "yo", 'ю': "yu", 'я': "ya"},
+	Before:  nil,
+}
+
+// Kazakh - буквы казахского алфавита, которых нет в русском.
+// Используется как запасная таблица.
+//
+//nolint:gochecknoglobals // transliteration table
+var Kazakh = &Table{
+	Name: "kk",
+	Letters: map[rune]string{
+		'ә': "a", 'ғ': "gh", 'қ': "q", 'ң': "ng", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h",
+		'і': "i",
+	},
+	Initial: nil,
+	Before:  nil,
+}
+
+// Tables - встроенные таблицы в порядке, в котором они подключаются
+// как запасные после основной.
+//
+//nolint:gochecknoglobals // registry of built-in tables
+var Tables = []*Table{GOST, Ukrainian, Belarusian, Kazakh}
+
+// TableByName ищет встроенную таблицу по имени: gost, uk, be, kk.
+func TableByName(name string) (*Table, bool) {
+	for _, table := range Tables {
+		if table.Name == name {
+			return table, true
+		}
+	}
+
+	return nil, false
+}
+
+// latin - латинские буквы, которые не раскладываются в NFKD на букву
+// и диакритический знак.
+//
+//nolint:gochecknoglobals // transliteration table
+var latin = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+}
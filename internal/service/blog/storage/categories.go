@@ -2,10 +2,13 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 
 	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -20,7 +23,8 @@ func NewCategoriesRepo(log *slog.Logger, db *sqlx.DB) *Categories {
 
 func (c *Categories) All(ctx context.Context) ([]*domain.Category, error) {
 	query := `SELECT id, name 
-			  FROM categories;`
+			  FROM categories
+			  ORDER BY id;`
 
 	categories := []*domain.Category{}
 
@@ -31,3 +35,69 @@ func (c *Categories) All(ctx context.Context) ([]*domain.Category, error) {
 
 	return categories, nil
 }
+
+func (c *Categories) Find(ctx context.Context, id int) (*domain.Category, error) {
+	var category domain.Category
+
+	err := c.DB.GetContext(ctx, &category, `SELECT id, name FROM categories WHERE id = $1;`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("category with id %d: %w", id, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get category: %w", err)
+	}
+
+	return &category, nil
+}
+
+func (c *Categories) Create(ctx context.Context, category *domain.Category) error {
+	err := c.DB.QueryRowContext(ctx, `INSERT INTO categories (name) VALUES ($1) RETURNING id;`, category.Name).
+		Scan(&category.ID)
+	if err != nil {
+		if IsErrorCode(err, UniqueViolationErr) {
+			return fmt.Errorf("category %s: %w", category.Name, errs.ErrDuplicate)
+		}
+
+		return fmt.Errorf("can't insert category: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Categories) Rename(ctx context.Context, id int, name string) error {
+	result, err := c.DB.ExecContext(ctx, `UPDATE categories SET name = $1 WHERE id = $2;`, name, id)
+	if err != nil {
+		if IsErrorCode(err, UniqueViolationErr) {
+			return fmt.Errorf("category %s: %w", name, errs.ErrDuplicate)
+		}
+
+		return fmt.Errorf("can't rename category: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("category with id %d: %w", id, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+// Delete удаляет пустую категорию. Категорию с постами удалить нельзя.
+func (c *Categories) Delete(ctx context.Context, id int) error {
+	result, err := c.DB.ExecContext(ctx, `DELETE FROM categories WHERE id = $1;`, id)
+	if err != nil {
+		if IsErrorCode(err, ForeignKeyViolationErr) {
+			return fmt.Errorf("category with id %d has posts: %w", id, errs.ErrConflict)
+		}
+
+		return fmt.Errorf("can't delete category: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("category with id %d: %w", id, errs.ErrNotFound)
+	}
+
+	return nil
+}
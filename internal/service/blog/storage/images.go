@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+// Images хранит картинки постов файлами в одной директории, откуда их
+// раздаёт /images/.
+type Images struct {
+	Dir string
+}
+
+func NewImagesDir(dir string) *Images {
+	return &Images{Dir: dir}
+}
+
+func (i *Images) Read(name string) ([]byte, error) {
+	path, err := i.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("image %s: %w", name, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't read image: %w", err)
+	}
+
+	return data, nil
+}
+
+// Write сохраняет картинку через временный файл, чтобы /images/ не отдал
+// её недописанной.
+func (i *Images) Write(name string, data []byte) error {
+	path, err := i.path(name)
+	if err != nil {
+		return err
+	}
+
+	//nolint:mnd // rwxr-xr-x
+	if err = os.MkdirAll(i.Dir, 0o755); err != nil {
+		return fmt.Errorf("can't create images directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(i.Dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("can't create image: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return fmt.Errorf("can't write image: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("can't write image: %w", err)
+	}
+
+	//nolint:mnd // rw-r--r--
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("can't write image: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("can't save image: %w", err)
+	}
+
+	return nil
+}
+
+// path не пускает за пределы директории: имя картинки - только имя файла.
+func (i *Images) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") || strings.Contains(name, `\`) {
+		return "", fmt.Errorf("image name %q: %w", name, errs.ErrInvalid)
+	}
+
+	return filepath.Join(i.Dir, name), nil
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -19,26 +20,36 @@ type Posts struct {
 }
 
 const (
-	UniqueViolationErr = "23505"
+	UniqueViolationErr     = "23505"
+	ForeignKeyViolationErr = "23503"
 )
 
 func NewPostsRepo(log *slog.Logger, db *sqlx.DB) *Posts {
 	return &Posts{log: log, DB: db}
 }
 
-func (p *Posts) All(ctx context.Context, limit int, offset int, publishedOnly bool) ([]*domain.Post, error) {
+func (p *Posts) All(
+	ctx context.Context,
+	limit int,
+	offset int,
+	publishedOnly bool,
+	draftsAuthorID int,
+) ([]*domain.Post, error) {
 	query := `
 		SELECT p.id, title, description, content, extension, slug, is_published, category_id, 
-		       c.name as category_name, created_at, updated_at
+		       c.name as category_name, COALESCE(p.author_id, 0) as author_id,
+		       COALESCE(u.display_name, '') as author_name, reactions, p.created_at, updated_at, version
 		FROM posts p
 		LEFT JOIN categories c ON category_id = c.id
+		LEFT JOIN users u ON p.author_id = u.id
 		WHERE ($3 = false OR is_published = true)
-		ORDER BY created_at DESC
+		  AND ($4 = 0 OR is_published = true OR p.author_id = $4)
+		ORDER BY p.created_at DESC
 		LIMIT $1 OFFSET $2;`
 
 	posts := []*domain.Post{}
 
-	err := p.DB.SelectContext(ctx, &posts, query, limit, offset, publishedOnly)
+	err := p.DB.SelectContext(ctx, &posts, query, limit, offset, publishedOnly, draftsAuthorID)
 	if err != nil {
 		return nil, fmt.Errorf("can't get posts from db: %w", err)
 	}
@@ -46,18 +57,46 @@ func (p *Posts) All(ctx context.Context, limit int, offset int, publishedOnly bo
 	return posts, nil
 }
 
+// AllPublished returns every published post without content, newest first.
+func (p *Posts) AllPublished(ctx context.Context) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, title, description, extension, slug, is_published, category_id,
+		       c.name as category_name, COALESCE(p.author_id, 0) as author_id,
+		       COALESCE(u.display_name, '') as author_name, reactions, p.created_at, updated_at, version
+		FROM posts p
+		LEFT JOIN categories c ON category_id = c.id
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE is_published = true
+		ORDER BY p.created_at DESC;`
+
+	posts := []*domain.Post{}
+
+	err := p.DB.SelectContext(ctx, &posts, query)
+	if err != nil {
+		return nil, fmt.Errorf("can't get published posts from db: %w", err)
+	}
+
+	return posts, nil
+}
+
 func (p *Posts) Find(ctx context.Context, postID int) (*domain.Post, error) {
 	query := `
 		SELECT p.id, title, description, content, extension, slug, is_published, category_id, 
-		       c.name as category_name, created_at, updated_at
+		       c.name as category_name, COALESCE(p.author_id, 0) as author_id,
+		       COALESCE(u.display_name, '') as author_name, reactions, p.created_at, updated_at, version
 		FROM posts p
 		LEFT JOIN categories c ON p.category_id = c.id
+		LEFT JOIN users u ON p.author_id = u.id
 		WHERE p.id = $1;`
 
 	var post domain.Post
 
 	err := p.DB.GetContext(ctx, &post, query, postID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("post with id %d: %w", postID, errs.ErrNotFound)
+		}
+
 		return nil, fmt.Errorf("can't get post from db: %w", err)
 	}
 
@@ -67,30 +106,48 @@ func (p *Posts) Find(ctx context.Context, postID int) (*domain.Post, error) {
 func (p *Posts) FindBySlug(ctx context.Context, slug string) (*domain.Post, error) {
 	query := `
 		SELECT p.id, title, description, content, extension, slug, is_published, category_id,
-		       c.name as category_name, created_at, updated_at
+		       c.name as category_name, COALESCE(p.author_id, 0) as author_id,
+		       COALESCE(u.display_name, '') as author_name, reactions, p.created_at, updated_at, version
 		FROM posts p
 		INNER JOIN categories c ON p.category_id = c.id
+		LEFT JOIN users u ON p.author_id = u.id
 		WHERE slug = $1;`
 
 	var post domain.Post
 
 	err := p.DB.GetContext(ctx, &post, query, slug)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("post with slug %s: %w", slug, errs.ErrNotFound)
+		}
+
 		return nil, fmt.Errorf("can't get post from db: %w", err)
 	}
 
 	return &post, nil
 }
 
+// TakenSlugs возвращает те из slugs, что уже заняты постами.
+func (p *Posts) TakenSlugs(ctx context.Context, slugs []string) ([]string, error) {
+	taken := make([]string, 0)
+
+	err := p.DB.SelectContext(ctx, &taken, `SELECT slug FROM posts WHERE slug = ANY($1);`, slugs)
+	if err != nil {
+		return nil, fmt.Errorf("can't select taken slugs: %w", err)
+	}
+
+	return taken, nil
+}
+
 func (p *Posts) Create(ctx context.Context, post *domain.Post) error {
 	query := `
 		INSERT INTO posts (title, description, content, extension, slug, is_published, 
-		                   category_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		                   category_id, author_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9, $10)
 		RETURNING id;`
 
 	args := []any{post.Title, post.Description, post.Content, post.Extension, post.Slug,
-		post.IsPublished, post.CategoryID, post.CreatedAt, post.UpdatedAt}
+		post.IsPublished, post.CategoryID, post.AuthorID, post.CreatedAt, post.UpdatedAt}
 
 	row := p.DB.QueryRowContext(ctx, query, args...)
 	if err := row.Scan(&post.ID); err != nil {
@@ -98,6 +155,10 @@ func (p *Posts) Create(ctx context.Context, post *domain.Post) error {
 			return fmt.Errorf("can't insert new row %w: %w", errs.ErrDuplicate, err)
 		}
 
+		if IsErrorCode(err, ForeignKeyViolationErr) {
+			return fmt.Errorf("unknown category %d: %w", post.CategoryID, errs.ErrInvalid)
+		}
+
 		return fmt.Errorf("can't scan id for post: %w", err)
 	}
 
@@ -108,6 +169,8 @@ func (p *Posts) Create(ctx context.Context, post *domain.Post) error {
 	return nil
 }
 
+// Update сохраняет правку поста. Если params.Version не 0 и не совпадает
+// с версией в базе, возвращает errs.ErrConflict.
 func (p *Posts) Update(ctx context.Context, params domain.UpdatePostParams) error {
 	query := `UPDATE posts
 			  SET title = $1,
@@ -115,27 +178,49 @@ func (p *Posts) Update(ctx context.Context, params domain.UpdatePostParams) erro
     			  description = $3,
     			  category_id = $4,
     			  content = $5,
-    			  updated_at = $6
-              WHERE id = $7`
+    			  updated_at = $6,
+    			  version = version + 1
+              WHERE id = $7 AND ($8 = 0 OR version = $8)`
 
-	args := []any{params.Title, params.Slug, params.Description, params.CategoryID, params.Content, time.Now(), params.ID}
+	args := []any{params.Title, params.Slug, params.Description, params.CategoryID, params.Content, time.Now(),
+		params.ID, params.Version}
 
 	result, err := p.DB.ExecContext(ctx, query, args...)
 	if err != nil {
+		if IsErrorCode(err, UniqueViolationErr) {
+			return fmt.Errorf("slug %s: %w", params.Slug, errs.ErrDuplicate)
+		}
+
+		if IsErrorCode(err, ForeignKeyViolationErr) {
+			return fmt.Errorf("unknown category %d: %w", params.CategoryID, errs.ErrInvalid)
+		}
+
 		return fmt.Errorf("can't update post: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("post with id %d for update: %w", params.ID, errs.ErrNotFound)
+	if rowsAffected > 0 {
+		return nil
 	}
 
-	return nil
+	var exists bool
+
+	err = p.DB.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1);`, params.ID)
+	if err != nil {
+		return fmt.Errorf("can't check post existence: %w", err)
+	}
+
+	if exists {
+		return fmt.Errorf("post with id %d version %d: %w", params.ID, params.Version, errs.ErrConflict)
+	}
+
+	return fmt.Errorf("post with id %d for update: %w", params.ID, errs.ErrNotFound)
 }
 
 func (p *Posts) SetPublicationStatus(ctx context.Context, postID int, isPublished bool) error {
 	query := `UPDATE posts
-				SET is_published = $1
+				SET is_published = $1,
+				    version = version + 1
 				WHERE id = $2;`
 
 	args := []any{isPublished, postID}
@@ -166,6 +251,73 @@ func (p *Posts) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+// AddReaction атомарно меняет счётчик реакции опубликованного поста на delta
+// и возвращает новые значения счётчиков.
+func (p *Posts) AddReaction(ctx context.Context, postID int, reaction string, delta int) (domain.ReactionCounts, error) {
+	query := `
+		UPDATE posts
+		SET reactions = jsonb_set(reactions, ARRAY[$2::text],
+		                          to_jsonb(GREATEST(COALESCE((reactions ->> $2::text)::int, 0) + $3, 0)))
+		WHERE id = $1 AND is_published = true
+		RETURNING reactions;`
+
+	var counts domain.ReactionCounts
+
+	err := p.DB.QueryRowContext(ctx, query, postID, reaction, delta).Scan(&counts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("published post with id %d: %w", postID, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't update reactions: %w", err)
+	}
+
+	return counts, nil
+}
+
+// List возвращает страницу постов без содержимого, от новых к старым.
+// Посты с одинаковым временем создания упорядочены по id, чтобы курсор
+// не пропускал и не повторял их.
+func (p *Posts) List(ctx context.Context, filter domain.PostsFilter) ([]*domain.Post, error) {
+	query := `
+		SELECT p.id, title, description, extension, slug, is_published, category_id,
+		       c.name as category_name, COALESCE(p.author_id, 0) as author_id,
+		       COALESCE(u.display_name, '') as author_name, reactions, p.created_at, updated_at, version
+		FROM posts p
+		LEFT JOIN categories c ON category_id = c.id
+		LEFT JOIN users u ON p.author_id = u.id
+		WHERE ($2 = 0 OR p.category_id = $2)
+		  AND ($3 = 0 OR p.author_id = $3)
+		  AND ($4::text = '' OR is_published = ($4::text = 'published'))
+		  AND ($5::timestamptz IS NULL OR (p.created_at, p.id) < ($5::timestamptz, $6))
+		  AND ($7 = 0 OR is_published = true OR p.author_id = $7)
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $1;`
+
+	var (
+		afterTime *time.Time
+		afterID   int
+	)
+
+	if filter.After != nil {
+		afterTime, afterID = &filter.After.CreatedAt, filter.After.ID
+	}
+
+	args := []any{
+		filter.Limit, filter.CategoryID, filter.AuthorID, string(filter.Status),
+		afterTime, afterID, filter.DraftsAuthorID,
+	}
+
+	posts := []*domain.Post{}
+
+	err := p.DB.SelectContext(ctx, &posts, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("can't get posts page from db: %w", err)
+	}
+
+	return posts, nil
+}
+
 func IsErrorCode(err error, errCode string) bool {
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
@@ -180,20 +332,24 @@ func (p *Posts) AllWithCategory(
 	limit int,
 	offset int,
 	publishedOnly bool,
+	draftsAuthorID int,
 	categoryID int,
 ) ([]*domain.Post, error) {
 	query := `
 		SELECT p.id, title, description, content, extension, slug, is_published, category_id, 
-		       c.name as category_name, created_at, updated_at
+		       c.name as category_name, COALESCE(p.author_id, 0) as author_id,
+		       COALESCE(u.display_name, '') as author_name, reactions, p.created_at, updated_at, version
 		FROM posts p
 		LEFT JOIN categories c ON category_id = c.id
+		LEFT JOIN users u ON p.author_id = u.id
 		WHERE ($3 = false OR is_published = true) AND p.category_id = $4
-		ORDER BY created_at DESC
+		  AND ($5 = 0 OR is_published = true OR p.author_id = $5)
+		ORDER BY p.created_at DESC
 		LIMIT $1 OFFSET $2;`
 
 	posts := []*domain.Post{}
 
-	err := p.DB.SelectContext(ctx, &posts, query, limit, offset, publishedOnly, categoryID)
+	err := p.DB.SelectContext(ctx, &posts, query, limit, offset, publishedOnly, categoryID, draftsAuthorID)
 	if err != nil {
 		return nil, fmt.Errorf("can't get posts from db: %w", err)
 	}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+// VaultNotes связывает заметки Obsidian (путь относительно хранилища)
+// с постами, созданными из них, и помнит картинки, загруженные из хранилища.
+type VaultNotes struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewVaultNotesRepo(log *slog.Logger, db *sqlx.DB) *VaultNotes {
+	return &VaultNotes{log: log, DB: db}
+}
+
+func (v *VaultNotes) PostID(ctx context.Context, path string) (int, error) {
+	var postID int
+
+	err := v.DB.GetContext(ctx, &postID, `SELECT post_id FROM vault_notes WHERE path = $1;`, path)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("note %s: %w", path, errs.ErrNotFound)
+		}
+
+		return 0, fmt.Errorf("can't get note: %w", err)
+	}
+
+	return postID, nil
+}
+
+func (v *VaultNotes) Save(ctx context.Context, path string, postID int) error {
+	query := `
+		INSERT INTO vault_notes (path, post_id, synced_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (path) DO UPDATE SET post_id = EXCLUDED.post_id, synced_at = EXCLUDED.synced_at;`
+
+	if _, err := v.DB.ExecContext(ctx, query, path, postID); err != nil {
+		if IsErrorCode(err, ForeignKeyViolationErr) {
+			return fmt.Errorf("post with id %d: %w", postID, errs.ErrNotFound)
+		}
+
+		return fmt.Errorf("can't save note: %w", err)
+	}
+
+	return nil
+}
+
+// HasAttachment сообщает, загружала ли синхронизация картинку name.
+func (v *VaultNotes) HasAttachment(ctx context.Context, name string) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM vault_attachments WHERE name = $1);`
+
+	if err := v.DB.GetContext(ctx, &exists, query, name); err != nil {
+		return false, fmt.Errorf("can't check attachment: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (v *VaultNotes) SaveAttachment(ctx context.Context, name string) error {
+	query := `
+		INSERT INTO vault_attachments (name, synced_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (name) DO UPDATE SET synced_at = EXCLUDED.synced_at;`
+
+	if _, err := v.DB.ExecContext(ctx, query, name); err != nil {
+		return fmt.Errorf("can't save attachment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Встраивания Obsidian ![[Pasted image.png|300]] и markdown картинки
+// с относительным путём ![alt](attachments/scheme.png).
+//
+//nolint:gochecknoglobals // compiled once
+var (
+	wikiEmbed     = regexp.MustCompile(`!\[\[([^\]]+)\]\]`)
+	markdownImage = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+)
+
+// resolveEmbeds приводит встраивания вложений к виду, который понимает
+// ImageProcessor.AddPrefix: ![[имя файла]] и ![alt](имя файла), где имя
+// экранировано для URL, потому что в Obsidian имена часто с пробелами.
+// Возвращает найденные вложения (имя -> путь в хранилище) и встраивания,
+// для которых файла нет: обычно это встроенные заметки, они становятся
+// ссылками [[заметка]], иначе блог покажет их как битые картинки.
+func resolveEmbeds(body []byte, attachments map[string]string) ([]byte, map[string]string, []string) {
+	embeds := make(map[string]string)
+
+	var missing []string
+
+	resolve := func(target string) (string, bool) {
+		name := path.Base(target)
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+
+		file, ok := attachments[name]
+		if !ok {
+			missing = append(missing, target)
+
+			return "", false
+		}
+
+		embeds[name] = file
+
+		return url.PathEscape(name), true
+	}
+
+	body = wikiEmbed.ReplaceAllFunc(body, func(match []byte) []byte {
+		target := string(wikiEmbed.FindSubmatch(match)[1])
+
+		// ![[file.png|300]] - размер, ![[note#heading]] - часть заметки
+		target, _, _ = strings.Cut(target, "|")
+		target, _, _ = strings.Cut(target, "#")
+
+		name, ok := resolve(strings.TrimSpace(target))
+		if !ok {
+			return match[1:]
+		}
+
+		return []byte("![[" + name + "]]")
+	})
+
+	body = markdownImage.ReplaceAllFunc(body, func(match []byte) []byte {
+		submatches := markdownImage.FindSubmatch(match)
+		alt, target := string(submatches[1]), string(submatches[2])
+
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "/") {
+			return match
+		}
+
+		name, ok := resolve(target)
+		if !ok {
+			return match
+		}
+
+		return []byte("![" + alt + "](" + name + ")")
+	})
+
+	return body, embeds, missing
+}
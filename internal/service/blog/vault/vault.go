@@ -0,0 +1,520 @@
+// Package vault публикует заметки из хранилища Obsidian как посты блога.
+//
+// Заметка связывается с постом по id поста в front matter, иначе по пути
+// заметки относительно хранилища: связь запоминается при создании поста.
+// Посты создаются и меняются через сервис блога, поэтому проходят те же
+// проверки, что и посты из формы. Удалённые из хранилища заметки посты
+// не удаляют.
+package vault
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/frontmatter"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+)
+
+type Blog interface {
+	Post(ctx context.Context, id int) (*domain.Post, error)
+	CreatePost(ctx context.Context, params domain.CreatePostParams) (*domain.Post, error)
+	UpdatePost(ctx context.Context, params domain.UpdatePostParams) error
+	ChangePublishStatus(ctx context.Context, id int, curPublishStatus bool) error
+	Categories(ctx context.Context) ([]*domain.Category, error)
+}
+
+type NotesRepository interface {
+	PostID(ctx context.Context, path string) (int, error)
+	Save(ctx context.Context, path string, postID int) error
+	HasAttachment(ctx context.Context, name string) (bool, error)
+	SaveAttachment(ctx context.Context, name string) error
+}
+
+type ImageStore interface {
+	Read(name string) ([]byte, error)
+	Write(name string, data []byte) error
+}
+
+type ImageProcessor interface {
+	AddPrefix(content []byte, prefix string) ([]byte, error)
+}
+
+type Options struct {
+	Dir      string // корень хранилища
+	Folder   string // папка с заметками для блога относительно Dir
+	Category string // категория заметок без category в front matter
+	AuthorID int    // автор новых постов
+}
+
+type Syncer struct {
+	log    *slog.Logger
+	blog   Blog
+	notes  NotesRepository
+	images ImageStore
+	proc   ImageProcessor
+	opts   Options
+}
+
+func New(
+	log *slog.Logger, blog Blog, notes NotesRepository, images ImageStore, proc ImageProcessor, opts Options,
+) *Syncer {
+	return &Syncer{log: log, blog: blog, notes: notes, images: images, proc: proc, opts: opts}
+}
+
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	ActionFailed    Action = "failed"
+)
+
+// NoteResult - что синхронизация сделала (или сделает при dry run) с заметкой.
+type NoteResult struct {
+	Path    string // путь относительно хранилища, через /
+	Action  Action
+	PostID  int // 0 для нового поста
+	Slug    string
+	Changes []domain.FieldChange // для ActionUpdate: Yours - заметка, Current - пост
+	Err     error                // для ActionFailed
+}
+
+type Report struct {
+	Notes []NoteResult
+	// Attachments - новые вложения, которые загружены (или будут загружены) в картинки блога.
+	Attachments []string
+	// Overwritten - изменённые вложения, которые заменили загруженные раньше из хранилища.
+	Overwritten []string
+	// Conflicts - вложения, имя которых занято картинкой не из хранилища, например
+	// загруженной через форму. Они пропускаются, чтобы не затереть чужую картинку.
+	Conflicts []string
+	// Missing - встраивания, для которых в хранилище нет файла.
+	Missing []string
+}
+
+// Count возвращает число заметок с действием action.
+func (r *Report) Count(action Action) int {
+	count := 0
+
+	for _, note := range r.Notes {
+		if note.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// plan - заметка, готовая к синхронизации.
+type plan struct {
+	result    *NoteResult
+	current   *domain.Post
+	params    domain.UpdatePostParams
+	published *bool // nil - статус в заметке не указан
+	// byID - пост найден по id из front matter, связь с путём ещё может быть не сохранена
+	byID bool
+}
+
+// Sync сверяет заметки с постами и при dryRun = false применяет изменения.
+// Ошибка в одной заметке не останавливает остальные и попадает в отчёт.
+func (s *Syncer) Sync(ctx context.Context, dryRun bool) (*Report, error) {
+	notes, attachments, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.blog.Categories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get categories: %w", err)
+	}
+
+	report := &Report{
+		Notes:       make([]NoteResult, 0, len(notes)),
+		Attachments: nil,
+		Overwritten: nil,
+		Conflicts:   nil,
+		Missing:     nil,
+	}
+	plans := make([]*plan, 0, len(notes))
+	embedded := make(map[string]string)
+
+	for _, notePath := range notes {
+		p, noteEmbeds, missing := s.planNote(ctx, notePath, attachments, categories)
+
+		for name, file := range noteEmbeds {
+			embedded[name] = file
+		}
+
+		for _, name := range missing {
+			if !slices.Contains(report.Missing, name) {
+				report.Missing = append(report.Missing, name)
+			}
+		}
+
+		plans = append(plans, p)
+	}
+
+	// вложения загружаются раньше постов, чтобы посты сразу показывались целиком
+	if err = s.syncAttachments(ctx, embedded, dryRun, report); err != nil {
+		return nil, err
+	}
+
+	for _, p := range plans {
+		if !dryRun {
+			s.apply(ctx, p)
+		}
+
+		report.Notes = append(report.Notes, *p.result)
+	}
+
+	return report, nil
+}
+
+// scan возвращает заметки из папки блога и все остальные файлы
+// хранилища по имени: Obsidian находит вложения по имени файла.
+func (s *Syncer) scan() ([]string, map[string]string, error) {
+	folder := filepath.Join(s.opts.Dir, filepath.FromSlash(s.opts.Folder))
+
+	var notes []string
+
+	attachments := make(map[string]string)
+
+	err := filepath.WalkDir(s.opts.Dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if file != s.opts.Dir && Hidden(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.opts.Dir, file)
+		if err != nil {
+			return fmt.Errorf("can't resolve %s: %w", file, err)
+		}
+
+		if strings.EqualFold(filepath.Ext(file), ".md") {
+			if inside(folder, file) {
+				notes = append(notes, filepath.ToSlash(rel))
+			}
+
+			return nil
+		}
+
+		// при одинаковых именах берётся файл с самым коротким путём, как в Obsidian
+		if current, ok := attachments[entry.Name()]; !ok || len(rel) < len(current) {
+			attachments[entry.Name()] = rel
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read vault: %w", err)
+	}
+
+	return notes, attachments, nil
+}
+
+// Hidden - служебные файлы и папки хранилища: .obsidian, .trash, .git.
+func Hidden(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+func inside(dir, file string) bool {
+	rel, err := filepath.Rel(dir, file)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// planNote читает заметку и сравнивает её с постом. Возвращает вложения
+// заметки (имя -> путь в хранилище) и ненайденные встраивания.
+func (s *Syncer) planNote(
+	ctx context.Context, notePath string, attachments map[string]string, categories []*domain.Category,
+) (*plan, map[string]string, []string) {
+	result := &NoteResult{Path: notePath, Action: ActionFailed, PostID: 0, Slug: "", Changes: nil, Err: nil}
+	p := &plan{result: result} //nolint:exhaustruct // filled below
+
+	fail := func(err error) (*plan, map[string]string, []string) {
+		result.Action, result.Err = ActionFailed, err
+
+		return p, nil, nil
+	}
+
+	raw, err := os.ReadFile(filepath.Join(s.opts.Dir, filepath.FromSlash(notePath)))
+	if err != nil {
+		return fail(fmt.Errorf("can't read note: %w", err))
+	}
+
+	fields, body, err := frontmatter.Split(raw)
+	if err != nil {
+		return fail(err)
+	}
+
+	body, embeds, missing := resolveEmbeds(body, attachments)
+
+	categoryID, err := s.category(fields["category"], categories)
+	if err != nil {
+		return fail(err)
+	}
+
+	if value, ok := fields["published"]; ok && value != "" {
+		published, parseErr := strconv.ParseBool(value)
+		if parseErr != nil {
+			return fail(fmt.Errorf("invalid published %q", value))
+		}
+
+		p.published = &published
+	}
+
+	p.params = domain.UpdatePostParams{
+		ID:          0,
+		Title:       cmp.Or(fields["title"], strings.TrimSuffix(path.Base(notePath), path.Ext(notePath))),
+		Slug:        fields["slug"],
+		Description: fields["description"],
+		CategoryID:  categoryID,
+		Content:     body,
+		Version:     0,
+	}
+
+	p.byID = fields["id"] != ""
+
+	if p.current, err = s.linkedPost(ctx, notePath, fields["id"]); err != nil {
+		return fail(err)
+	}
+
+	if p.current == nil {
+		result.Action = ActionCreate
+
+		return p, embeds, missing
+	}
+
+	if err = s.compare(p); err != nil {
+		return fail(err)
+	}
+
+	return p, embeds, missing
+}
+
+// linkedPost ищет пост заметки: по id из front matter, затем по пути.
+// Возвращает nil, если заметка ещё не публиковалась.
+func (s *Syncer) linkedPost(ctx context.Context, notePath, id string) (*domain.Post, error) {
+	if id != "" {
+		postID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", id)
+		}
+
+		post, err := s.blog.Post(ctx, postID)
+		if err != nil {
+			return nil, fmt.Errorf("post from id: %w", err)
+		}
+
+		return post, nil
+	}
+
+	postID, err := s.notes.PostID(ctx, notePath)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, nil //nolint:nilnil // new note
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't find linked post: %w", err)
+	}
+
+	post, err := s.blog.Post(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("linked post: %w", err)
+	}
+
+	return post, nil
+}
+
+// compare заполняет изменения поста. Текст сравнивается в том виде,
+// в каком его сохранит блог.
+func (s *Syncer) compare(p *plan) error {
+	current := p.current
+
+	p.result.PostID, p.result.Slug = current.ID, current.Slug
+	p.params.ID, p.params.Version = current.ID, current.Version
+
+	if p.params.Slug == "" {
+		p.params.Slug = current.Slug
+	}
+
+	stored, err := s.proc.AddPrefix(p.params.Content, "/images/")
+	if err != nil {
+		return fmt.Errorf("can't process note: %w", err)
+	}
+
+	compared := p.params
+	compared.Content = stored
+	p.result.Changes = compared.Diff(current)
+
+	if p.published != nil && *p.published != current.IsPublished {
+		p.result.Changes = append(p.result.Changes, domain.FieldChange{
+			Field:   "published",
+			Yours:   strconv.FormatBool(*p.published),
+			Current: strconv.FormatBool(current.IsPublished),
+		})
+	}
+
+	p.result.Action = ActionUnchanged
+	if len(p.result.Changes) > 0 {
+		p.result.Action = ActionUpdate
+	}
+
+	return nil
+}
+
+func (s *Syncer) category(name string, categories []*domain.Category) (int, error) {
+	name = cmp.Or(name, s.opts.Category)
+	if name == "" {
+		return 0, errors.New("no category in front matter and no default category")
+	}
+
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) {
+			return category.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("category %q: %w", name, errs.ErrNotFound)
+}
+
+// syncAttachments загружает новые и изменённые вложения. Картинка блога
+// заменяется, только если её тоже загрузила синхронизация.
+func (s *Syncer) syncAttachments(ctx context.Context, embedded map[string]string, dryRun bool, report *Report) error {
+	names := make([]string, 0, len(embedded))
+	for name := range embedded {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.opts.Dir, embedded[name]))
+		if err != nil {
+			return fmt.Errorf("can't read attachment: %w", err)
+		}
+
+		current, err := s.images.Read(name)
+
+		var uploaded *[]string
+
+		switch {
+		case err == nil && bytes.Equal(current, data):
+			continue
+		case errors.Is(err, errs.ErrNotFound):
+			uploaded = &report.Attachments
+		case err != nil:
+			return fmt.Errorf("can't check attachment %s: %w", name, err)
+		default:
+			owned, ownedErr := s.notes.HasAttachment(ctx, name)
+			if ownedErr != nil {
+				return fmt.Errorf("can't check attachment %s: %w", name, ownedErr)
+			}
+
+			if !owned {
+				report.Conflicts = append(report.Conflicts, name)
+
+				continue
+			}
+
+			uploaded = &report.Overwritten
+		}
+
+		if !dryRun {
+			if err = s.images.Write(name, data); err != nil {
+				return fmt.Errorf("can't upload attachment %s: %w", name, err)
+			}
+
+			if err = s.notes.SaveAttachment(ctx, name); err != nil {
+				return fmt.Errorf("can't save attachment %s: %w", name, err)
+			}
+		}
+
+		*uploaded = append(*uploaded, name)
+	}
+
+	return nil
+}
+
+func (s *Syncer) apply(ctx context.Context, p *plan) {
+	var err error
+
+	switch p.result.Action {
+	case ActionCreate:
+		err = s.create(ctx, p)
+	case ActionUpdate:
+		err = s.update(ctx, p)
+	case ActionUnchanged:
+		// связь по id из front matter запоминается и для неизменённых заметок
+		if p.byID {
+			err = s.notes.Save(ctx, p.result.Path, p.result.PostID)
+		}
+	case ActionFailed:
+		return
+	}
+
+	if err != nil {
+		p.result.Action, p.result.Err = ActionFailed, err
+		s.log.Warn("can't sync note", slog.String("path", p.result.Path), slog.Any("error", err))
+	}
+}
+
+func (s *Syncer) create(ctx context.Context, p *plan) error {
+	post, err := s.blog.CreatePost(ctx, domain.CreatePostParams{
+		Title:       p.params.Title,
+		Slug:        p.params.Slug,
+		Description: p.params.Description,
+		Filename:    path.Base(p.result.Path),
+		CategoryID:  p.params.CategoryID,
+		AuthorID:    s.opts.AuthorID,
+		IsPublished: p.published != nil && *p.published,
+		Content:     p.params.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("can't create post: %w", err)
+	}
+
+	p.result.PostID, p.result.Slug = post.ID, post.Slug
+
+	return s.notes.Save(ctx, p.result.Path, post.ID) //nolint:wrapcheck // repository errors name the note
+}
+
+func (s *Syncer) update(ctx context.Context, p *plan) error {
+	if err := s.blog.UpdatePost(ctx, p.params); err != nil {
+		return fmt.Errorf("can't update post: %w", err)
+	}
+
+	p.result.Slug = p.params.Slug
+
+	if p.published != nil && *p.published != p.current.IsPublished {
+		if err := s.blog.ChangePublishStatus(ctx, p.current.ID, p.current.IsPublished); err != nil {
+			return fmt.Errorf("can't change publish status: %w", err)
+		}
+	}
+
+	return s.notes.Save(ctx, p.result.Path, p.current.ID) //nolint:wrapcheck // repository errors name the note
+}
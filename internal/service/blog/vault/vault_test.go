@@ -0,0 +1,314 @@
+package vault_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/service/processor"
+	"github.com/arevbond/arevbond-blog/internal/service/blog/vault"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBlog хранит посты в памяти и, как настоящий сервис, добавляет
+// к картинкам префикс /images/.
+type fakeBlog struct {
+	proc  *processor.ImageProcessor
+	posts map[int]*domain.Post
+}
+
+func (b *fakeBlog) Post(_ context.Context, id int) (*domain.Post, error) {
+	post, ok := b.posts[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	copied := *post
+
+	return &copied, nil
+}
+
+func (b *fakeBlog) CreatePost(_ context.Context, params domain.CreatePostParams) (*domain.Post, error) {
+	content, err := b.proc.AddPrefix(params.Content, "/images/")
+	if err != nil {
+		return nil, err
+	}
+
+	post := &domain.Post{
+		ID:          len(b.posts) + 1,
+		Title:       params.Title,
+		Description: params.Description,
+		Content:     content,
+		IsPublished: params.IsPublished,
+		Slug:        params.Slug,
+		CategoryID:  params.CategoryID,
+		AuthorID:    params.AuthorID,
+		Version:     1,
+	}
+	if post.Slug == "" {
+		post.Slug = "post-" + post.Title
+	}
+
+	b.posts[post.ID] = post
+
+	return post, nil
+}
+
+func (b *fakeBlog) UpdatePost(_ context.Context, params domain.UpdatePostParams) error {
+	post := b.posts[params.ID]
+
+	content, err := b.proc.AddPrefix(params.Content, "/images/")
+	if err != nil {
+		return err
+	}
+
+	post.Title, post.Slug, post.Description = params.Title, params.Slug, params.Description
+	post.CategoryID, post.Content = params.CategoryID, content
+	post.Version++
+
+	return nil
+}
+
+func (b *fakeBlog) ChangePublishStatus(_ context.Context, id int, curPublishStatus bool) error {
+	b.posts[id].IsPublished = !curPublishStatus
+
+	return nil
+}
+
+func (b *fakeBlog) Categories(context.Context) ([]*domain.Category, error) {
+	return []*domain.Category{{ID: 1, Name: "Go"}, {ID: 2, Name: "Заметки"}}, nil
+}
+
+// fakeNotes хранит связи заметок с постами и имена загруженных вложений.
+type fakeNotes struct {
+	posts       map[string]int
+	attachments map[string]bool
+}
+
+func (n *fakeNotes) PostID(_ context.Context, path string) (int, error) {
+	id, ok := n.posts[path]
+	if !ok {
+		return 0, errs.ErrNotFound
+	}
+
+	return id, nil
+}
+
+func (n *fakeNotes) Save(_ context.Context, path string, postID int) error {
+	n.posts[path] = postID
+
+	return nil
+}
+
+func (n *fakeNotes) HasAttachment(_ context.Context, name string) (bool, error) {
+	return n.attachments[name], nil
+}
+
+func (n *fakeNotes) SaveAttachment(_ context.Context, name string) error {
+	n.attachments[name] = true
+
+	return nil
+}
+
+type fakeImages map[string][]byte
+
+func (i fakeImages) Read(name string) ([]byte, error) {
+	data, ok := i[name]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (i fakeImages) Write(name string, data []byte) error {
+	i[name] = data
+
+	return nil
+}
+
+type env struct {
+	dir    string
+	blog   *fakeBlog
+	notes  *fakeNotes
+	images fakeImages
+	syncer *vault.Syncer
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	proc := processor.NewImageProcessor(log)
+
+	e := &env{
+		dir:    t.TempDir(),
+		blog:   &fakeBlog{proc: proc, posts: make(map[int]*domain.Post)},
+		notes:  &fakeNotes{posts: make(map[string]int), attachments: make(map[string]bool)},
+		images: make(fakeImages),
+		syncer: nil,
+	}
+	e.syncer = vault.New(log, e.blog, e.notes, e.images, proc, vault.Options{
+		Dir:      e.dir,
+		Folder:   "Blog",
+		Category: "Go",
+		AuthorID: 7,
+	})
+
+	return e
+}
+
+func (e *env) write(t *testing.T, name, content string) {
+	t.Helper()
+
+	file := filepath.Join(e.dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+}
+
+const note = `---
+title: Слайсы
+slug: slices
+published: true
+---
+# Слайсы
+
+![[Pasted image 1.png|300]]
+![схема](../attachments/scheme.png)
+![[Другая заметка]]
+`
+
+func TestSyncCreatesAndUpdates(t *testing.T) {
+	t.Parallel()
+
+	e := newEnv(t)
+	e.write(t, "Blog/slices.md", note)
+	e.write(t, "Drafts/draft.md", "не для блога")
+	e.write(t, "attachments/Pasted image 1.png", "png")
+	e.write(t, "attachments/scheme.png", "scheme")
+	e.write(t, ".obsidian/workspace.json", "{}")
+
+	report, err := e.syncer.Sync(t.Context(), false)
+	require.NoError(t, err)
+
+	require.Len(t, report.Notes, 1)
+	assert.Equal(t, vault.ActionCreate, report.Notes[0].Action)
+	assert.Equal(t, []string{"Pasted image 1.png", "scheme.png"}, report.Attachments)
+	assert.Equal(t, []string{"Другая заметка"}, report.Missing)
+
+	post := e.blog.posts[1]
+	require.NotNil(t, post)
+	assert.Equal(t, "slices", post.Slug)
+	assert.True(t, post.IsPublished)
+	assert.Equal(t, 1, post.CategoryID)
+	assert.Equal(t, 7, post.AuthorID)
+	assert.Contains(t, string(post.Content), "![](/images/Pasted%20image%201.png)")
+	assert.Contains(t, string(post.Content), "![схема](/images/scheme.png)")
+	assert.Contains(t, string(post.Content), "\n[[Другая заметка]]")
+	assert.Equal(t, []byte("png"), e.images["Pasted image 1.png"])
+	assert.Equal(t, 1, e.notes.posts["Blog/slices.md"])
+
+	report, err = e.syncer.Sync(t.Context(), false)
+	require.NoError(t, err)
+	assert.Equal(t, vault.ActionUnchanged, report.Notes[0].Action)
+	assert.Empty(t, report.Attachments)
+
+	e.write(t, "Blog/slices.md", "---\ntitle: Слайсы в Go\ncategory: заметки\n---\nНовый текст\n")
+
+	report, err = e.syncer.Sync(t.Context(), false)
+	require.NoError(t, err)
+
+	result := report.Notes[0]
+	assert.Equal(t, vault.ActionUpdate, result.Action)
+
+	fields := make([]string, 0, len(result.Changes))
+	for _, change := range result.Changes {
+		fields = append(fields, change.Field)
+	}
+
+	assert.Equal(t, []string{"title", "category_id", "content"}, fields)
+	assert.Equal(t, "Слайсы в Go", e.blog.posts[1].Title)
+	assert.Equal(t, "slices", e.blog.posts[1].Slug, "slug is kept when the note has none")
+	assert.Equal(t, 2, e.blog.posts[1].CategoryID)
+}
+
+func TestSyncDryRun(t *testing.T) {
+	t.Parallel()
+
+	e := newEnv(t)
+	e.write(t, "Blog/slices.md", note)
+	e.write(t, "attachments/Pasted image 1.png", "png")
+
+	report, err := e.syncer.Sync(t.Context(), true)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Count(vault.ActionCreate))
+	assert.Equal(t, []string{"Pasted image 1.png"}, report.Attachments)
+	assert.Empty(t, e.blog.posts)
+	assert.Empty(t, e.notes.posts)
+	assert.Empty(t, e.notes.attachments)
+	assert.Empty(t, e.images)
+}
+
+func TestSyncLinksByFrontMatterID(t *testing.T) {
+	t.Parallel()
+
+	e := newEnv(t)
+	e.blog.posts[5] = &domain.Post{ID: 5, Title: "Старый", Slug: "old", CategoryID: 1, Content: []byte("текст\n")}
+	e.write(t, "Blog/old.md", "---\nid: 5\ntitle: Старый\npublished: true\n---\nтекст\n")
+	e.write(t, "Blog/broken.md", "---\ncategory: Rust\n---\nтекст\n")
+
+	report, err := e.syncer.Sync(t.Context(), false)
+	require.NoError(t, err)
+	require.Len(t, report.Notes, 2)
+
+	byPath := make(map[string]vault.NoteResult)
+	for _, result := range report.Notes {
+		byPath[result.Path] = result
+	}
+
+	assert.Equal(t, vault.ActionFailed, byPath["Blog/broken.md"].Action)
+	require.ErrorIs(t, byPath["Blog/broken.md"].Err, errs.ErrNotFound)
+
+	linked := byPath["Blog/old.md"]
+	assert.Equal(t, vault.ActionUpdate, linked.Action)
+	require.Len(t, linked.Changes, 1)
+	assert.Equal(t, "published", linked.Changes[0].Field)
+	assert.True(t, e.blog.posts[5].IsPublished)
+	assert.Equal(t, 5, e.notes.posts["Blog/old.md"])
+}
+
+func TestSyncAttachmentsKeepForeignImages(t *testing.T) {
+	t.Parallel()
+
+	e := newEnv(t)
+	e.write(t, "Blog/slices.md", note)
+	e.write(t, "attachments/Pasted image 1.png", "png")
+	e.write(t, "attachments/scheme.png", "scheme")
+
+	// scheme.png уже загружена через форму, а Pasted image 1.png - прошлой синхронизацией
+	e.images["scheme.png"] = []byte("form upload")
+	e.images["Pasted image 1.png"] = []byte("old png")
+	e.notes.attachments["Pasted image 1.png"] = true
+
+	report, err := e.syncer.Sync(t.Context(), true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Attachments)
+	assert.Equal(t, []string{"Pasted image 1.png"}, report.Overwritten)
+	assert.Equal(t, []string{"scheme.png"}, report.Conflicts)
+	assert.Equal(t, []byte("old png"), e.images["Pasted image 1.png"])
+
+	report, err = e.syncer.Sync(t.Context(), false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pasted image 1.png"}, report.Overwritten)
+	assert.Equal(t, []string{"scheme.png"}, report.Conflicts)
+	assert.Equal(t, []byte("png"), e.images["Pasted image 1.png"])
+	assert.Equal(t, []byte("form upload"), e.images["scheme.png"])
+	assert.False(t, e.notes.attachments["scheme.png"])
+}
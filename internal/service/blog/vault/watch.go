@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch синхронизирует хранилище сразу и затем после каждой серии
+// изменений: синхронизация запускается, когда файлы не меняются
+// debounce. Результат каждого прохода передаётся в onSync. Работает
+// до отмены ctx.
+func (s *Syncer) Watch(
+	ctx context.Context, dryRun bool, debounce time.Duration, onSync func(*Report, error),
+) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("can't watch vault: %w", err)
+	}
+	defer watcher.Close()
+
+	// fsnotify не следит за вложенными папками, каждая добавляется отдельно
+	if err = s.watchTree(watcher, s.opts.Dir); err != nil {
+		return err
+	}
+
+	onSync(s.Sync(ctx, dryRun))
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if !s.relevant(event) {
+				continue
+			}
+
+			if event.Has(fsnotify.Create) {
+				if info, statErr := os.Stat(event.Name); statErr == nil && info.IsDir() {
+					if err = s.watchTree(watcher, event.Name); err != nil {
+						s.log.Warn("can't watch new folder", slog.String("path", event.Name), slog.Any("error", err))
+					}
+				}
+			}
+
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			s.log.Warn("vault watcher error", slog.Any("error", err))
+		case <-timer.C:
+			onSync(s.Sync(ctx, dryRun))
+		}
+	}
+}
+
+func (s *Syncer) watchTree(watcher *fsnotify.Watcher, root string) error {
+	err := filepath.WalkDir(root, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if dir != s.opts.Dir && Hidden(entry.Name()) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(dir)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("can't watch vault: %w", err)
+	}
+
+	return nil
+}
+
+// relevant отбрасывает события служебных папок: Obsidian постоянно
+// пишет в .obsidian/workspace.json.
+func (s *Syncer) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	rel, err := filepath.Rel(s.opts.Dir, event.Name)
+	if err != nil {
+		return false
+	}
+
+	for dir := rel; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if Hidden(filepath.Base(dir)) {
+			return false
+		}
+	}
+
+	return true
+}
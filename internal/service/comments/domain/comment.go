@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+type Comment struct {
+	ID         int       `db:"id"`
+	PostID     int       `db:"post_id"`
+	ParentID   int       `db:"parent_id"` // 0 для комментария верхнего уровня
+	AuthorName string    `db:"author_name"`
+	Content    string    `db:"content"`
+	Status     Status    `db:"status"`
+	CreatedAt  time.Time `db:"created_at"`
+
+	// заполняются только в очереди модерации
+	PostTitle string `db:"post_title"`
+	PostSlug  string `db:"post_slug"`
+
+	HTML    []byte     `db:"-"`
+	Replies []*Comment `db:"-"`
+}
+
+type CreateCommentParams struct {
+	PostID     int
+	ParentID   int
+	AuthorName string
+	Content    string
+	IP         string
+}
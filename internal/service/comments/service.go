@@ -0,0 +1,15 @@
+package comments
+
+import (
+	"log/slog"
+
+	"github.com/arevbond/arevbond-blog/internal/service/comments/service"
+	"github.com/arevbond/arevbond-blog/internal/service/comments/storage"
+	"github.com/jmoiron/sqlx"
+)
+
+func NewCommentsModule(log *slog.Logger, db *sqlx.DB) *service.Comments {
+	commentsRepo := storage.NewCommentsRepo(log, db)
+
+	return service.New(log, commentsRepo)
+}
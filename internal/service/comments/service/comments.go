@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arevbond/arevbond-blog/internal/service/comments/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+const (
+	MaxAuthorNameLen = 100
+	MaxContentLen    = 5000
+
+	moderationQueueLimit = 200
+
+	// не больше commentsPerWindow комментариев с одного ip за rateWindow.
+	commentsPerWindow = 3
+	rateWindow        = 10 * time.Minute
+)
+
+type CommentsRepository interface {
+	Create(ctx context.Context, comment *domain.Comment) error
+	Find(ctx context.Context, id int) (*domain.Comment, error)
+	ByPost(ctx context.Context, postID int, status domain.Status) ([]*domain.Comment, error)
+	ByStatus(ctx context.Context, status domain.Status, limit int) ([]*domain.Comment, error)
+	SetStatus(ctx context.Context, id int, status domain.Status) error
+	Delete(ctx context.Context, id int) error
+}
+
+type Comments struct {
+	log          *slog.Logger
+	CommentsRepo CommentsRepository
+
+	limiter *ipLimiter
+}
+
+func New(log *slog.Logger, comments CommentsRepository) *Comments {
+	return &Comments{
+		log:          log,
+		CommentsRepo: comments,
+		limiter:      newIPLimiter(commentsPerWindow, rateWindow),
+	}
+}
+
+// Create добавляет комментарий в очередь модерации.
+// Ответ на ответ прикрепляется к корневому комментарию: ветки только одного уровня.
+func (c *Comments) Create(ctx context.Context, params domain.CreateCommentParams) (*domain.Comment, error) {
+	authorName := strings.TrimSpace(params.AuthorName)
+	content := strings.TrimSpace(params.Content)
+
+	switch {
+	case authorName == "" || utf8.RuneCountInString(authorName) > MaxAuthorNameLen:
+		return nil, fmt.Errorf("%w: author name must be 1-%d characters", errs.ErrInvalid, MaxAuthorNameLen)
+	case content == "" || utf8.RuneCountInString(content) > MaxContentLen:
+		return nil, fmt.Errorf("%w: content must be 1-%d characters", errs.ErrInvalid, MaxContentLen)
+	}
+
+	if !c.limiter.Allow(params.IP, time.Now()) {
+		return nil, fmt.Errorf("comments from %s: %w", params.IP, errs.ErrRateLimited)
+	}
+
+	parentID := params.ParentID
+	if parentID != 0 {
+		parent, err := c.CommentsRepo.Find(ctx, parentID)
+		if err != nil {
+			return nil, fmt.Errorf("can't find parent comment: %w", err)
+		}
+
+		if parent.PostID != params.PostID || parent.Status != domain.StatusApproved {
+			return nil, fmt.Errorf("%w: parent comment %d", errs.ErrInvalid, parentID)
+		}
+
+		if parent.ParentID != 0 {
+			parentID = parent.ParentID
+		}
+	}
+
+	comment := &domain.Comment{
+		ID:         0,
+		PostID:     params.PostID,
+		ParentID:   parentID,
+		AuthorName: authorName,
+		Content:    content,
+		Status:     domain.StatusPending,
+		CreatedAt:  time.Now(),
+		PostTitle:  "",
+		PostSlug:   "",
+		HTML:       nil,
+		Replies:    nil,
+	}
+
+	if err := c.CommentsRepo.Create(ctx, comment); err != nil {
+		return nil, fmt.Errorf("can't create comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// Approved возвращает одобренные комментарии поста в виде дерева глубиной в один уровень.
+func (c *Comments) Approved(ctx context.Context, postID int) ([]*domain.Comment, error) {
+	comments, err := c.CommentsRepo.ByPost(ctx, postID, domain.StatusApproved)
+	if err != nil {
+		return nil, fmt.Errorf("comments: %w", err)
+	}
+
+	roots := make([]*domain.Comment, 0, len(comments))
+	byID := make(map[int]*domain.Comment, len(comments))
+
+	for _, comment := range comments {
+		comment.HTML = RenderMarkdown([]byte(comment.Content))
+
+		if comment.ParentID == 0 {
+			roots = append(roots, comment)
+			byID[comment.ID] = comment
+		}
+	}
+
+	for _, comment := range comments {
+		if comment.ParentID == 0 {
+			continue
+		}
+
+		// ответы на неодобренные комментарии не показываем
+		if parent, ok := byID[comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		}
+	}
+
+	return roots, nil
+}
+
+// Queue возвращает комментарии с указанным статусом для модерации.
+func (c *Comments) Queue(ctx context.Context, status domain.Status) ([]*domain.Comment, error) {
+	comments, err := c.CommentsRepo.ByStatus(ctx, status, moderationQueueLimit)
+	if err != nil {
+		return nil, fmt.Errorf("comments: %w", err)
+	}
+
+	for _, comment := range comments {
+		comment.HTML = RenderMarkdown([]byte(comment.Content))
+	}
+
+	return comments, nil
+}
+
+func (c *Comments) Approve(ctx context.Context, id int) error {
+	if err := c.CommentsRepo.SetStatus(ctx, id, domain.StatusApproved); err != nil {
+		return fmt.Errorf("can't approve comment: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Comments) Reject(ctx context.Context, id int) error {
+	if err := c.CommentsRepo.SetStatus(ctx, id, domain.StatusRejected); err != nil {
+		return fmt.Errorf("can't reject comment: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Comments) Delete(ctx context.Context, id int) error {
+	if err := c.CommentsRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("can't delete comment: %w", err)
+	}
+
+	return nil
+}
+
+// RenderMarkdown - урезанный рендерер для комментариев читателей:
+// сырой html и картинки вырезаются, ссылки получают rel="nofollow".
+func RenderMarkdown(md []byte) []byte {
+	extensions := parser.NoIntraEmphasis | parser.FencedCode | parser.Autolink |
+		parser.Strikethrough | parser.HardLineBreak
+	p := parser.NewWithExtensions(extensions)
+	doc := p.Parse(md)
+
+	htmlFlags := html.SkipHTML | html.SkipImages | html.Safelink | html.NofollowLinks |
+		html.NoreferrerLinks | html.NoopenerLinks | html.HrefTargetBlank
+	//nolint:exhaustruct // default render options
+	opts := html.RendererOptions{Flags: htmlFlags}
+	renderer := html.NewRenderer(opts)
+
+	return markdown.Render(doc, renderer)
+}
+
+// ipLimiter ограничивает количество событий с одного ip в скользящем окне.
+type ipLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[string][]time.Time
+}
+
+func newIPLimiter(limit int, window time.Duration) *ipLimiter {
+	return &ipLimiter{mu: sync.Mutex{}, limit: limit, window: window, events: make(map[string][]time.Time)}
+}
+
+func (l *ipLimiter) Allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	threshold := now.Add(-l.window)
+
+	recent := l.events[ip][:0]
+
+	for _, t := range l.events[ip] {
+		if t.After(threshold) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.limit {
+		l.events[ip] = recent
+
+		return false
+	}
+
+	l.events[ip] = append(recent, now)
+
+	// чистим устаревшие ip, чтобы карта не росла бесконечно
+	for key, times := range l.events {
+		if len(times) == 0 || !times[len(times)-1].After(threshold) {
+			delete(l.events, key)
+		}
+	}
+
+	return true
+}
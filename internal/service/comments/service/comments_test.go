@@ -0,0 +1,126 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/arevbond/arevbond-blog/internal/service/comments/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/comments/service"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCommentsRepo struct {
+	comments map[int]*domain.Comment
+}
+
+func newFakeCommentsRepo() *fakeCommentsRepo {
+	return &fakeCommentsRepo{comments: make(map[int]*domain.Comment)}
+}
+
+func (f *fakeCommentsRepo) Create(_ context.Context, comment *domain.Comment) error {
+	comment.ID = len(f.comments) + 1
+	f.comments[comment.ID] = comment
+
+	return nil
+}
+
+func (f *fakeCommentsRepo) Find(_ context.Context, id int) (*domain.Comment, error) {
+	comment, ok := f.comments[id]
+	if !ok {
+		return nil, errs.ErrNotFound
+	}
+
+	return comment, nil
+}
+
+func (f *fakeCommentsRepo) ByPost(_ context.Context, postID int, status domain.Status) ([]*domain.Comment, error) {
+	var result []*domain.Comment
+
+	for id := 1; id <= len(f.comments); id++ {
+		if c := f.comments[id]; c.PostID == postID && c.Status == status {
+			result = append(result, c)
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fakeCommentsRepo) ByStatus(context.Context, domain.Status, int) ([]*domain.Comment, error) {
+	return nil, nil
+}
+
+func (f *fakeCommentsRepo) SetStatus(_ context.Context, id int, status domain.Status) error {
+	f.comments[id].Status = status
+
+	return nil
+}
+
+func (f *fakeCommentsRepo) Delete(_ context.Context, id int) error {
+	if _, ok := f.comments[id]; !ok {
+		return errs.ErrNotFound
+	}
+
+	delete(f.comments, id)
+
+	return nil
+}
+
+func TestComments_Create(t *testing.T) {
+	t.Parallel()
+
+	repo := newFakeCommentsRepo()
+	comments := service.New(slog.Default(), repo)
+	ctx := t.Context()
+
+	root, err := comments.Create(ctx, domain.CreateCommentParams{PostID: 1, AuthorName: "Ann", Content: "first", IP: "1.1.1.1"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, root.Status)
+
+	_, err = comments.Create(ctx, domain.CreateCommentParams{PostID: 1, ParentID: root.ID, AuthorName: "Bob", Content: "re", IP: "2.2.2.2"})
+	require.ErrorIs(t, err, errs.ErrInvalid, "can't reply to comment on moderation")
+
+	require.NoError(t, comments.Approve(ctx, root.ID))
+
+	reply, err := comments.Create(ctx, domain.CreateCommentParams{PostID: 1, ParentID: root.ID, AuthorName: "Bob", Content: "re", IP: "2.2.2.2"})
+	require.NoError(t, err)
+	require.NoError(t, comments.Approve(ctx, reply.ID))
+
+	nested, err := comments.Create(ctx, domain.CreateCommentParams{PostID: 1, ParentID: reply.ID, AuthorName: "Ann", Content: "re re", IP: "1.1.1.1"})
+	require.NoError(t, err)
+	assert.Equal(t, root.ID, nested.ParentID, "threads are one level deep")
+
+	_, err = comments.Create(ctx, domain.CreateCommentParams{PostID: 1, AuthorName: "Ann", Content: "   ", IP: "1.1.1.1"})
+	require.ErrorIs(t, err, errs.ErrInvalid)
+
+	_, err = comments.Create(ctx, domain.CreateCommentParams{PostID: 1, AuthorName: "Ann", Content: "spam", IP: "1.1.1.1"})
+	require.NoError(t, err)
+
+	_, err = comments.Create(ctx, domain.CreateCommentParams{PostID: 1, AuthorName: "Ann", Content: "spam", IP: "1.1.1.1"})
+	require.ErrorIs(t, err, errs.ErrRateLimited)
+
+	tree, err := comments.Approved(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	assert.Len(t, tree[0].Replies, 1)
+
+	// повторное удаление сообщает, что комментария нет, и модерация отвечает 404
+	require.NoError(t, comments.Delete(ctx, reply.ID))
+	require.ErrorIs(t, comments.Delete(ctx, reply.ID), errs.ErrNotFound)
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+
+	html := string(service.RenderMarkdown([]byte(
+		"**bold** <script>alert(1)</script> ![img](/x.png) [link](javascript:alert(1)) https://go.dev")))
+
+	assert.Contains(t, html, "<strong>bold</strong>")
+	assert.NotContains(t, html, "<script>")
+	assert.NotContains(t, html, "<img")
+	assert.NotContains(t, html, `href="javascript`)
+	assert.True(t, strings.Contains(html, `rel="nofollow`), html)
+}
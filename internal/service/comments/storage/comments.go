@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/arevbond/arevbond-blog/internal/service/comments/domain"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+type Comments struct {
+	log *slog.Logger
+	DB  *sqlx.DB
+}
+
+func NewCommentsRepo(log *slog.Logger, db *sqlx.DB) *Comments {
+	return &Comments{log: log, DB: db}
+}
+
+// Create сохраняет комментарий, только если пост существует и опубликован.
+func (c *Comments) Create(ctx context.Context, comment *domain.Comment) error {
+	query := `
+		INSERT INTO comments (post_id, parent_id, author_name, content, status, created_at)
+		SELECT $1, NULLIF($2, 0), $3, $4, $5, $6
+		WHERE EXISTS (SELECT 1 FROM posts WHERE id = $1 AND is_published = true)
+		RETURNING id;`
+
+	args := []any{comment.PostID, comment.ParentID, comment.AuthorName, comment.Content,
+		comment.Status, comment.CreatedAt}
+
+	err := c.DB.QueryRowContext(ctx, query, args...).Scan(&comment.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("published post with id %d: %w", comment.PostID, errs.ErrNotFound)
+		}
+
+		return fmt.Errorf("can't insert comment: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Comments) Find(ctx context.Context, id int) (*domain.Comment, error) {
+	query := `
+		SELECT id, post_id, COALESCE(parent_id, 0) AS parent_id, author_name, content, status, created_at
+		FROM comments
+		WHERE id = $1;`
+
+	var comment domain.Comment
+
+	err := c.DB.GetContext(ctx, &comment, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("comment with id %d: %w", id, errs.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("can't get comment from db: %w", err)
+	}
+
+	return &comment, nil
+}
+
+func (c *Comments) ByPost(ctx context.Context, postID int, status domain.Status) ([]*domain.Comment, error) {
+	query := `
+		SELECT id, post_id, COALESCE(parent_id, 0) AS parent_id, author_name, content, status, created_at
+		FROM comments
+		WHERE post_id = $1 AND status = $2
+		ORDER BY created_at;`
+
+	comments := []*domain.Comment{}
+
+	err := c.DB.SelectContext(ctx, &comments, query, postID, status)
+	if err != nil {
+		return nil, fmt.Errorf("can't get post comments from db: %w", err)
+	}
+
+	return comments, nil
+}
+
+// ByStatus возвращает комментарии со всех постов, новые первыми.
+func (c *Comments) ByStatus(ctx context.Context, status domain.Status, limit int) ([]*domain.Comment, error) {
+	query := `
+		SELECT cm.id, cm.post_id, COALESCE(cm.parent_id, 0) AS parent_id, cm.author_name, cm.content,
+		       cm.status, cm.created_at, p.title AS post_title, p.slug AS post_slug
+		FROM comments cm
+		INNER JOIN posts p ON p.id = cm.post_id
+		WHERE cm.status = $1
+		ORDER BY cm.created_at DESC
+		LIMIT $2;`
+
+	comments := []*domain.Comment{}
+
+	err := c.DB.SelectContext(ctx, &comments, query, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get comments from db: %w", err)
+	}
+
+	return comments, nil
+}
+
+func (c *Comments) SetStatus(ctx context.Context, id int, status domain.Status) error {
+	query := `UPDATE comments SET status = $1 WHERE id = $2;`
+
+	result, err := c.DB.ExecContext(ctx, query, status, id)
+	if err != nil {
+		return fmt.Errorf("can't set comment status: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("comment with id %d: %w", id, errs.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (c *Comments) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM comments WHERE id = $1;`
+
+	result, err := c.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("can't delete comment: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("comment with id %d: %w", id, errs.ErrNotFound)
+	}
+
+	return nil
+}
@@ -6,3 +6,6 @@ import (
 
 var ErrNotFound = errors.New("not found")
 var ErrDuplicate = errors.New("duplicate")
+var ErrInvalid = errors.New("invalid")
+var ErrRateLimited = errors.New("rate limited")
+var ErrConflict = errors.New("conflict")
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS page_views (
+    day DATE NOT NULL,
+    path TEXT NOT NULL,
+    post_id INT REFERENCES posts (id) ON DELETE SET NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    views INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, path, referrer)
+);
+
+CREATE INDEX IF NOT EXISTS page_views_post_id_idx ON page_views (post_id);
+
+CREATE TABLE IF NOT EXISTS page_visitors (
+    day DATE NOT NULL,
+    path TEXT NOT NULL,
+    post_id INT REFERENCES posts (id) ON DELETE SET NULL,
+    visitor_hash CHAR(64) NOT NULL,
+    PRIMARY KEY (day, path, visitor_hash)
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE IF EXISTS page_visitors;
+
+DROP TABLE IF EXISTS page_views;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    parent_id INT REFERENCES comments (id) ON DELETE CASCADE,
+    author_name TEXT NOT NULL,
+    content TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX IF NOT EXISTS comments_post_id_status_idx ON comments (post_id, status);
+
+CREATE INDEX IF NOT EXISTS comments_status_idx ON comments (status);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE IF EXISTS comments;
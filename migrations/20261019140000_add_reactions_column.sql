@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+-- счётчики реакций хранятся в самой таблице постов, чтобы список постов не требовал join
+ALTER TABLE posts
+ADD COLUMN reactions JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+ALTER TABLE posts
+DROP COLUMN reactions;
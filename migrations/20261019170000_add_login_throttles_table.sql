@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key TEXT PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW (),
+    locked_until TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW ()
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE IF EXISTS login_throttles;
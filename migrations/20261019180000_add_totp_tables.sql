@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS totp_secrets (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    secret TEXT NOT NULL,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW (),
+    confirmed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW (),
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS amr TEXT NOT NULL DEFAULT 'pwd';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mfa_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+ALTER TABLE sessions DROP COLUMN IF EXISTS mfa_at;
+ALTER TABLE sessions DROP COLUMN IF EXISTS amr;
+
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS totp_secrets;
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    display_name VARCHAR(100) NOT NULL,
+    -- пустой хэш у admin: пароль выставляется из ADMIN_TOKEN при старте
+    password_hash TEXT NOT NULL DEFAULT '',
+    role VARCHAR(16) NOT NULL CHECK (role IN ('author', 'editor', 'admin')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW ()
+);
+
+INSERT INTO users (username, display_name, role) VALUES ('admin', 'admin', 'admin')
+ON CONFLICT (username) DO NOTHING;
+
+-- всё, что было создано до появления пользователей, принадлежит админу
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author_id INT REFERENCES users (id) ON DELETE SET NULL;
+UPDATE posts SET author_id = (SELECT id FROM users WHERE username = 'admin');
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users (id) ON DELETE CASCADE;
+UPDATE sessions SET user_id = (SELECT id FROM users WHERE username = 'admin');
+ALTER TABLE sessions ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE totp_secrets ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users (id) ON DELETE CASCADE;
+UPDATE totp_secrets SET user_id = (SELECT id FROM users WHERE username = 'admin');
+ALTER TABLE totp_secrets DROP CONSTRAINT IF EXISTS totp_secrets_pkey;
+ALTER TABLE totp_secrets DROP COLUMN IF EXISTS id;
+ALTER TABLE totp_secrets ADD PRIMARY KEY (user_id);
+
+ALTER TABLE recovery_codes ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users (id) ON DELETE CASCADE;
+UPDATE recovery_codes SET user_id = (SELECT id FROM users WHERE username = 'admin');
+ALTER TABLE recovery_codes ALTER COLUMN user_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP INDEX IF EXISTS idx_sessions_user_id;
+DROP INDEX IF EXISTS idx_posts_author_id;
+
+DELETE FROM recovery_codes WHERE user_id <> (SELECT id FROM users WHERE username = 'admin');
+ALTER TABLE recovery_codes DROP COLUMN IF EXISTS user_id;
+
+DELETE FROM totp_secrets WHERE user_id <> (SELECT id FROM users WHERE username = 'admin');
+ALTER TABLE totp_secrets DROP CONSTRAINT IF EXISTS totp_secrets_pkey;
+ALTER TABLE totp_secrets ADD COLUMN IF NOT EXISTS id SMALLINT NOT NULL DEFAULT 1 CHECK (id = 1);
+ALTER TABLE totp_secrets ADD PRIMARY KEY (id);
+ALTER TABLE totp_secrets DROP COLUMN IF EXISTS user_id;
+
+DELETE FROM sessions WHERE user_id <> (SELECT id FROM users WHERE username = 'admin');
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_id;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS author_id;
+
+DROP TABLE IF EXISTS users;
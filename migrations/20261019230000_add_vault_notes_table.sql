@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS vault_notes (
+    path TEXT PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX IF NOT EXISTS vault_notes_post_id_idx ON vault_notes (post_id);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE IF EXISTS vault_notes;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+CREATE TABLE IF NOT EXISTS vault_attachments (
+    name TEXT PRIMARY KEY,
+    synced_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW ()
+);
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+DROP TABLE IF EXISTS vault_attachments;
@@ -0,0 +1,9 @@
+// Package migrations встраивает SQL миграции схемы в бинарник.
+package migrations
+
+import "embed"
+
+// FS - файлы миграций goose.
+//
+//go:embed *.sql
+var FS embed.FS
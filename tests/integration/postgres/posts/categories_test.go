@@ -2,7 +2,11 @@ package posts
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/arevbond/arevbond-blog/internal/service/blog/domain"
 	"github.com/arevbond/arevbond-blog/internal/service/blog/storage"
+	"github.com/arevbond/arevbond-blog/internal/service/errs"
 )
 
 func (s *StorageSuite) TestCategoriesAll() {
@@ -27,3 +31,36 @@ func (s *StorageSuite) TestCategoriesAll() {
 		}
 	}
 }
+
+func (s *StorageSuite) TestCategoriesCRUD() {
+	repo := storage.NewCategoriesRepo(s.log, s.conn)
+	posts := storage.NewPostsRepo(s.log, s.conn)
+
+	category := &domain.Category{Name: "Путешествия"}
+	s.Require().NoError(repo.Create(s.ctx, category))
+	s.Assert().NotZero(category.ID)
+
+	s.Require().ErrorIs(repo.Create(s.ctx, &domain.Category{Name: "Книги"}), errs.ErrDuplicate)
+
+	s.Require().NoError(repo.Rename(s.ctx, category.ID, "Поездки"))
+	s.Require().ErrorIs(repo.Rename(s.ctx, category.ID, "Книги"), errs.ErrDuplicate)
+	s.Require().ErrorIs(repo.Rename(s.ctx, 12345, "Другое"), errs.ErrNotFound)
+
+	found, err := repo.Find(s.ctx, category.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal("Поездки", found.Name)
+
+	post := &domain.Post{
+		Title: "title", Content: []byte("content"), Slug: "trip", CategoryID: category.ID, Extension: ".md",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	s.Require().NoError(posts.Create(s.ctx, post))
+
+	s.Require().ErrorIs(repo.Delete(s.ctx, category.ID), errs.ErrConflict)
+
+	s.Require().NoError(posts.Delete(s.ctx, post.ID))
+	s.Require().NoError(repo.Delete(s.ctx, category.ID))
+
+	_, err = repo.Find(s.ctx, category.ID)
+	s.Require().ErrorIs(err, errs.ErrNotFound)
+}
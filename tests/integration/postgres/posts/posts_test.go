@@ -146,6 +146,49 @@ func (s *StorageSuite) TestPostsUpdate() {
 	s.Assert().Equal(params.Content, postInDB.Content)
 }
 
+func (s *StorageSuite) TestPostsUpdate_Version() {
+	post, err := s.insertTestPost("slug")
+	s.Require().NoError(err)
+
+	repo := storage.NewPostsRepo(s.log, s.conn)
+
+	params := domain.UpdatePostParams{
+		ID:          post.ID,
+		Title:       "first",
+		Slug:        post.Slug,
+		Description: post.Description,
+		CategoryID:  post.CategoryID,
+		Content:     post.Content,
+		Version:     1,
+	}
+	s.Require().NoError(repo.Update(s.ctx, params))
+
+	updated, err := repo.Find(s.ctx, post.ID)
+	s.Require().NoError(err)
+	s.Assert().Equal(2, updated.Version)
+
+	// правка на основе первой версии уже устарела
+	params.Title = "second"
+	s.Require().ErrorIs(repo.Update(s.ctx, params), errs.ErrConflict)
+
+	params.ID = 1234
+	s.Require().ErrorIs(repo.Update(s.ctx, params), errs.ErrNotFound)
+}
+
+func (s *StorageSuite) TestPostsTakenSlugs() {
+	_, err := s.insertTestPost("slug")
+	s.Require().NoError(err)
+
+	_, err = s.insertTestPost("slug-3")
+	s.Require().NoError(err)
+
+	repo := storage.NewPostsRepo(s.log, s.conn)
+
+	taken, err := repo.TakenSlugs(s.ctx, []string{"slug", "slug-2", "slug-3"})
+	s.Require().NoError(err)
+	s.Assert().ElementsMatch([]string{"slug", "slug-3"}, taken)
+}
+
 func (s *StorageSuite) TestPostsFind() {
 	expectedPost, err := s.insertTestPost("slug")
 	s.Require().NoError(err)
@@ -223,20 +266,47 @@ func (s *StorageSuite) TestPostsAll() {
 	}
 
 	s.Run("posts only published", func() {
-		result, err := repo.All(s.ctx, 10, 0, true)
+		result, err := repo.All(s.ctx, 10, 0, true, 0)
 		s.Require().NoError(err)
 
 		s.Require().Equal(3, len(result), "should return all published posts")
 	})
 
 	s.Run("all posts", func() {
-		result, err := repo.All(s.ctx, 10, 0, false)
+		result, err := repo.All(s.ctx, 10, 0, false, 0)
 		s.Require().NoError(err)
 
 		s.Require().Equal(len(result), len(result), "should return all published posts")
 	})
 }
 
+func (s *StorageSuite) TestPostsAllPublished() {
+	repo := storage.NewPostsRepo(s.log, s.conn)
+
+	for i := 0; i < 4; i++ {
+		post := &domain.Post{
+			Title:       fmt.Sprintf("Post %d", i+1),
+			Description: fmt.Sprintf("Description %d", i+1),
+			Content:     []byte(fmt.Sprintf("Content %d", i+1)),
+			Slug:        fmt.Sprintf("post-%d", i+1),
+			CategoryID:  1,
+			Extension:   ".md",
+			IsPublished: i%2 == 0,
+			CreatedAt:   time.Now().Add(-time.Duration(i) * time.Hour),
+			UpdatedAt:   time.Now(),
+		}
+		s.Require().NoError(repo.Create(s.ctx, post))
+	}
+
+	result, err := repo.AllPublished(s.ctx)
+	s.Require().NoError(err)
+
+	s.Require().Len(result, 2)
+	s.Assert().Equal("post-1", result[0].Slug)
+	s.Assert().Empty(result[0].Content, "content shouldn't be selected")
+	s.Assert().Equal("Книги", result[0].CategoryName)
+}
+
 func (s *StorageSuite) TestPostsAll_Pagination() {
 	repo := storage.NewPostsRepo(s.log, s.conn)
 
@@ -295,7 +365,7 @@ func (s *StorageSuite) TestPostsAll_Pagination() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result, err := repo.All(s.ctx, tt.limit, tt.offset, true)
+			result, err := repo.All(s.ctx, tt.limit, tt.offset, true, 0)
 			s.Require().NoError(err)
 			s.Assert().Len(result, tt.expectedCount)
 
@@ -370,7 +440,7 @@ func (s *StorageSuite) TestPostsAllWithCategory() {
 	}
 
 	s.Run("published posts only from category 1", func() {
-		result, err := repo.AllWithCategory(s.ctx, 10, 0, true, 1)
+		result, err := repo.AllWithCategory(s.ctx, 10, 0, true, 0, 1)
 		s.Require().NoError(err)
 		s.Assert().Len(result, 1, "should return 1 published post from category 1")
 		s.Assert().Equal(1, result[0].CategoryID, "all posts should be from category 1")
@@ -378,7 +448,7 @@ func (s *StorageSuite) TestPostsAllWithCategory() {
 	})
 
 	s.Run("published posts only from category 2", func() {
-		result, err := repo.AllWithCategory(s.ctx, 10, 0, true, 2)
+		result, err := repo.AllWithCategory(s.ctx, 10, 0, true, 0, 2)
 		s.Require().NoError(err)
 		s.Assert().Len(result, 2, "should return 2 published posts from category 2")
 		for _, post := range result {
@@ -388,7 +458,7 @@ func (s *StorageSuite) TestPostsAllWithCategory() {
 	})
 
 	s.Run("all posts from category 1", func() {
-		result, err := repo.AllWithCategory(s.ctx, 10, 0, false, 1)
+		result, err := repo.AllWithCategory(s.ctx, 10, 0, false, 0, 1)
 		s.Require().NoError(err)
 		s.Assert().Len(result, 2, "should return all posts from category 1")
 		for _, post := range result {
@@ -397,7 +467,7 @@ func (s *StorageSuite) TestPostsAllWithCategory() {
 	})
 
 	s.Run("all posts from category 2", func() {
-		result, err := repo.AllWithCategory(s.ctx, 10, 0, false, 2)
+		result, err := repo.AllWithCategory(s.ctx, 10, 0, false, 0, 2)
 		s.Require().NoError(err)
 		s.Assert().Len(result, 3, "should return all posts from category 2")
 		for _, post := range result {
@@ -476,7 +546,7 @@ func (s *StorageSuite) TestPostsAllWithCategory_Pagination() {
 
 	for _, tt := range tests {
 		s.Run(tt.name, func() {
-			result, err := repo.AllWithCategory(s.ctx, tt.limit, tt.offset, true, tt.categoryID)
+			result, err := repo.AllWithCategory(s.ctx, tt.limit, tt.offset, true, 0, tt.categoryID)
 			s.Require().NoError(err)
 			s.Assert().Len(result, tt.expectedCount)
 
@@ -489,3 +559,77 @@ func (s *StorageSuite) TestPostsAllWithCategory_Pagination() {
 		})
 	}
 }
+
+func (s *StorageSuite) TestPostsAddReaction() {
+	post, err := s.insertTestPost("slug")
+	s.Require().NoError(err)
+
+	repo := storage.NewPostsRepo(s.log, s.conn)
+
+	counts, err := repo.AddReaction(s.ctx, post.ID, "like", 1)
+	s.Require().NoError(err)
+	s.Assert().Equal(1, counts["like"])
+
+	counts, err = repo.AddReaction(s.ctx, post.ID, "like", -1)
+	s.Require().NoError(err)
+	s.Assert().Equal(0, counts["like"])
+
+	counts, err = repo.AddReaction(s.ctx, post.ID, "like", -1)
+	s.Require().NoError(err)
+	s.Assert().Equal(0, counts["like"], "counter can't be negative")
+
+	postInDB, err := repo.FindBySlug(s.ctx, "slug")
+	s.Require().NoError(err)
+	s.Assert().Equal(0, postInDB.Reactions["like"])
+
+	_, err = repo.AddReaction(s.ctx, 100500, "like", 1)
+	s.Assert().ErrorIs(err, errs.ErrNotFound)
+}
+
+func (s *StorageSuite) TestPostsList_Cursor() {
+	repo := storage.NewPostsRepo(s.log, s.conn)
+
+	createdAt := time.Now().Truncate(time.Microsecond)
+
+	for i := 0; i < 5; i++ {
+		post := &domain.Post{
+			Title:       fmt.Sprintf("Post %d", i+1),
+			Content:     []byte("content"),
+			Slug:        fmt.Sprintf("post-%d", i+1),
+			CategoryID:  1,
+			Extension:   ".md",
+			IsPublished: i != 2,
+			// у двух постов одинаковое время, порядок между ними задаёт id
+			CreatedAt: createdAt.Add(-time.Duration(min(i, 3)) * time.Hour),
+			UpdatedAt: createdAt,
+		}
+		s.Require().NoError(repo.Create(s.ctx, post))
+	}
+
+	var titles []string
+
+	filter := domain.PostsFilter{Limit: 2, Status: domain.PostStatusAny}
+
+	for range 5 {
+		page, err := repo.List(s.ctx, filter)
+		s.Require().NoError(err)
+
+		if len(page) == 0 {
+			break
+		}
+
+		for _, post := range page {
+			s.Assert().Empty(post.Content, "list must not load content")
+			titles = append(titles, post.Title)
+		}
+
+		filter.After = domain.CursorOf(page[len(page)-1])
+	}
+
+	s.Assert().Equal([]string{"Post 1", "Post 2", "Post 3", "Post 5", "Post 4"}, titles)
+
+	drafts, err := repo.List(s.ctx, domain.PostsFilter{Limit: 10, Status: domain.PostStatusDraft})
+	s.Require().NoError(err)
+	s.Require().Len(drafts, 1)
+	s.Assert().Equal("Post 3", drafts[0].Title)
+}
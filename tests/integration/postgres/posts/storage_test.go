@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -13,7 +12,6 @@ import (
 	"github.com/arevbond/arevbond-blog/internal/config"
 	"github.com/arevbond/arevbond-blog/internal/db"
 	"github.com/jmoiron/sqlx"
-	"github.com/pressly/goose"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
@@ -75,8 +73,12 @@ func (s *StorageSuite) SetupSuite() {
 	s.Require().NoError(err)
 	s.Require().NoError(s.conn.Ping())
 
-	err = migrate(s.conn)
+	migrator, err := db.NewMigrator(s.conn)
 	s.Require().NoError(err)
+
+	_, err = migrator.Up(s.ctx)
+	s.Require().NoError(err)
+	s.Require().NoError(db.CheckSchema(s.ctx, migrator))
 }
 
 func (s *StorageSuite) TearDownSuite() {
@@ -102,28 +104,3 @@ func (s *StorageSuite) truncateTables() {
 		s.Require().NoError(err)
 	}
 }
-
-func migrate(conn *sqlx.DB) error {
-	root := findRootDir()
-	migrationPath := filepath.Join(root, "migrations")
-	_, err := os.Stat(migrationPath)
-	if err != nil {
-		return fmt.Errorf("dir migrations doesn't exists: %w", err)
-	}
-
-	return goose.Up(conn.DB, migrationPath)
-}
-
-func findRootDir() string {
-	dir, _ := os.Getwd()
-	for {
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			return ""
-		}
-		dir = parent
-	}
-}